@@ -0,0 +1,14 @@
+package main
+
+import (
+	"os"
+
+	"github.com/buildpacks/libcnb"
+	"github.com/chuxel/devpacks/internal/buildpacks/golang"
+)
+
+func main() {
+	args := []string{"build"}
+	args = append(args, os.Args[1:]...)
+	libcnb.Main(nil, golang.GoRuntimeBuilder{}, libcnb.WithArguments(args))
+}
@@ -0,0 +1,14 @@
+package main
+
+import (
+	"os"
+
+	"github.com/buildpacks/libcnb"
+	"github.com/chuxel/devpacks/internal/buildpacks/golang"
+)
+
+func main() {
+	args := []string{"detect"}
+	args = append(args, os.Args[1:]...)
+	libcnb.Main(golang.GoRuntimeDetector{}, nil, libcnb.WithArguments(args))
+}
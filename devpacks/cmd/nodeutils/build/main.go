@@ -0,0 +1,14 @@
+package main
+
+import (
+	"os"
+
+	"github.com/buildpacks/libcnb"
+	"github.com/chuxel/devpacks/internal/buildpacks/nodeutils"
+)
+
+func main() {
+	args := []string{"build"}
+	args = append(args, os.Args[1:]...)
+	libcnb.Main(nil, nodeutils.NodeUtilsBuilder{}, libcnb.WithArguments(args))
+}
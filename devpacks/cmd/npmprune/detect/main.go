@@ -0,0 +1,14 @@
+package main
+
+import (
+	"os"
+
+	"github.com/buildpacks/libcnb"
+	"github.com/chuxel/devpacks/internal/buildpacks/npmprune"
+)
+
+func main() {
+	args := []string{"detect"}
+	args = append(args, os.Args[1:]...)
+	libcnb.Main(npmprune.NpmPruneDetector{}, nil, libcnb.WithArguments(args))
+}
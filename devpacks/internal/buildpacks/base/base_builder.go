@@ -17,6 +17,12 @@ type DefaultBuilder interface {
 	NewLayerContributor(buildMode string, layerTypes libcnb.LayerTypes, context libcnb.BuildContext) libcnb.LayerContributor
 }
 
+// Optionally implemented by a DefaultBuilder that needs layers (e.g. cache-only layers) in addition
+// to the one from NewLayerContributor. These are contributed first so the main layer can use them.
+type AdditionalLayersBuilder interface {
+	AdditionalLayerContributors(buildMode string, context libcnb.BuildContext) []libcnb.LayerContributor
+}
+
 func DefaultBuild(builder DefaultBuilder, context libcnb.BuildContext) (libcnb.BuildResult, error) {
 	buildMode := devcontainer.ContainerImageBuildMode()
 	log.Println("Devpack path:", context.Buildpack.Path)
@@ -63,6 +69,11 @@ func DefaultBuild(builder DefaultBuilder, context libcnb.BuildContext) (libcnb.B
 		field.Set(reflect.ValueOf(value))
 	}
 
+	// Builders can also contribute layers with their own layer types (e.g. the download cache or a package cache).
+	// These are added first so they are ready before the buildpack's own layer is contributed.
+	if additionalLayersBuilder, ok := builder.(AdditionalLayersBuilder); ok {
+		result.Layers = append(result.Layers, additionalLayersBuilder.AdditionalLayerContributors(buildMode, context)...)
+	}
 	// Use reflection to create a contributor based on the type assigned to the builder
 	result.Layers = append(result.Layers, builder.NewLayerContributor(buildMode, layerTypes, context))
 
@@ -0,0 +1,86 @@
+package base
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/buildpacks/libcnb"
+	"github.com/chuxel/devpacks/internal/common/utils"
+)
+
+// Layer metadata property the cache key is stored in
+const CACHE_KEY_METADATA_NAME = "sha256"
+
+// Everything that determines if a cached layer can be reused. The architecture and distro version
+// are always included since compiled dependencies (e.g. native node modules or wheels) depend on them.
+type CacheKey struct {
+	// Files whose contents are hashed, like lockfiles. Files that do not exist are hashed as missing.
+	Files []string
+	// Env vars whose values are hashed, like PYTHON_VERSION or NODE_VERSION from a runtime buildpack
+	EnvVarNames []string
+	// Any other values, like a list of tools to install
+	Values []string
+}
+
+// Returns the hex encoded sha256 of the key's inputs
+func (cacheKey CacheKey) Sum() (string, error) {
+	hashGen := sha256.New()
+	// Separate each input with its name and a null so moving text between inputs changes the key
+	writeEntry := func(name string, value string) {
+		fmt.Fprintf(hashGen, "%s=%d:%s\x00", name, len(value), value)
+	}
+
+	writeEntry("arch", runtime.GOARCH)
+	distroVersionId := ""
+	if distroInfo, err := utils.ReadLinuxDistroInfo(); err == nil {
+		distroVersionId = distroInfo.Id + " " + distroInfo.VersionId
+	}
+	writeEntry("distro", distroVersionId)
+	for _, name := range cacheKey.EnvVarNames {
+		writeEntry("env:"+name, os.Getenv(name))
+	}
+	for _, value := range cacheKey.Values {
+		writeEntry("value", value)
+	}
+	for _, filePath := range cacheKey.Files {
+		file, err := os.Open(filePath)
+		if os.IsNotExist(err) {
+			writeEntry("missing:"+filepath.Base(filePath), "")
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to open %s: %w", filePath, err)
+		}
+		info, err := file.Stat()
+		if err != nil {
+			file.Close()
+			return "", fmt.Errorf("failed to stat %s: %w", filePath, err)
+		}
+		fmt.Fprintf(hashGen, "file:%s=%d:", filepath.Base(filePath), info.Size())
+		_, err = io.Copy(hashGen, file)
+		file.Close()
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %w", filePath, err)
+		}
+		hashGen.Write([]byte{0})
+	}
+	return hex.EncodeToString(hashGen.Sum(nil)), nil
+}
+
+// Returns true if the layer was restored from the cache with the same key
+func LayerMatchesCacheKey(layer libcnb.Layer, cacheKey string) bool {
+	if layer.Metadata[CACHE_KEY_METADATA_NAME] == nil {
+		return false
+	}
+	if fmt.Sprint(layer.Metadata[CACHE_KEY_METADATA_NAME]) != cacheKey {
+		log.Println("Cache key changed, not reusing cached layer.")
+		return false
+	}
+	return true
+}
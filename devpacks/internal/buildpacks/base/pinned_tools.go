@@ -0,0 +1,120 @@
+package base
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/buildpacks/libcnb"
+)
+
+// Layer metadata property pinned tools are stored in
+const PINNED_TOOLS_METADATA_NAME = "tools"
+
+// A tool installed into its own folder in a layer. Version is what the request (e.g. "latest") resolved
+// to when the tool was first installed, and is reused until the request changes so builds are reproducible.
+type PinnedTool struct {
+	Name      string
+	Requested string
+	Version   string
+	CacheKey  string
+}
+
+var toolFolderNameRegexp = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// Reads tools recorded by a previous build of the layer, keyed by name
+func PinnedToolsFromLayer(layer libcnb.Layer) map[string]PinnedTool {
+	pinnedTools := map[string]PinnedTool{}
+	toolsMetadata, isMap := layer.Metadata[PINNED_TOOLS_METADATA_NAME].(map[string]interface{})
+	if !isMap {
+		return pinnedTools
+	}
+	for name, value := range toolsMetadata {
+		if toolMetadata, isMap := value.(map[string]interface{}); isMap {
+			pinnedTools[name] = PinnedTool{
+				Name:      name,
+				Requested: fmt.Sprint(toolMetadata["requested"]),
+				Version:   fmt.Sprint(toolMetadata["version"]),
+				CacheKey:  fmt.Sprint(toolMetadata["sha256"]),
+			}
+		}
+	}
+	return pinnedTools
+}
+
+// Converts the tools to layer metadata that PinnedToolsFromLayer can read
+func PinnedToolsMetadata(pinnedTools []PinnedTool) map[string]interface{} {
+	toolsMetadata := make(map[string]interface{}, len(pinnedTools))
+	for _, tool := range pinnedTools {
+		toolsMetadata[tool.Name] = map[string]interface{}{
+			"requested": tool.Requested,
+			"version":   tool.Version,
+			"sha256":    tool.CacheKey,
+		}
+	}
+	return toolsMetadata
+}
+
+// Folder name for a tool's sub-layer folder (e.g. golang.org/x/tools/gopls is golang.org_x_tools_gopls)
+func ToolFolderName(name string) string {
+	return toolFolderNameRegexp.ReplaceAllString(name, "_")
+}
+
+// Removes any folders in toolsPath that are not in toolFolderNames (e.g. tools that were removed from the list)
+func RemoveUnlistedToolFolders(toolsPath string, toolFolderNames []string) error {
+	entries, err := os.ReadDir(toolsPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read %s: %w", toolsPath, err)
+	}
+	listed := make(map[string]bool, len(toolFolderNames))
+	for _, folderName := range toolFolderNames {
+		listed[folderName] = true
+	}
+	for _, entry := range entries {
+		if !listed[entry.Name()] {
+			if err := os.RemoveAll(filepath.Join(toolsPath, entry.Name())); err != nil {
+				return fmt.Errorf("failed to remove %s: %w", entry.Name(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// Recreates the layer's bin folder with links to the binaries in each tool folder's bin folder. If two
+// tools have a binary with the same name, the one from the tool listed first is used.
+func LinkToolBinaries(layerPath string, toolsPath string, toolFolderNames []string) error {
+	binPath := filepath.Join(layerPath, "bin")
+	if err := os.RemoveAll(binPath); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", binPath, err)
+	}
+	if err := os.MkdirAll(binPath, 0755); err != nil {
+		return fmt.Errorf("unable to create %s: %w", binPath, err)
+	}
+	for _, folderName := range toolFolderNames {
+		toolBinPath := filepath.Join(toolsPath, folderName, "bin")
+		entries, err := os.ReadDir(toolBinPath)
+		if os.IsNotExist(err) {
+			// Some tools (e.g. type definitions for typescript) have no binaries
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", toolBinPath, err)
+		}
+		for _, entry := range entries {
+			linkPath := filepath.Join(binPath, entry.Name())
+			if _, err := os.Lstat(linkPath); err == nil {
+				log.Printf("Warning: %s from %s is also in an earlier tool. Skipping.\n", entry.Name(), folderName)
+				continue
+			}
+			if err := os.Symlink(filepath.Join(toolBinPath, entry.Name()), linkPath); err != nil {
+				return fmt.Errorf("failed to link %s: %w", entry.Name(), err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package base
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLinkToolBinaries(t *testing.T) {
+	layerPath := t.TempDir()
+	toolsPath := filepath.Join(layerPath, "tools")
+	toolBinaries := map[string][]string{
+		"eslint":   {"eslint"},
+		"prettier": {"prettier"},
+		// Same binary name as eslint, so it should be skipped
+		"eslint_d": {"eslint", "eslint_d"},
+		// No bin folder
+		"types": nil,
+	}
+	for folderName, binaries := range toolBinaries {
+		if binaries == nil {
+			if err := os.MkdirAll(filepath.Join(toolsPath, folderName), 0755); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		for _, binary := range binaries {
+			if err := os.MkdirAll(filepath.Join(toolsPath, folderName, "bin"), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(toolsPath, folderName, "bin", binary), []byte{}, 0755); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	// An existing bin folder from a previous build is replaced
+	if err := os.MkdirAll(filepath.Join(layerPath, "bin", "stale"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := LinkToolBinaries(layerPath, toolsPath, []string{"eslint", "types", "eslint_d", "prettier"}); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"eslint":   filepath.Join(toolsPath, "eslint", "bin", "eslint"),
+		"eslint_d": filepath.Join(toolsPath, "eslint_d", "bin", "eslint_d"),
+		"prettier": filepath.Join(toolsPath, "prettier", "bin", "prettier"),
+	}
+	entries, err := os.ReadDir(filepath.Join(layerPath, "bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(expected) {
+		t.Errorf("expected %d links, got %d", len(expected), len(entries))
+	}
+	for name, target := range expected {
+		linkTarget, err := os.Readlink(filepath.Join(layerPath, "bin", name))
+		if err != nil {
+			t.Errorf("expected a link for %s: %v", name, err)
+		} else if linkTarget != target {
+			t.Errorf("expected %s to link to %s, got %s", name, target, linkTarget)
+		}
+	}
+}
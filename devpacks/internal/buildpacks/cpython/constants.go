@@ -1,3 +1,10 @@
 package cpython
 
 const BUILDPACK_NAME = "cpython"
+const PYTHON_VERSION_ENV_VAR_NAME = "BP_CPYTHON_VERSION"
+const DEFAULT_PYTHON_VERSION = "latest"
+const PYTHON_VERSIONS_MANIFEST_URL = "https://raw.githubusercontent.com/actions/python-versions/main/versions-manifest.json"
+
+// Set in the metadata of pipinstall's plan requirement so the venv it adds is the default interpreter
+// in devcontainer.json rather than the python3 in this buildpack's layer
+const VENV_PLAN_METADATA_NAME = "venv"
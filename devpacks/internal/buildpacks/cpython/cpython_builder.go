@@ -3,18 +3,20 @@ package cpython
 import (
 	"bytes"
 	_ "embed"
+	"encoding/json"
 	"fmt"
 	"log"
 	"os"
 	"path"
 	"path/filepath"
-	"strings"
 
 	"github.com/buildpacks/libcnb"
 	"github.com/chuxel/devpacks/internal/buildpacks/base"
 	"github.com/chuxel/devpacks/internal/common/actions"
 	"github.com/chuxel/devpacks/internal/common/devcontainer"
+	"github.com/chuxel/devpacks/internal/common/downloads"
 	"github.com/chuxel/devpacks/internal/common/utils"
+	"github.com/chuxel/devpacks/internal/common/versions"
 )
 
 //go:embed assets/devcontainer.json
@@ -53,6 +55,11 @@ func (builder CPythonBuilder) NewLayerContributor(buildMode string, layerTypes l
 	return CPythonLayerContributor{BuildMode: buildMode, LayerTypes: layerTypes, Context: context}
 }
 
+// Implementation of base.AdditionalLayersBuilder.AdditionalLayerContributors
+func (builder CPythonBuilder) AdditionalLayerContributors(buildMode string, context libcnb.BuildContext) []libcnb.LayerContributor {
+	return []libcnb.LayerContributor{downloads.CacheLayerContributor{}}
+}
+
 // Implementation of libcnb.LayerContributor.Name
 func (contrib CPythonLayerContributor) Name() string {
 	return BUILDPACK_NAME
@@ -62,33 +69,37 @@ func (contrib CPythonLayerContributor) Name() string {
 func (contrib CPythonLayerContributor) Contribute(layer libcnb.Layer) (libcnb.Layer, error) {
 
 	// Version to download
-	requestedVersion := "latest"
-
-	// Can be specified in project.toml or pack command line
-	if os.Getenv("BP_PYTHON_VERSION") != "" {
-		requestedVersion = os.Getenv("BP_CPYTHON_VERSION")
-	} else {
-		// Otherwise look for version in a few common files
-		var candidateVersion string
-		var found bool
-		if candidateVersion, found = contrib.versionInFile("runtime.txt", "python-"); found {
-			requestedVersion = candidateVersion
-		}
-		/* else if candidateVersion, found = contrib.versionInFile(".node-version"); found {
-			requestedVersion = candidateVersion
-		}*/
+	request, err := ResolvePythonVersionRequest(contrib.Context.Application.Path)
+	if err != nil {
+		return layer, err
 	}
 
-	// Determine real node version to acquire (since requested could be a semver range)
-	manifest := actions.NewVersionManifestFromUrl("https://raw.githubusercontent.com/actions/python-versions/main/versions-manifest.json")
-	version := manifest.FindVersion(requestedVersion, true)
+	// Determine real python version to acquire (since requested could be a semver range)
+	cache := downloads.NewDownloadCache(contrib.Context)
+	manifestBytes, err := cache.Bytes(PYTHON_VERSIONS_MANIFEST_URL)
+	if err != nil {
+		return layer, err
+	}
+	manifest, err := actions.NewVersionManifestFromBytes(manifestBytes)
+	if err != nil {
+		return layer, err
+	}
+	// Prereleases are only matched if the requested version explicitly references one
+	versionRange, err := versions.NewPythonRange(request.Version)
+	if err != nil {
+		return layer, err
+	}
+	version, err := manifest.FindVersionInRange(versionRange, false)
+	if err != nil {
+		return layer, fmt.Errorf("unable to match Python version %s: %w", request.Version, err)
+	}
 
 	install := true
 	// Check to see if a cached layer has already been restored and compare the version to see if we should recreate it
 	if layer.Metadata["python_version"] != nil {
 		if version != fmt.Sprint(layer.Metadata["python_version"]) {
 			if err := os.RemoveAll(layer.Path); err != nil {
-				log.Fatal("Unable to remove ", layer.Path, ". ", err)
+				return layer, fmt.Errorf("unable to remove %s: %w", layer.Path, err)
 			}
 			install = true
 		} else {
@@ -98,82 +109,112 @@ func (contrib CPythonLayerContributor) Contribute(layer libcnb.Layer) (libcnb.La
 	}
 
 	if install {
-		dlUrl := manifest.FindDownloadUrl(version)
+		dlUrl, err := manifest.FindDownloadUrl(version)
+		if err != nil {
+			return layer, err
+		}
 		log.Println("Downloading python ", version, " from ", dlUrl)
-		tgzBytes := utils.DownloadBytesFromUrl(dlUrl)
+		tgzPath, _, err := cache.File(dlUrl)
+		if err != nil {
+			return layer, err
+		}
 		log.Println("Expanding tgz...")
-		utils.UntarBytes(tgzBytes, layer.Path, 0)
+		if err := os.MkdirAll(layer.Path, 0755); err != nil {
+			return layer, fmt.Errorf("unable to create %s: %w", layer.Path, err)
+		}
+		tgzFile, err := os.Open(tgzPath)
+		if err != nil {
+			return layer, fmt.Errorf("failed to open %s: %w", tgzPath, err)
+		}
+		err = utils.Untar(tgzFile, layer.Path, 0)
+		tgzFile.Close()
+		if err != nil {
+			return layer, err
+		}
 		// Delete source tarball
 		if err := os.Remove(filepath.Join(layer.Path, "Python-"+version+".tgz")); err != nil {
-			log.Fatal("Unable to remove Python source code tgz. ", err)
+			return layer, fmt.Errorf("unable to remove Python source code tgz: %w", err)
 		}
 
 		// Recursively fix hard coded paths in files. Several files have hard coded to expected Actions spot
 		log.Println("Fixing hardcoded paths...")
-		contrib.fixPathR(filepath.Join(layer.Path, "bin"), "#!/opt/hostedtoolcache/Python/"+version+"/"+manifest.OSArch(), "#!"+layer.Path)
+		if err := contrib.fixPathR(filepath.Join(layer.Path, "bin"), "#!/opt/hostedtoolcache/Python/"+version+"/"+manifest.OSArch(), "#!"+layer.Path); err != nil {
+			return layer, err
+		}
 
-		// Add PYTHON_VERSION env var
-		layer.SharedEnvironment.Default("PYTHON_VERSION", version)
 		// Update devcontainer.json search path for finalize buildpack to pull in properties
 		layer.BuildEnvironment.Append(devcontainer.FINALIZE_JSON_SEARCH_PATH_ENV_VAR_NAME, string(filepath.ListSeparator), layer.Path)
 	}
 
+	// Add PYTHON_VERSION env var even when reusing the layer since later buildpacks use it in cache keys
+	layer.SharedEnvironment.Default("PYTHON_VERSION", version)
+
 	// Set the layer types based on what was set for the contributor
 	layer.LayerTypes = contrib.LayerTypes
 	layer.Metadata = map[string]interface{}{
 		"python_version": version,
 	}
 	// Write devcontainer.json in all cases since its quick and we can avoid doing a checksum when caching
-	updatedBytes := bytes.ReplaceAll(devcontainerJsonBytes, []byte("{{layerDir}}"), []byte(layer.Path))
-	if err := utils.WriteFile(path.Join(layer.Path, "devcontainer.json"), updatedBytes); err != nil {
-		log.Fatal("Unable to write devcontainer.json. ", err)
+	if err := writeDevContainerJson(layer.Path, !venvInPlan(contrib.Context.Plan)); err != nil {
+		return layer, err
 	}
 
 	return layer, nil
 }
 
-func (contrib CPythonLayerContributor) versionInFile(name string, prefix string) (string, bool) {
-	versionFilePath := filepath.Join(contrib.Context.Application.Path, name)
-	// Get engine value for nodejs if it exists in package.json
-	if _, err := os.Stat(versionFilePath); err == nil {
-		content, err := os.ReadFile(versionFilePath)
-		if err != nil {
-			log.Fatal("Failed to read ", name, ". ", err)
-		}
-		text := string(content)
-		lines := strings.Split(text, "\n")
-		for _, line := range lines {
-			line := strings.TrimSpace(line)
-			if strings.HasPrefix(line, prefix) {
-				return strings.TrimPrefix(line, prefix), true
-			}
-		}
+// Writes devcontainer.json, leaving out python.defaultInterpreterPath if another buildpack sets it
+func writeDevContainerJson(layerPath string, setInterpreterPath bool) error {
+	var properties map[string]interface{}
+	if err := json.Unmarshal(bytes.ReplaceAll(devcontainerJsonBytes, []byte("{{layerDir}}"), []byte(layerPath)), &properties); err != nil {
+		return fmt.Errorf("failed to parse devcontainer.json asset: %w", err)
 	}
+	if !setInterpreterPath {
+		vscode := properties["customizations"].(map[string]interface{})["vscode"].(map[string]interface{})
+		delete(vscode["settings"].(map[string]interface{}), "python.defaultInterpreterPath")
+	}
+	updatedBytes, err := json.MarshalIndent(properties, "", "    ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal devcontainer.json: %w", err)
+	}
+	if err := utils.WriteFile(path.Join(layerPath, "devcontainer.json"), updatedBytes); err != nil {
+		return fmt.Errorf("unable to write devcontainer.json: %w", err)
+	}
+	return nil
+}
 
-	return "", false
+// Checks for the requirement from pipinstall, whose venv should be used as the interpreter instead
+func venvInPlan(plan libcnb.BuildpackPlan) bool {
+	for _, entry := range plan.Entries {
+		if venv, isBool := entry.Metadata[VENV_PLAN_METADATA_NAME].(bool); entry.Name == BUILDPACK_NAME && isBool && venv {
+			return true
+		}
+	}
+	return false
 }
 
-func (contrib CPythonLayerContributor) fixPathR(dir string, oldPath string, newPath string) {
+func (contrib CPythonLayerContributor) fixPathR(dir string, oldPath string, newPath string) error {
 	files, err := os.ReadDir(dir)
 	if err != nil {
-		log.Fatal("Failed to read contents of ", dir, " folder. ", err)
+		return fmt.Errorf("failed to read contents of %s folder: %w", dir, err)
 	}
 	for _, file := range files {
 		fullPath := filepath.Join(dir, file.Name())
 		if file.IsDir() {
-			contrib.fixPathR(filepath.Join(dir, file.Name()), oldPath, newPath)
+			if err := contrib.fixPathR(filepath.Join(dir, file.Name()), oldPath, newPath); err != nil {
+				return err
+			}
 		} else {
 			contents, err := os.ReadFile(fullPath)
 			if err != nil {
-				log.Fatal("Failed to read file ", fullPath, ". ", err)
+				return fmt.Errorf("failed to read file %s: %w", fullPath, err)
 			}
 			if bytes.Contains(contents, []byte(oldPath)) {
 				newContents := bytes.Replace(contents, []byte(oldPath), []byte(newPath), -1)
-				utils.WriteFile(fullPath, newContents)
-				if err != nil {
-					log.Fatal("Failed to write file ", fullPath, ". ", err)
+				if err := utils.WriteFile(fullPath, newContents); err != nil {
+					return fmt.Errorf("failed to write file %s: %w", fullPath, err)
 				}
 			}
 		}
 	}
+	return nil
 }
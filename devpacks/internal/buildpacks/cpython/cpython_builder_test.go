@@ -0,0 +1,148 @@
+package cpython
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/buildpacks/libcnb"
+	"github.com/chuxel/devpacks/internal/common/devcontainer"
+	"github.com/chuxel/devpacks/internal/common/harness"
+)
+
+func TestWriteDevContainerJson(t *testing.T) {
+	tests := []struct {
+		name            string
+		plan            libcnb.BuildpackPlan
+		expectedSetting bool
+	}{
+		{
+			name:            "interpreter set without a venv",
+			plan:            libcnb.BuildpackPlan{Entries: []libcnb.BuildpackPlanEntry{{Name: BUILDPACK_NAME, Metadata: map[string]interface{}{"build": true}}}},
+			expectedSetting: true,
+		},
+		{
+			name: "interpreter left to pipinstall's venv",
+			plan: libcnb.BuildpackPlan{Entries: []libcnb.BuildpackPlanEntry{
+				{Name: BUILDPACK_NAME, Metadata: map[string]interface{}{"build": true}},
+				{Name: BUILDPACK_NAME, Metadata: map[string]interface{}{VENV_PLAN_METADATA_NAME: true}},
+			}},
+			expectedSetting: false,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			layerPath := t.TempDir()
+			if err := writeDevContainerJson(layerPath, !venvInPlan(test.plan)); err != nil {
+				t.Fatal(err)
+			}
+			devContainer, err := devcontainer.NewDevContainer(layerPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			settings := devContainer.Properties["customizations"].(map[string]interface{})["vscode"].(map[string]interface{})["settings"].(map[string]interface{})
+			interpreterPath, hasSetting := settings["python.defaultInterpreterPath"]
+			if hasSetting != test.expectedSetting {
+				t.Errorf("expected python.defaultInterpreterPath to be set: %t, got %v", test.expectedSetting, settings)
+			}
+			if hasSetting && interpreterPath != layerPath+"/bin/python3" {
+				t.Errorf("expected interpreter in layer, got %s", interpreterPath)
+			}
+		})
+	}
+}
+
+const TEST_PYTHON_DOWNLOAD_BASE_URL = "https://github.com/actions/python-versions/releases/download"
+
+// Serves a versions-manifest.json and a stand-in tarball for each version, laid out like the real ones
+func addPythonReleaseFixtures(t *testing.T, transport *harness.StubTransport, versions map[string]bool) {
+	t.Helper()
+	dlArch := runtime.GOARCH
+	if dlArch == "amd64" {
+		dlArch = "x64"
+	}
+	type manifestFile struct {
+		Filename    string `json:"filename"`
+		Arch        string `json:"arch"`
+		Platform    string `json:"platform"`
+		DownloadUrl string `json:"download_url"`
+	}
+	type manifestEntry struct {
+		Version string         `json:"version"`
+		Stable  bool           `json:"stable"`
+		Files   []manifestFile `json:"files"`
+	}
+	manifest := []manifestEntry{}
+	for version, stable := range versions {
+		filename := "python-" + version + "-linux-" + dlArch + ".tar.gz"
+		dlUrl := TEST_PYTHON_DOWNLOAD_BASE_URL + "/" + version + "/" + filename
+		tgzBytes, err := harness.TarGz(map[string]string{
+			"bin/python3":                "#!/opt/hostedtoolcache/Python/" + version + "/" + dlArch + "/bin/python3.x\necho " + version + "\n",
+			"Python-" + version + ".tgz": "source",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		transport.AddBytes(dlUrl, tgzBytes)
+		manifest = append(manifest, manifestEntry{Version: version, Stable: stable, Files: []manifestFile{{Filename: filename, Arch: dlArch, Platform: "linux", DownloadUrl: dlUrl}}})
+	}
+	manifestBytes, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport.AddBytes(PYTHON_VERSIONS_MANIFEST_URL, manifestBytes)
+}
+
+func TestCPythonBuilder(t *testing.T) {
+	tests := []struct {
+		requestedVersion string
+		expectedVersion  string
+	}{
+		{"3.11", "3.11.7"},
+		{"", "3.12.1"},
+		{"~=3.11.2", "3.11.7"},
+		{"3.13.0rc1", "3.13.0-rc.1"},
+	}
+	for _, test := range tests {
+		t.Run(test.requestedVersion, func(t *testing.T) {
+			h, err := harness.NewHarness(BUILDPACK_NAME, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer h.Cleanup()
+			h.Setenv(PYTHON_VERSION_ENV_VAR_NAME, test.requestedVersion)
+			transport := harness.NewStubTransport()
+			addPythonReleaseFixtures(t, transport, map[string]bool{"3.11.7": true, "3.12.1": true, "3.13.0-rc.1": false})
+			h.UseStubTransport(transport)
+			plan, err := h.DefaultPlan()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			output, err := h.Build(CPythonBuilder{}, plan)
+			if err != nil {
+				t.Fatal(err)
+			}
+			layer, hasLayer := output.Layer(BUILDPACK_NAME)
+			if !hasLayer {
+				t.Fatal("no cpython layer contributed")
+			}
+			if pythonVersion, err := harness.ReadLayerEnvFile(layer, "env", "PYTHON_VERSION.default"); err != nil || pythonVersion != test.expectedVersion {
+				t.Errorf("expected PYTHON_VERSION %s, got %s (%v)", test.expectedVersion, pythonVersion, err)
+			}
+			python3, err := os.ReadFile(filepath.Join(layer.Path, "bin", "python3"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(string(python3), "#!"+layer.Path+"/bin/python3.x") {
+				t.Errorf("expected the hard coded path to be fixed, got %s", python3)
+			}
+			if _, err := os.Stat(filepath.Join(layer.Path, "Python-"+test.expectedVersion+".tgz")); err == nil {
+				t.Error("expected the source tarball to be removed")
+			}
+		})
+	}
+}
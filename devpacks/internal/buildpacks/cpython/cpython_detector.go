@@ -1,11 +1,9 @@
 package cpython
 
 import (
-	"fmt"
 	"log"
 	"os"
 	"path/filepath"
-	"strings"
 
 	"github.com/buildpacks/libcnb"
 	"github.com/chuxel/devpacks/internal/buildpacks/base"
@@ -34,12 +32,12 @@ func (detector CPythonDetector) AlwaysPass() bool {
 
 func (detector CPythonDetector) DoDetect(context libcnb.DetectContext) (bool, []libcnb.BuildPlanRequire, map[string]interface{}, error) {
 	// Can be specified in project.toml or pack command line
-	if os.Getenv("BP_CPYTHON_VERSION") != "" {
+	if os.Getenv(PYTHON_VERSION_ENV_VAR_NAME) != "" {
 		return true, nil, nil, nil
 	}
 
-	// Look for requirements.txt in the root - TODO: Others? e.g. any .py file?
-	filesToCheck := []string{"requirements.txt"}
+	// Look for Python project files in the root - TODO: Others? e.g. any .py file?
+	filesToCheck := []string{"requirements.txt", ".python-version", "pyproject.toml", "Pipfile"}
 	for _, file := range filesToCheck {
 		if _, err := os.Stat(filepath.Join(context.Application.Path, file)); err == nil {
 			log.Println("Detection passed.")
@@ -47,13 +45,11 @@ func (detector CPythonDetector) DoDetect(context libcnb.DetectContext) (bool, []
 		}
 	}
 
-	// See if runtime.txt is present, and contains a python reference
-	if _, err := os.Stat(filepath.Join(context.Application.Path, "runtime.txt")); err == nil {
-		contents, err := os.ReadFile(filepath.Join(context.Application.Path, "runtime.txt"))
-		if err != nil {
-			log.Fatal("Failed to read runtime.txt. ", err)
-		}
-		if strings.Contains(fmt.Sprint(contents), "python-") {
+	// runtime.txt and .tool-versions are also used by other languages, so check they reference python
+	for _, lookup := range []func(appPath string) (string, bool, error){runtimeTxtVersion, toolVersionsVersion} {
+		if _, found, err := lookup(context.Application.Path); err != nil {
+			return false, nil, nil, err
+		} else if found {
 			log.Println("Detection passed.")
 			return true, nil, nil, nil
 		}
@@ -0,0 +1,51 @@
+package cpython
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chuxel/devpacks/internal/common/harness"
+)
+
+func TestCPythonDetector(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		detected bool
+	}{
+		{"requirements.txt", map[string]string{"requirements.txt": "flask\n"}, true},
+		{".python-version", map[string]string{".python-version": "3.11\n"}, true},
+		{"runtime.txt", map[string]string{"runtime.txt": "python-3.11.7\n"}, true},
+		{"other runtime.txt", map[string]string{"runtime.txt": "java-17\n"}, false},
+		{"pyproject.toml", map[string]string{"pyproject.toml": "[tool.black]\n"}, true},
+		{"Pipfile", map[string]string{"Pipfile": "[packages]\nflask = \"*\"\n"}, true},
+		{".tool-versions", map[string]string{".tool-versions": "nodejs 18.18.2\npython 3.11.7\n"}, true},
+		{"other .tool-versions", map[string]string{".tool-versions": "nodejs 18.18.2\n"}, false},
+		{"no python files", map[string]string{"package.json": "{}"}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h, err := harness.NewHarness(BUILDPACK_NAME, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer h.Cleanup()
+			h.Setenv(PYTHON_VERSION_ENV_VAR_NAME, "")
+			for filename, content := range test.files {
+				if err := os.WriteFile(filepath.Join(h.ApplicationPath, filename), []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			result, err := h.Detect(CPythonDetector{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Always passes, but only requires itself when detected
+			_, requiresItself := harness.PlanRequire(result, BUILDPACK_NAME)
+			if !result.Pass || requiresItself != test.detected {
+				t.Errorf("expected pass with detected %t, got pass %t with detected %t", test.detected, result.Pass, requiresItself)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package cpython
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/chuxel/devpacks/internal/common/versions"
+)
+
+// A requested Python version (a PEP 440 specifier, version or Poetry range) and where it came from
+type PythonVersionRequest struct {
+	Version string
+	Source  string
+}
+
+// Finds the requested Python version by looking at BP_CPYTHON_VERSION, .python-version, runtime.txt,
+// requires-python in pyproject.toml, python_version in Pipfile and then asdf's .tool-versions, in
+// that order. Logs which source was used.
+func ResolvePythonVersionRequest(appPath string) (PythonVersionRequest, error) {
+	sources := []struct {
+		name   string
+		lookup func(appPath string) (string, bool, error)
+	}{
+		{PYTHON_VERSION_ENV_VAR_NAME, func(string) (string, bool, error) {
+			version := strings.TrimSpace(os.Getenv(PYTHON_VERSION_ENV_VAR_NAME))
+			return version, version != "", nil
+		}},
+		{".python-version", pythonVersionFileVersion},
+		{"runtime.txt", runtimeTxtVersion},
+		{"requires-python in pyproject.toml", pyprojectVersion},
+		{"python_version in Pipfile", pipfileVersion},
+		{".tool-versions", toolVersionsVersion},
+	}
+
+	for _, source := range sources {
+		version, found, err := source.lookup(appPath)
+		if err != nil {
+			return PythonVersionRequest{}, err
+		}
+		if !found {
+			continue
+		}
+		if _, err := versions.NewPythonRange(version); err != nil {
+			return PythonVersionRequest{}, fmt.Errorf("invalid Python version %s from %s: %w", version, source.name, err)
+		}
+		log.Printf("Using Python version %s from %s.\n", version, source.name)
+		return PythonVersionRequest{Version: version, Source: source.name}, nil
+	}
+	log.Println("No Python version specified, using", DEFAULT_PYTHON_VERSION)
+	return PythonVersionRequest{Version: DEFAULT_PYTHON_VERSION, Source: "default"}, nil
+}
+
+// .python-version can have several lines for pyenv. The first one that is a CPython version is used.
+func pythonVersionFileVersion(appPath string) (string, bool, error) {
+	lines, found, err := readLines(filepath.Join(appPath, ".python-version"))
+	if err != nil || !found {
+		return "", false, err
+	}
+	for _, line := range lines {
+		if line != "" && line[0] >= '0' && line[0] <= '9' {
+			return line, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// runtime.txt is in the form python-3.10.4
+func runtimeTxtVersion(appPath string) (string, bool, error) {
+	lines, found, err := readLines(filepath.Join(appPath, "runtime.txt"))
+	if err != nil || !found {
+		return "", false, err
+	}
+	for _, line := range lines {
+		if strings.HasPrefix(line, "python-") {
+			return strings.TrimPrefix(line, "python-"), true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// Uses requires-python from [project], or the python dependency for Poetry
+func pyprojectVersion(appPath string) (string, bool, error) {
+	pyprojectPath := filepath.Join(appPath, "pyproject.toml")
+	if _, err := os.Stat(pyprojectPath); err != nil {
+		return "", false, nil
+	}
+	type PyprojectToml struct {
+		Project struct {
+			RequiresPython string `toml:"requires-python"`
+		}
+		Tool struct {
+			Poetry struct {
+				Dependencies map[string]interface{}
+			}
+		}
+	}
+	var pyproject PyprojectToml
+	if _, err := toml.DecodeFile(pyprojectPath, &pyproject); err != nil {
+		return "", false, fmt.Errorf("failed to parse pyproject.toml: %w", err)
+	}
+	if pyproject.Project.RequiresPython != "" {
+		return pyproject.Project.RequiresPython, true, nil
+	}
+	if version, isString := pyproject.Tool.Poetry.Dependencies["python"].(string); isString && version != "" {
+		return version, true, nil
+	}
+	return "", false, nil
+}
+
+// Uses python_full_version or python_version from [requires]
+func pipfileVersion(appPath string) (string, bool, error) {
+	pipfilePath := filepath.Join(appPath, "Pipfile")
+	if _, err := os.Stat(pipfilePath); err != nil {
+		return "", false, nil
+	}
+	type Pipfile struct {
+		Requires struct {
+			PythonVersion     string `toml:"python_version"`
+			PythonFullVersion string `toml:"python_full_version"`
+		}
+	}
+	var pipfile Pipfile
+	if _, err := toml.DecodeFile(pipfilePath, &pipfile); err != nil {
+		return "", false, fmt.Errorf("failed to parse Pipfile: %w", err)
+	}
+	if pipfile.Requires.PythonFullVersion != "" {
+		return pipfile.Requires.PythonFullVersion, true, nil
+	}
+	return pipfile.Requires.PythonVersion, pipfile.Requires.PythonVersion != "", nil
+}
+
+// Finds the python entry in asdf's .tool-versions (e.g. "python 3.11.1 3.10.9"), using the first version
+func toolVersionsVersion(appPath string) (string, bool, error) {
+	lines, found, err := readLines(filepath.Join(appPath, ".tool-versions"))
+	if err != nil || !found {
+		return "", false, err
+	}
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "python" {
+			continue
+		}
+		for _, version := range fields[1:] {
+			// Skip asdf specific values like "system" and other implementations like "pypy3.9-7.3.9"
+			if version[0] >= '0' && version[0] <= '9' {
+				return version, true, nil
+			}
+		}
+	}
+	return "", false, nil
+}
+
+// Returns trimmed lines without comments, or false if the file does not exist
+func readLines(filePath string) ([]string, bool, error) {
+	if _, err := os.Stat(filePath); err != nil {
+		return nil, false, nil
+	}
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read %s: %w", filepath.Base(filePath), err)
+	}
+	lines := []string{}
+	for _, line := range strings.Split(string(content), "\n") {
+		if commentStart := strings.Index(line, "#"); commentStart >= 0 {
+			line = line[:commentStart]
+		}
+		lines = append(lines, strings.TrimSpace(line))
+	}
+	return lines, true, nil
+}
@@ -0,0 +1,146 @@
+package cpython
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/blang/semver/v4"
+	"github.com/chuxel/devpacks/internal/common/versions"
+)
+
+func TestResolvePythonVersionRequest(t *testing.T) {
+	tests := []struct {
+		name            string
+		envVersion      string
+		files           map[string]string
+		expectedVersion string
+		expectedSource  string
+		// A version the request should resolve to, to check the value is a usable range
+		matches string
+	}{
+		{
+			name:            "env var",
+			envVersion:      "3.11",
+			files:           map[string]string{".python-version": "3.10.4\n"},
+			expectedVersion: "3.11",
+			expectedSource:  PYTHON_VERSION_ENV_VAR_NAME,
+			matches:         "3.11.7",
+		},
+		{
+			name:            ".python-version skips other implementations",
+			files:           map[string]string{".python-version": "# pyenv\npypy3.9-7.3.9\n3.10.4\n", "runtime.txt": "python-3.9.1"},
+			expectedVersion: "3.10.4",
+			expectedSource:  ".python-version",
+			matches:         "3.10.4",
+		},
+		{
+			name:            "runtime.txt",
+			files:           map[string]string{"runtime.txt": "python-3.9.1\n", "pyproject.toml": "[project]\nrequires-python = \">=3.8\"\n"},
+			expectedVersion: "3.9.1",
+			expectedSource:  "runtime.txt",
+			matches:         "3.9.1",
+		},
+		{
+			name:            "requires-python in pyproject.toml",
+			files:           map[string]string{"pyproject.toml": "[project]\nrequires-python = \">=3.9,<3.12\"\n"},
+			expectedVersion: ">=3.9,<3.12",
+			expectedSource:  "requires-python in pyproject.toml",
+			matches:         "3.11.7",
+		},
+		{
+			name:            "Poetry caret",
+			files:           map[string]string{"pyproject.toml": "[tool.poetry.dependencies]\npython = \"^3.10\"\n"},
+			expectedVersion: "^3.10",
+			expectedSource:  "requires-python in pyproject.toml",
+			matches:         "3.12.1",
+		},
+		{
+			name:            "Poetry wildcard",
+			files:           map[string]string{"pyproject.toml": "[tool.poetry.dependencies]\npython = \"3.10.*\"\n"},
+			expectedVersion: "3.10.*",
+			expectedSource:  "requires-python in pyproject.toml",
+			matches:         "3.10.13",
+		},
+		{
+			name:            "Poetry space separated clauses",
+			files:           map[string]string{"pyproject.toml": "[tool.poetry.dependencies]\npython = \">=3.8 <4.0\"\n"},
+			expectedVersion: ">=3.8 <4.0",
+			expectedSource:  "requires-python in pyproject.toml",
+			matches:         "3.12.1",
+		},
+		{
+			name:            "python_version in Pipfile",
+			files:           map[string]string{"Pipfile": "[requires]\npython_version = \"3.9\"\n"},
+			expectedVersion: "3.9",
+			expectedSource:  "python_version in Pipfile",
+			matches:         "3.9.18",
+		},
+		{
+			name:            "python_full_version in Pipfile",
+			files:           map[string]string{"Pipfile": "[requires]\npython_version = \"3.9\"\npython_full_version = \"3.9.7\"\n"},
+			expectedVersion: "3.9.7",
+			expectedSource:  "python_version in Pipfile",
+			matches:         "3.9.7",
+		},
+		{
+			name:            ".tool-versions",
+			files:           map[string]string{".tool-versions": "nodejs 18.12.0\npython system 3.11.1 3.10.9\n"},
+			expectedVersion: "3.11.1",
+			expectedSource:  ".tool-versions",
+			matches:         "3.11.1",
+		},
+		{
+			name:            "default",
+			files:           map[string]string{"pyproject.toml": "[tool.black]\nline-length = 100\n"},
+			expectedVersion: DEFAULT_PYTHON_VERSION,
+			expectedSource:  "default",
+			matches:         "3.12.1",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv(PYTHON_VERSION_ENV_VAR_NAME, test.envVersion)
+			appPath := t.TempDir()
+			for filename, content := range test.files {
+				if err := os.WriteFile(filepath.Join(appPath, filename), []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			request, err := ResolvePythonVersionRequest(appPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if request.Version != test.expectedVersion || request.Source != test.expectedSource {
+				t.Errorf("expected %s from %s, got %s from %s", test.expectedVersion, test.expectedSource, request.Version, request.Source)
+			}
+			versionRange, err := versions.NewPythonRange(request.Version)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !versionRange(semver.MustParse(test.matches)) {
+				t.Errorf("expected %s to match %s", test.matches, request.Version)
+			}
+		})
+	}
+}
+
+func TestResolvePythonVersionRequestErrors(t *testing.T) {
+	tests := map[string]string{
+		"pyproject.toml": "[project]\nrequires-python = \">=3.9,<\"\n",
+		"Pipfile":        "[requires\n",
+		"runtime.txt":    "python-three\n",
+	}
+	for filename, content := range tests {
+		t.Run(filename, func(t *testing.T) {
+			t.Setenv(PYTHON_VERSION_ENV_VAR_NAME, "")
+			appPath := t.TempDir()
+			if err := os.WriteFile(filepath.Join(appPath, filename), []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := ResolvePythonVersionRequest(appPath); err == nil {
+				t.Errorf("expected an error for %s", content)
+			}
+		})
+	}
+}
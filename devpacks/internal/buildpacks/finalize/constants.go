@@ -1,3 +1,9 @@
 package finalize
 
 const BUILDPACK_NAME = "devpack-finalize"
+
+// Set to "true" to add the application's own devcontainer.json to the metadata label
+const INCLUDE_PROJECT_DEVCONTAINER_JSON_ENV_VAR_NAME = "BP_DCNB_INCLUDE_PROJECT_DEVCONTAINER_JSON"
+
+// Properties in the application's devcontainer.json that do not apply to the resulting image
+var EXCLUDED_PROJECT_DEVCONTAINER_JSON_PROPERTIES = []string{"image", "build", "dockerFile", "dockerComposeFile"}
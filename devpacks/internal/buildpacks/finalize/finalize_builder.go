@@ -3,6 +3,7 @@ package finalize
 import (
 	_ "embed"
 	"encoding/json"
+	"fmt"
 	"log"
 	"os"
 	"path"
@@ -10,6 +11,7 @@ import (
 
 	"github.com/buildpacks/libcnb"
 	"github.com/chuxel/devpacks/internal/common/devcontainer"
+	"github.com/chuxel/devpacks/internal/common/utils"
 )
 
 type FinalizeBuilder struct {
@@ -35,17 +37,32 @@ func (builder FinalizeBuilder) Build(context libcnb.BuildContext) (libcnb.BuildR
 	// For each path in search list and merge properties
 	for _, loc := range devcontainerJsonLocs {
 		if _, err := os.Stat(path.Join(loc, "devcontainer.json")); err == nil {
-			labelContents = append(labelContents, devcontainer.NewDevContainer(loc).Properties)
+			devContainer, err := devcontainer.NewDevContainer(loc)
+			if err != nil {
+				return result, err
+			}
+			labelContents = append(labelContents, devContainer.Properties)
 		}
 	}
 	// Force userEnvProbe to something other than "none" - needed so env vars are picked up
 	labelContents = append(labelContents, map[string]interface{}{"userEnvProbe": "loginInteractiveShell"})
 
+	// Optionally add the application's own devcontainer.json last so its settings win over buildpack defaults
+	if os.Getenv(INCLUDE_PROJECT_DEVCONTAINER_JSON_ENV_VAR_NAME) == "true" {
+		projectProperties, err := projectDevContainerProperties(context.Application.Path)
+		if err != nil {
+			return result, err
+		}
+		if projectProperties != nil {
+			labelContents = append(labelContents, projectProperties)
+		}
+	}
+
 	// Add the result to the label
 	log.Println("Adding dev container metadata content to label ", devcontainer.DEVCONTAINER_JSON_LABEL_NAME)
 	devContainerJsonBytes, err := json.Marshal(labelContents)
 	if err != nil {
-		log.Fatal(err)
+		return result, fmt.Errorf("failed to convert dev container metadata to json: %w", err)
 	}
 	result.Labels = []libcnb.Label{
 		{
@@ -68,11 +85,11 @@ func (builder FinalizeBuilder) Build(context libcnb.BuildContext) (libcnb.BuildR
 	log.Println("Removing workspace contents from image...")
 	files, err := os.ReadDir(context.Application.Path)
 	if err != nil {
-		log.Fatal("Failed to get directory contents in", context.Application.Path, "-", err)
+		return result, fmt.Errorf("failed to get directory contents in %s: %w", context.Application.Path, err)
 	}
 	for _, file := range files {
 		if err := os.RemoveAll(filepath.Join(context.Application.Path, file.Name())); err != nil {
-			log.Fatal(err)
+			return result, fmt.Errorf("failed to remove %s: %w", file.Name(), err)
 		}
 	}
 
@@ -88,3 +105,24 @@ func (builder FinalizeBuilder) Build(context libcnb.BuildContext) (libcnb.BuildR
 	return result, nil
 
 }
+
+// Returns the properties from the application's devcontainer.json that apply to the image, or nil if there isn't one
+func projectDevContainerProperties(applicationFolder string) (map[string]interface{}, error) {
+	devContainer := devcontainer.NewEmptyDevContainer()
+	devContainerJsonPath, err := devContainer.Load(applicationFolder)
+	if err != nil {
+		return nil, err
+	}
+	if devContainerJsonPath == "" {
+		log.Println("No devcontainer.json found in", applicationFolder)
+		return nil, nil
+	}
+	log.Println("Adding properties from", devContainerJsonPath)
+	properties := make(map[string]interface{}, len(devContainer.Properties))
+	for key, value := range devContainer.Properties {
+		if !utils.SliceContainsString(EXCLUDED_PROJECT_DEVCONTAINER_JSON_PROPERTIES, key) {
+			properties[key] = value
+		}
+	}
+	return properties, nil
+}
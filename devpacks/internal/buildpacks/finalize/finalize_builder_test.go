@@ -0,0 +1,88 @@
+package finalize
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chuxel/devpacks/internal/common/devcontainer"
+	"github.com/chuxel/devpacks/internal/common/harness"
+)
+
+func TestFinalizeBuilder(t *testing.T) {
+	tests := []struct {
+		name           string
+		includeProject bool
+		expectedLabels int
+	}{
+		{"buildpack devcontainer.json files", false, 3},
+		{"with the project devcontainer.json", true, 4},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h, err := harness.NewHarness("finalize", "test/test-project")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer h.Cleanup()
+			h.BuildMode = "devcontainer"
+			projectDevContainerJson := `{"image": "mcr.microsoft.com/devcontainers/base", "remoteUser": "vscode"}`
+			if err := os.MkdirAll(filepath.Join(h.ApplicationPath, ".devcontainer"), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(h.ApplicationPath, ".devcontainer", "devcontainer.json"), []byte(projectDevContainerJson), 0644); err != nil {
+				t.Fatal(err)
+			}
+			// Stands in for layers from buildpack-1 and buildpack-2, plus one without a devcontainer.json
+			searchPath := filepath.Join(h.Buildpack.Path, "buildpack-1") + string(filepath.ListSeparator) +
+				filepath.Join(h.Buildpack.Path, "missing") + string(filepath.ListSeparator) +
+				filepath.Join(h.Buildpack.Path, "buildpack-2")
+			h.Setenv(devcontainer.FINALIZE_JSON_SEARCH_PATH_ENV_VAR_NAME, searchPath)
+			if test.includeProject {
+				h.Setenv(INCLUDE_PROJECT_DEVCONTAINER_JSON_ENV_VAR_NAME, "true")
+			}
+			plan, err := h.DefaultPlan()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			output, err := h.Build(FinalizeBuilder{}, plan)
+			if err != nil {
+				t.Fatal(err)
+			}
+			metadata, err := harness.DevContainerMetadataLabel(output.Result)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(metadata) != test.expectedLabels {
+				t.Fatalf("expected %d entries in the label, got %d: %v", test.expectedLabels, len(metadata), metadata)
+			}
+			if _, hasCapAdd := metadata[1]["capAdd"]; !hasCapAdd {
+				t.Errorf("expected buildpack-2's properties second, got %v", metadata[1])
+			}
+			if metadata[2]["userEnvProbe"] != "loginInteractiveShell" {
+				t.Errorf("expected userEnvProbe to be set, got %v", metadata[2])
+			}
+			if test.includeProject {
+				if metadata[3]["remoteUser"] != "vscode" {
+					t.Errorf("expected the project's remoteUser last, got %v", metadata[3])
+				}
+				if _, hasImage := metadata[3]["image"]; hasImage {
+					t.Error("expected image to be left out of the project's properties")
+				}
+			}
+
+			process, hasProcess := output.Process("devcontainer")
+			if !hasProcess || !process.Default {
+				t.Errorf("expected a default devcontainer process, got %+v", output.Result.Processes)
+			}
+			if entries, err := os.ReadDir(h.ApplicationPath); err != nil || len(entries) != 0 {
+				t.Errorf("expected the workspace to be emptied, got %d entries (%v)", len(entries), err)
+			}
+			// Everything but the devpack-finalize entry
+			if len(output.Result.Unmet) != 3 {
+				t.Errorf("expected 3 unmet entries, got %v", output.Result.Unmet)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package finalize
+
+import (
+	"testing"
+
+	"github.com/chuxel/devpacks/internal/common/harness"
+)
+
+func TestFinalizeDetector(t *testing.T) {
+	for buildMode, expected := range map[string]bool{"devcontainer": true, "production": false} {
+		t.Run(buildMode, func(t *testing.T) {
+			h, err := harness.NewHarness("finalize", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer h.Cleanup()
+			h.BuildMode = buildMode
+			result, err := h.Detect(FinalizeDetector{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Pass != expected || harness.PlanProvides(result, BUILDPACK_NAME) != expected {
+				t.Errorf("expected detection to pass: %t, got %t", expected, result.Pass)
+			}
+		})
+	}
+}
@@ -0,0 +1,12 @@
+package golang
+
+const BUILDPACK_NAME = "golang"
+
+// Build plan entry provided by this buildpack. This is the same as the Paketo go-dist buildpack so
+// buildpacks like goutils can use either.
+const PLAN_ENTRY_NAME = "go"
+
+const GO_DOWNLOAD_BASE_URL = "https://go.dev/dl/"
+const GO_RELEASES_URL = GO_DOWNLOAD_BASE_URL + "?mode=json&include=all"
+const GO_VERSION_ENV_VAR_NAME = "BP_GO_VERSION"
+const DEFAULT_GO_VERSION = "^1.20.0"
@@ -0,0 +1,211 @@
+package golang
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	"github.com/buildpacks/libcnb"
+	"github.com/chuxel/devpacks/internal/buildpacks/base"
+	"github.com/chuxel/devpacks/internal/common/devcontainer"
+	"github.com/chuxel/devpacks/internal/common/downloads"
+	"github.com/chuxel/devpacks/internal/common/utils"
+	"github.com/chuxel/devpacks/internal/common/versions"
+)
+
+//go:embed assets/devcontainer.json
+var devcontainerJsonBytes []byte
+
+type GoRuntimeBuilder struct {
+	// Implements base.DefaultBuilder
+
+	// Build(context libcnb.BuildContext) (libcnb.BuildResult, error)
+	// Name() string
+	// NewLayerContributor(buildMode string, layerTypes libcnb.LayerTypes, context libcnb.BuildContext) libcnb.BaseLayerContributor
+}
+
+type GoRuntimeLayerContributor struct {
+	// Implements libcnb.LayerContributor
+
+	// Contribute(context libcnb.ContributeContext) (libcnb.Layer, error)
+	// Name() string
+
+	LayerTypes libcnb.LayerTypes
+	Context    libcnb.BuildContext
+	BuildMode  string
+}
+
+// A release in https://go.dev/dl/?mode=json
+type goRelease struct {
+	Version string
+	Files   []goReleaseFile
+}
+
+type goReleaseFile struct {
+	Filename string
+	Os       string
+	Arch     string
+	Sha256   string
+	Kind     string
+}
+
+func (builder GoRuntimeBuilder) Build(context libcnb.BuildContext) (libcnb.BuildResult, error) {
+	return base.DefaultBuild(builder, context)
+}
+
+// Implementation of base.BaseBuilder.Name
+func (builder GoRuntimeBuilder) Name() string {
+	return PLAN_ENTRY_NAME
+}
+
+// Implementation of base.BaseBuilder.NewLayerContributor
+func (builder GoRuntimeBuilder) NewLayerContributor(buildMode string, layerTypes libcnb.LayerTypes, context libcnb.BuildContext) libcnb.LayerContributor {
+	return GoRuntimeLayerContributor{BuildMode: buildMode, LayerTypes: layerTypes, Context: context}
+}
+
+// Implementation of base.AdditionalLayersBuilder.AdditionalLayerContributors
+func (builder GoRuntimeBuilder) AdditionalLayerContributors(buildMode string, context libcnb.BuildContext) []libcnb.LayerContributor {
+	return []libcnb.LayerContributor{downloads.CacheLayerContributor{}}
+}
+
+// Implementation of libcnb.LayerContributor.Name
+func (contrib GoRuntimeLayerContributor) Name() string {
+	return BUILDPACK_NAME
+}
+
+// Implementation of libcnb.LayerContributor.Contribute
+func (contrib GoRuntimeLayerContributor) Contribute(layer libcnb.Layer) (libcnb.Layer, error) {
+
+	// Version of Go to download
+	request, err := ResolveGoVersionRequest(contrib.Context.Application.Path)
+	if err != nil {
+		return layer, err
+	}
+
+	// Determine real Go version and archive to acquire (since requested could be a semver range)
+	cache := downloads.NewDownloadCache(contrib.Context)
+	goVersion, archive, err := findGoRelease(request.Version, runtime.GOARCH, cache)
+	if err != nil {
+		return layer, err
+	}
+
+	installGo := true
+	// Check to see if a cached layer has already been restored and compare the version to see if we should recreate it
+	if layer.Metadata["go_version"] != nil {
+		if goVersion != fmt.Sprint(layer.Metadata["go_version"]) {
+			if err := os.RemoveAll(layer.Path); err != nil {
+				return layer, fmt.Errorf("unable to remove %s: %w", layer.Path, err)
+			}
+		} else {
+			log.Println("Reusing cached layer.")
+			installGo = false
+		}
+	}
+
+	if installGo {
+		if err := downloadAndUntarGo(archive, layer.Path, cache); err != nil {
+			return layer, err
+		}
+	}
+
+	// Add GO_VERSION env var even when reusing the layer since later buildpacks use it in cache keys
+	layer.SharedEnvironment.Default("GO_VERSION", goVersion)
+
+	// Update devcontainer.json search path for finalize buildpack
+	layer.BuildEnvironment.Append(devcontainer.FINALIZE_JSON_SEARCH_PATH_ENV_VAR_NAME, string(filepath.ListSeparator), layer.Path)
+
+	// Set the layer types based on what was set for the contributor
+	layer.LayerTypes = contrib.LayerTypes
+	layer.Metadata = map[string]interface{}{
+		"go_version": goVersion,
+		"sha256":     archive.Sha256,
+	}
+	// Write devcontainer.json in all cases since its quick and we can avoid doing a checksum when caching
+	updatedBytes := bytes.ReplaceAll(devcontainerJsonBytes, []byte("{{layerDir}}"), []byte(layer.Path))
+	if err := utils.WriteFile(path.Join(layer.Path, "devcontainer.json"), updatedBytes); err != nil {
+		return layer, fmt.Errorf("unable to write devcontainer.json: %w", err)
+	}
+
+	return layer, nil
+}
+
+func downloadAndUntarGo(archive goReleaseFile, targetPath string, cache downloads.DownloadCache) error {
+	// Make sure target path exists
+	if err := os.MkdirAll(targetPath, 0755); err != nil {
+		return fmt.Errorf("unable to create %s: %w", targetPath, err)
+	}
+
+	// Download file to the cache first so we can do a checksum before expanding it
+	tgzPath, digest, err := cache.File(GO_DOWNLOAD_BASE_URL + archive.Filename)
+	if err != nil {
+		return err
+	}
+	if digest != strings.ToLower(archive.Sha256) {
+		return fmt.Errorf("checksum mismatch for %s: expected sha256 %s, got %s", archive.Filename, archive.Sha256, digest)
+	}
+	log.Println("Verified sha256 of", archive.Filename, "is", digest)
+
+	// Untar into the target location, removing the top level "go" folder
+	tgzFile, err := os.Open(tgzPath)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", tgzPath, err)
+	}
+	defer tgzFile.Close()
+	return utils.Untar(tgzFile, targetPath, 1)
+}
+
+// Finds the latest release in https://go.dev/dl/?mode=json&include=all that matches the requested
+// range and has an archive for the architecture (a GOARCH value). Returns the version without the "go"
+// prefix (e.g. 1.21.3) and the archive to download.
+func findGoRelease(requestedVersion string, arch string, cache downloads.DownloadCache) (string, goReleaseFile, error) {
+	goReleasesBytes, err := cache.Bytes(GO_RELEASES_URL)
+	if err != nil {
+		return "", goReleaseFile{}, err
+	}
+	goReleases := []goRelease{}
+	if err := json.Unmarshal(goReleasesBytes, &goReleases); err != nil {
+		return "", goReleaseFile{}, fmt.Errorf("failed to parse Go releases: %w", err)
+	}
+
+	expectedRange, err := utils.NewSemverRange(requestedVersion)
+	if err != nil {
+		return "", goReleaseFile{}, err
+	}
+	// Go uses armv6l for its arm archives
+	dlArch := arch
+	if dlArch == "arm" {
+		dlArch = "armv6l"
+	}
+
+	var latestVersion *semver.Version
+	var latestRelease goRelease
+	var latestArchive goReleaseFile
+	for _, release := range goReleases {
+		version, err := versions.ParseGoVersion(release.Version)
+		if err != nil {
+			// Skip any versions in an unexpected form
+			continue
+		}
+		if !expectedRange(version) || (latestVersion != nil && version.LTE(*latestVersion)) {
+			continue
+		}
+		for _, file := range release.Files {
+			if file.Os == "linux" && file.Arch == dlArch && file.Kind == "archive" {
+				latestVersion, latestRelease, latestArchive = &version, release, file
+				break
+			}
+		}
+	}
+	if latestVersion == nil {
+		return "", goReleaseFile{}, fmt.Errorf("unable to match Go version %s for linux/%s", requestedVersion, dlArch)
+	}
+	return strings.TrimPrefix(latestRelease.Version, "go"), latestArchive, nil
+}
@@ -0,0 +1,187 @@
+package golang
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/chuxel/devpacks/internal/common/downloads"
+	"github.com/chuxel/devpacks/internal/common/harness"
+)
+
+// Newest first like the real list, with a release candidate for the next minor version
+const TEST_GO_RELEASES_JSON = `[
+	{"version": "go1.22rc1", "files": [
+		{"filename": "go1.22rc1.linux-amd64.tar.gz", "os": "linux", "arch": "amd64", "sha256": "aa", "kind": "archive"},
+		{"filename": "go1.22rc1.linux-arm64.tar.gz", "os": "linux", "arch": "arm64", "sha256": "ab", "kind": "archive"}
+	]},
+	{"version": "go1.21.3", "files": [
+		{"filename": "go1.21.3.src.tar.gz", "os": "", "arch": "", "sha256": "ba", "kind": "source"},
+		{"filename": "go1.21.3.darwin-amd64.tar.gz", "os": "darwin", "arch": "amd64", "sha256": "bb", "kind": "archive"},
+		{"filename": "go1.21.3.linux-amd64.tar.gz", "os": "linux", "arch": "amd64", "sha256": "bc", "kind": "archive"},
+		{"filename": "go1.21.3.linux-armv6l.tar.gz", "os": "linux", "arch": "armv6l", "sha256": "bd", "kind": "archive"}
+	]},
+	{"version": "go1.21.2", "files": [
+		{"filename": "go1.21.2.linux-amd64.tar.gz", "os": "linux", "arch": "amd64", "sha256": "ca", "kind": "archive"},
+		{"filename": "go1.21.2.linux-arm64.tar.gz", "os": "linux", "arch": "arm64", "sha256": "cb", "kind": "archive"}
+	]},
+	{"version": "go1.21rc2", "files": [
+		{"filename": "go1.21rc2.linux-amd64.tar.gz", "os": "linux", "arch": "amd64", "sha256": "da", "kind": "archive"}
+	]},
+	{"version": "go1.20.10", "files": [
+		{"filename": "go1.20.10.linux-amd64.tar.gz", "os": "linux", "arch": "amd64", "sha256": "ea", "kind": "archive"},
+		{"filename": "go1.20.10.linux-amd64.msi", "os": "linux", "arch": "amd64", "sha256": "eb", "kind": "installer"}
+	]}
+]`
+
+func TestFindGoRelease(t *testing.T) {
+	transport := harness.NewStubTransport()
+	transport.AddBytes(GO_RELEASES_URL, []byte(TEST_GO_RELEASES_JSON))
+	h, err := harness.NewHarness(BUILDPACK_NAME, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Cleanup()
+	h.UseStubTransport(transport)
+	cache := downloads.DownloadCache{Path: t.TempDir()}
+
+	tests := []struct {
+		requestedVersion string
+		arch             string
+		expectedVersion  string
+		expectedFilename string
+		expectError      bool
+	}{
+		// Release candidates are skipped unless requested
+		{requestedVersion: "~1.21.0", arch: "amd64", expectedVersion: "1.21.3", expectedFilename: "go1.21.3.linux-amd64.tar.gz"},
+		{requestedVersion: "^1.20.0", arch: "amd64", expectedVersion: "1.21.3", expectedFilename: "go1.21.3.linux-amd64.tar.gz"},
+		{requestedVersion: "1.22.0-rc.1", arch: "amd64", expectedVersion: "1.22rc1", expectedFilename: "go1.22rc1.linux-amd64.tar.gz"},
+		{requestedVersion: "~1.20.0", arch: "amd64", expectedVersion: "1.20.10", expectedFilename: "go1.20.10.linux-amd64.tar.gz"},
+		// Releases without an archive for the architecture are skipped
+		{requestedVersion: "~1.21.0", arch: "arm64", expectedVersion: "1.21.2", expectedFilename: "go1.21.2.linux-arm64.tar.gz"},
+		{requestedVersion: "~1.21.0", arch: "arm", expectedVersion: "1.21.3", expectedFilename: "go1.21.3.linux-armv6l.tar.gz"},
+		{requestedVersion: "~1.21.0", arch: "riscv64", expectError: true},
+		{requestedVersion: "~1.19.0", arch: "amd64", expectError: true},
+	}
+	for _, test := range tests {
+		t.Run(test.requestedVersion+" "+test.arch, func(t *testing.T) {
+			version, archive, err := findGoRelease(test.requestedVersion, test.arch, cache)
+			if test.expectError {
+				if err == nil {
+					t.Errorf("expected an error, got %s", archive.Filename)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if version != test.expectedVersion || archive.Filename != test.expectedFilename {
+				t.Errorf("expected %s (%s), got %s (%s)", test.expectedVersion, test.expectedFilename, version, archive.Filename)
+			}
+		})
+	}
+}
+
+// Serves a release list with a stand-in archive for go1.21.3 on this architecture and the given digest
+// (or the real one if empty)
+func addGoReleaseFixtures(t *testing.T, transport *harness.StubTransport, digest string) goReleaseFile {
+	t.Helper()
+	dlArch := runtime.GOARCH
+	if dlArch == "arm" {
+		dlArch = "armv6l"
+	}
+	tgzBytes, err := harness.TarGz(map[string]string{
+		"go/bin/go":  "#!/bin/sh\necho go version go1.21.3\n",
+		"go/VERSION": "go1.21.3",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if digest == "" {
+		sum := sha256.Sum256(tgzBytes)
+		digest = hex.EncodeToString(sum[:])
+	}
+	archive := goReleaseFile{Filename: "go1.21.3.linux-" + dlArch + ".tar.gz", Os: "linux", Arch: dlArch, Sha256: digest, Kind: "archive"}
+	releasesJson := fmt.Sprintf(`[{"version": "go1.21.3", "files": [{"filename": "%s", "os": "linux", "arch": "%s", "sha256": "%s", "kind": "archive"}]}]`, archive.Filename, dlArch, digest)
+	transport.AddBytes(GO_RELEASES_URL, []byte(releasesJson))
+	transport.AddBytes(GO_DOWNLOAD_BASE_URL+archive.Filename, tgzBytes)
+	return archive
+}
+
+func TestDownloadAndUntarGoChecksumMismatch(t *testing.T) {
+	transport := harness.NewStubTransport()
+	archive := addGoReleaseFixtures(t, transport, strings.Repeat("0", 64))
+	h, err := harness.NewHarness(BUILDPACK_NAME, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Cleanup()
+	h.UseStubTransport(transport)
+	targetPath := filepath.Join(t.TempDir(), "go")
+	err = downloadAndUntarGo(archive, targetPath, downloads.DownloadCache{Path: t.TempDir()})
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("expected a checksum mismatch, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(targetPath, "bin", "go")); err == nil {
+		t.Error("expected nothing to be extracted after a checksum mismatch")
+	}
+}
+
+func TestGoRuntimeBuilder(t *testing.T) {
+	h, err := harness.NewHarness(BUILDPACK_NAME, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Cleanup()
+	if err := os.WriteFile(filepath.Join(h.ApplicationPath, "go.mod"), []byte("module test\n\ngo 1.21\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	h.Setenv(GO_VERSION_ENV_VAR_NAME, "")
+	transport := harness.NewStubTransport()
+	addGoReleaseFixtures(t, transport, "")
+	h.UseStubTransport(transport)
+	plan, err := h.DefaultPlan()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := h.Build(GoRuntimeBuilder{}, plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	layer, hasLayer := output.Layer(BUILDPACK_NAME)
+	if !hasLayer {
+		t.Fatal("no golang layer contributed")
+	}
+	if _, err := os.Stat(filepath.Join(layer.Path, "bin", "go")); err != nil {
+		t.Errorf("expected bin/go in layer without the top level go folder: %v", err)
+	}
+	if goVersion, err := harness.ReadLayerEnvFile(layer, "env", "GO_VERSION.default"); err != nil || goVersion != "1.21.3" {
+		t.Errorf("expected GO_VERSION 1.21.3, got %s (%v)", goVersion, err)
+	}
+	// Plan entries are build=false cache=true launch=false and build=true cache=false
+	if !layer.LayerTypes.Build || !layer.LayerTypes.Cache || layer.LayerTypes.Launch {
+		t.Errorf("unexpected layer types %+v", layer.LayerTypes)
+	}
+	if _, err := harness.LayerDevContainerJson(layer); err != nil {
+		t.Errorf("expected devcontainer.json in layer: %v", err)
+	}
+
+	// A second build reuses the layer without downloading the archive again
+	requestCount := len(transport.RequestedUrls())
+	output, err = h.Build(GoRuntimeBuilder{}, plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if urls := transport.RequestedUrls(); len(urls) != requestCount {
+		t.Errorf("expected no downloads when reusing the layer, got %v", urls[requestCount:])
+	}
+	if layer, _ := output.Layer(BUILDPACK_NAME); layer.Metadata["go_version"] != "1.21.3" {
+		t.Errorf("expected go_version metadata 1.21.3, got %v", layer.Metadata)
+	}
+}
@@ -0,0 +1,47 @@
+package golang
+
+import (
+	"log"
+	"os"
+	"path"
+
+	"github.com/buildpacks/libcnb"
+	"github.com/chuxel/devpacks/internal/buildpacks/base"
+)
+
+type GoRuntimeDetector struct {
+	// Implements base.DefaultDetector
+
+	// Detect(context libcnb.DetectContext) (libcnb.DetectResult, error)
+	// DoDetect(context libcnb.DetectContext) (bool, map[string]interface{}, error)
+	// Name() string
+	// AlwaysPass() bool
+}
+
+func (detector GoRuntimeDetector) Detect(context libcnb.DetectContext) (libcnb.DetectResult, error) {
+	return base.DefaultDetect(detector, context)
+}
+
+func (detector GoRuntimeDetector) Name() string {
+	return PLAN_ENTRY_NAME
+}
+
+func (detector GoRuntimeDetector) AlwaysPass() bool {
+	return true
+}
+
+func (detector GoRuntimeDetector) DoDetect(context libcnb.DetectContext) (bool, []libcnb.BuildPlanRequire, map[string]interface{}, error) {
+	// Can be specified in project.toml or pack command line
+	if os.Getenv(GO_VERSION_ENV_VAR_NAME) != "" {
+		return true, nil, nil, nil
+	}
+
+	// Look for go.mod in the root
+	if _, err := os.Stat(path.Join(context.Application.Path, "go.mod")); err != nil {
+		log.Println("No go.mod found in ", context.Application.Path)
+		return false, nil, nil, nil
+	}
+
+	log.Println("Detection passed.")
+	return true, nil, nil, nil
+}
@@ -0,0 +1,49 @@
+package golang
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chuxel/devpacks/internal/common/harness"
+)
+
+func TestGoRuntimeDetector(t *testing.T) {
+	tests := []struct {
+		name           string
+		goVersion      string
+		goMod          bool
+		expectedToPass bool
+	}{
+		{name: "go.mod", goMod: true, expectedToPass: true},
+		{name: "BP_GO_VERSION", goVersion: "1.21", expectedToPass: true},
+		{name: "no go.mod", expectedToPass: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h, err := harness.NewHarness(BUILDPACK_NAME, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer h.Cleanup()
+			h.Setenv(GO_VERSION_ENV_VAR_NAME, test.goVersion)
+			if test.goMod {
+				if err := os.WriteFile(filepath.Join(h.ApplicationPath, "go.mod"), []byte("module test\n\ngo 1.21\n"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			result, err := h.Detect(GoRuntimeDetector{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			// golang always passes so goutils can require go, but only requires go itself when detected
+			if !result.Pass || !harness.PlanProvides(result, PLAN_ENTRY_NAME) {
+				t.Fatalf("expected detection to pass and provide %s", PLAN_ENTRY_NAME)
+			}
+			if _, hasRequire := harness.PlanRequire(result, PLAN_ENTRY_NAME); hasRequire != test.expectedToPass {
+				t.Errorf("expected plan to require %s to be %v", PLAN_ENTRY_NAME, test.expectedToPass)
+			}
+		})
+	}
+}
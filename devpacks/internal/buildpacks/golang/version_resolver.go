@@ -0,0 +1,112 @@
+package golang
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/chuxel/devpacks/internal/common/versions"
+)
+
+// A requested Go version range and where it came from
+type GoVersionRequest struct {
+	Version string
+	Source  string
+}
+
+// Finds the requested Go version by looking at BP_GO_VERSION, the toolchain directive in go.mod and
+// then the go directive in go.mod, in that order. Logs which source was used.
+func ResolveGoVersionRequest(appPath string) (GoVersionRequest, error) {
+	sources := []struct {
+		name   string
+		lookup func(appPath string) (string, bool, error)
+	}{
+		{GO_VERSION_ENV_VAR_NAME, func(string) (string, bool, error) {
+			version := strings.TrimSpace(os.Getenv(GO_VERSION_ENV_VAR_NAME))
+			// Allow the same form as go.mod and go.dev/dl (e.g. go1.21.3)
+			if len(version) > 2 && strings.HasPrefix(version, "go") && version[2] >= '0' && version[2] <= '9' {
+				version = version[2:]
+			}
+			return version, version != "", nil
+		}},
+		{"toolchain directive in go.mod", goModToolchainVersion},
+		{"go directive in go.mod", goModGoVersion},
+	}
+
+	var request *GoVersionRequest
+	skipped := []string{}
+	for _, source := range sources {
+		version, found, err := source.lookup(appPath)
+		if err != nil {
+			return GoVersionRequest{}, err
+		}
+		if !found {
+			continue
+		}
+		if request == nil {
+			request = &GoVersionRequest{Version: version, Source: source.name}
+		} else {
+			skipped = append(skipped, fmt.Sprintf("%s (%s)", source.name, version))
+		}
+	}
+	if request == nil {
+		log.Println("No Go version specified, using default", DEFAULT_GO_VERSION)
+		return GoVersionRequest{Version: DEFAULT_GO_VERSION, Source: "default"}, nil
+	}
+	log.Printf("Using Go version %s from %s.\n", request.Version, request.Source)
+	if len(skipped) > 0 {
+		log.Println("Ignoring lower priority versions from:", strings.Join(skipped, ", "))
+	}
+	return *request, nil
+}
+
+// The toolchain directive (e.g. "toolchain go1.21.3") is an exact version
+func goModToolchainVersion(appPath string) (string, bool, error) {
+	toolchain, found, err := goModDirective(appPath, "toolchain")
+	if err != nil || !found || toolchain == "default" {
+		return "", false, err
+	}
+	version, err := versions.ParseGoVersion(toolchain)
+	if err != nil {
+		return "", false, fmt.Errorf("unsupported toolchain directive in go.mod: %w", err)
+	}
+	return version.String(), true, nil
+}
+
+// The go directive (e.g. "go 1.20" or "go 1.21.3") is a minimum version, so use the latest patch
+// release of the same minor version
+func goModGoVersion(appPath string) (string, bool, error) {
+	goDirective, found, err := goModDirective(appPath, "go")
+	if err != nil || !found {
+		return "", false, err
+	}
+	version, err := versions.ParseGoVersion(goDirective)
+	if err != nil {
+		return "", false, fmt.Errorf("unsupported go directive in go.mod: %w", err)
+	}
+	return "~" + version.String(), true, nil
+}
+
+// Returns the value of a single value directive like "go 1.20" in go.mod
+func goModDirective(appPath string, directive string) (string, bool, error) {
+	goModPath := filepath.Join(appPath, "go.mod")
+	if _, err := os.Stat(goModPath); err != nil {
+		return "", false, nil
+	}
+	content, err := os.ReadFile(goModPath)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read go.mod: %w", err)
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		if commentStart := strings.Index(line, "//"); commentStart >= 0 {
+			line = line[:commentStart]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == directive {
+			return fields[1], true, nil
+		}
+	}
+	return "", false, nil
+}
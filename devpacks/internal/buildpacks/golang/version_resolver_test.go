@@ -0,0 +1,111 @@
+package golang
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveGoVersionRequest(t *testing.T) {
+	tests := []struct {
+		name            string
+		envVersion      string
+		goMod           string
+		expectedVersion string
+		expectedSource  string
+	}{
+		{
+			name:            "env var",
+			envVersion:      "1.21.3",
+			goMod:           "module test\n\ngo 1.20\n",
+			expectedVersion: "1.21.3",
+			expectedSource:  GO_VERSION_ENV_VAR_NAME,
+		},
+		{
+			name:            "env var with go prefix",
+			envVersion:      "go1.21.3",
+			expectedVersion: "1.21.3",
+			expectedSource:  GO_VERSION_ENV_VAR_NAME,
+		},
+		{
+			name:            "env var range",
+			envVersion:      "~1.21",
+			expectedVersion: "~1.21",
+			expectedSource:  GO_VERSION_ENV_VAR_NAME,
+		},
+		{
+			name:            "toolchain directive",
+			goMod:           "module test\n\ngo 1.21\n\ntoolchain go1.21.3\n",
+			expectedVersion: "1.21.3",
+			expectedSource:  "toolchain directive in go.mod",
+		},
+		{
+			name:            "toolchain default",
+			goMod:           "module test\n\ngo 1.21.0\n\ntoolchain default\n",
+			expectedVersion: "~1.21.0",
+			expectedSource:  "go directive in go.mod",
+		},
+		{
+			name:            "go directive without patch",
+			goMod:           "module test\n\ngo 1.20\n",
+			expectedVersion: "~1.20.0",
+			expectedSource:  "go directive in go.mod",
+		},
+		{
+			name:            "go directive with patch",
+			goMod:           "module test\n\ngo 1.21.3\n",
+			expectedVersion: "~1.21.3",
+			expectedSource:  "go directive in go.mod",
+		},
+		{
+			name:            "comments",
+			goMod:           "module test // go 1.19\n\ngo 1.20 // minimum version\n// toolchain go1.22.0\n",
+			expectedVersion: "~1.20.0",
+			expectedSource:  "go directive in go.mod",
+		},
+		{
+			name:            "default",
+			goMod:           "module test\n",
+			expectedVersion: DEFAULT_GO_VERSION,
+			expectedSource:  "default",
+		},
+		{
+			name:            "no go.mod",
+			expectedVersion: DEFAULT_GO_VERSION,
+			expectedSource:  "default",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv(GO_VERSION_ENV_VAR_NAME, test.envVersion)
+			appPath := t.TempDir()
+			if test.goMod != "" {
+				if err := os.WriteFile(filepath.Join(appPath, "go.mod"), []byte(test.goMod), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			request, err := ResolveGoVersionRequest(appPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if request.Version != test.expectedVersion || request.Source != test.expectedSource {
+				t.Errorf("expected %s from %s, got %s from %s", test.expectedVersion, test.expectedSource, request.Version, request.Source)
+			}
+		})
+	}
+}
+
+func TestResolveGoVersionRequestErrors(t *testing.T) {
+	for _, goMod := range []string{"module test\n\ngo one.twenty\n", "module test\n\ngo 1.21\n\ntoolchain gotip\n"} {
+		t.Run(goMod, func(t *testing.T) {
+			t.Setenv(GO_VERSION_ENV_VAR_NAME, "")
+			appPath := t.TempDir()
+			if err := os.WriteFile(filepath.Join(appPath, "go.mod"), []byte(goMod), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := ResolveGoVersionRequest(appPath); err == nil {
+				t.Errorf("expected an error for %q", goMod)
+			}
+		})
+	}
+}
@@ -4,3 +4,15 @@ const BUILDPACK_NAME = "goutils"
 
 // Go tools that are isImportant && !replacedByGopls based on https://github.com/golang/vscode-go/blob/v0.31.1/src/goToolsInformation.ts
 const DEFAULT_GO_UTILS = "golang.org/x/tools/gopls@latest honnef.co/go/tools/cmd/staticcheck@latest golang.org/x/lint/golint@latest github.com/mgechev/revive@latest github.com/uudashr/gopkgs/v2/cmd/gopkgs@latest github.com/ramya-rao-a/go-outline@latest github.com/go-delve/delve/cmd/dlv@latest"
+
+// Layer used as GOPATH, which has the module cache for the project's go.mod pre-populated
+const GOPATH_LAYER_NAME = "gopath"
+
+// Cache-only layer for GOCACHE so tools and modules are not recompiled on every build
+const GOCACHE_LAYER_NAME = "go-build-cache"
+
+// Folder in the layer with a sub-folder for each tool. Binaries are linked into the layer's bin folder.
+const TOOLS_FOLDER_NAME = "tools"
+
+// Section of .devpacks/tools.toml with tools for this buildpack
+const TOOLS_FILE_ECOSYSTEM = "go"
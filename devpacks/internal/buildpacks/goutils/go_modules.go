@@ -0,0 +1,98 @@
+package goutils
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/buildpacks/libcnb"
+	"github.com/chuxel/devpacks/internal/buildpacks/base"
+	"github.com/chuxel/devpacks/internal/common/utils"
+)
+
+type GoPathLayerContributor struct {
+	// Implements libcnb.LayerContributor
+
+	// Contribute(context libcnb.ContributeContext) (libcnb.Layer, error)
+	// Name() string
+
+	Context libcnb.BuildContext
+}
+
+type GoBuildCacheLayerContributor struct {
+	// Implements libcnb.LayerContributor
+
+	// Contribute(context libcnb.ContributeContext) (libcnb.Layer, error)
+	// Name() string
+}
+
+// Implementation of libcnb.LayerContributor.Name
+func (contrib GoPathLayerContributor) Name() string {
+	return GOPATH_LAYER_NAME
+}
+
+// Implementation of libcnb.LayerContributor.Contribute
+func (contrib GoPathLayerContributor) Contribute(layer libcnb.Layer) (libcnb.Layer, error) {
+	goModCache := filepath.Join(layer.Path, "pkg", "mod")
+	os.Setenv("GOMODCACHE", goModCache)
+	// Binaries from "go install" end up in the layer's bin folder, which is already in the PATH
+	layer.SharedEnvironment.Override("GOPATH", layer.Path)
+	layer.SharedEnvironment.Override("GOMODCACHE", goModCache)
+	layer.LayerTypes = libcnb.LayerTypes{
+		Build:  true,
+		Cache:  true,
+		Launch: true,
+	}
+	if err := os.MkdirAll(goModCache, 0755); err != nil {
+		return layer, fmt.Errorf("unable to create layer folder %s: %w", goModCache, err)
+	}
+
+	goModPath := filepath.Join(contrib.Context.Application.Path, "go.mod")
+	if _, err := os.Stat(goModPath); err != nil {
+		log.Println("No go.mod found, skipping module download.")
+		return layer, nil
+	}
+	cacheKey := base.CacheKey{
+		Files:       []string{goModPath, filepath.Join(contrib.Context.Application.Path, "go.sum")},
+		EnvVarNames: []string{"GO_VERSION", "GOPROXY", "GOPRIVATE"},
+	}
+	currentHash, err := cacheKey.Sum()
+	if err != nil {
+		return layer, err
+	}
+	if base.LayerMatchesCacheKey(layer, currentHash) {
+		log.Println("Reusing cached module cache.")
+		return layer, nil
+	}
+
+	// The module cache is content addressed, so anything already in it is kept and only new modules are
+	// downloaded. -modcacherw leaves the files writable so the cache can be cleaned up in the dev container.
+	log.Println("Downloading modules in go.mod.")
+	if _, err := utils.ExecCmd(contrib.Context.Application.Path, false, "go", "mod", "download", "-modcacherw"); err != nil {
+		return layer, err
+	}
+	layer.Metadata = map[string]interface{}{
+		base.CACHE_KEY_METADATA_NAME: currentHash,
+	}
+	return layer, nil
+}
+
+// Implementation of libcnb.LayerContributor.Name
+func (contrib GoBuildCacheLayerContributor) Name() string {
+	return GOCACHE_LAYER_NAME
+}
+
+// Implementation of libcnb.LayerContributor.Contribute
+func (contrib GoBuildCacheLayerContributor) Contribute(layer libcnb.Layer) (libcnb.Layer, error) {
+	if err := os.MkdirAll(layer.Path, 0755); err != nil {
+		return layer, fmt.Errorf("unable to create layer folder %s: %w", layer.Path, err)
+	}
+	os.Setenv("GOCACHE", layer.Path)
+	layer.LayerTypes = libcnb.LayerTypes{
+		Build:  false,
+		Cache:  true,
+		Launch: false,
+	}
+	return layer, nil
+}
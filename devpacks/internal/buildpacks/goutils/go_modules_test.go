@@ -0,0 +1,84 @@
+package goutils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chuxel/devpacks/internal/buildpacks/base"
+)
+
+func TestGoPathLayerContributor(t *testing.T) {
+	h, logPath := newGoUtilsHarness(t, "")
+	defer h.Cleanup()
+	if err := os.WriteFile(filepath.Join(h.ApplicationPath, "go.mod"), []byte("module test\n\ngo 1.21\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	plan, err := h.DefaultPlan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	contrib := GoPathLayerContributor{Context: h.BuildContext(plan)}
+	layer, err := h.Layer(GOPATH_LAYER_NAME)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	layer, err = contrib.Contribute(layer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !layer.LayerTypes.Build || !layer.LayerTypes.Cache || !layer.LayerTypes.Launch {
+		t.Errorf("unexpected layer types %+v", layer.LayerTypes)
+	}
+	if layer.SharedEnvironment["GOPATH.override"] != layer.Path || layer.SharedEnvironment["GOMODCACHE.override"] != filepath.Join(layer.Path, "pkg", "mod") {
+		t.Errorf("expected GOPATH and GOMODCACHE in the layer, got %v", layer.SharedEnvironment)
+	}
+	if _, hasCacheKey := layer.Metadata[base.CACHE_KEY_METADATA_NAME]; !hasCacheKey {
+		t.Errorf("expected a cache key in the layer metadata, got %v", layer.Metadata)
+	}
+
+	// The same go.mod and go.sum reuse the module cache, and a changed go.sum downloads again
+	if layer, err = contrib.Contribute(layer); err != nil {
+		t.Fatal(err)
+	}
+	if goLog := readGoLog(t, logPath); len(goLog) != 1 || goLog[0] != "mod download -modcacherw" {
+		t.Errorf("expected go mod download to run once, got %v", goLog)
+	}
+	if err := os.WriteFile(filepath.Join(h.ApplicationPath, "go.sum"), []byte("golang.org/x/mod v0.14.0 h1:abc=\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = contrib.Contribute(layer); err != nil {
+		t.Fatal(err)
+	}
+	if goLog := readGoLog(t, logPath); len(goLog) != 2 {
+		t.Errorf("expected go mod download to run again for a changed go.sum, got %v", goLog)
+	}
+}
+
+func TestGoPathLayerContributorWithoutGoMod(t *testing.T) {
+	h, logPath := newGoUtilsHarness(t, "")
+	defer h.Cleanup()
+	plan, err := h.DefaultPlan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	layer, err := h.Layer(GOPATH_LAYER_NAME)
+	if err != nil {
+		t.Fatal(err)
+	}
+	layer, err = GoPathLayerContributor{Context: h.BuildContext(plan)}.Contribute(layer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// GOPATH is still set up for "go install" in the dev container
+	if _, err := os.Stat(filepath.Join(layer.Path, "pkg", "mod")); err != nil {
+		t.Errorf("expected the module cache folder to be created: %v", err)
+	}
+	if goLog := readGoLog(t, logPath); len(goLog) != 0 {
+		t.Errorf("expected go not to run without go.mod, got %v", goLog)
+	}
+	if len(layer.Metadata) != 0 {
+		t.Errorf("expected no cache key without go.mod, got %v", layer.Metadata)
+	}
+}
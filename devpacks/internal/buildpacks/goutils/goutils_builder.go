@@ -2,11 +2,8 @@ package goutils
 
 import (
 	"bytes"
-	"crypto/sha256"
 	_ "embed"
-	"encoding/base64"
 	"fmt"
-	"log"
 	"os"
 	"path"
 	"path/filepath"
@@ -15,6 +12,7 @@ import (
 	"github.com/buildpacks/libcnb"
 	"github.com/chuxel/devpacks/internal/buildpacks/base"
 	"github.com/chuxel/devpacks/internal/common/devcontainer"
+	"github.com/chuxel/devpacks/internal/common/tools"
 	"github.com/chuxel/devpacks/internal/common/utils"
 )
 
@@ -54,6 +52,11 @@ func (builder GoUtilsBuilder) NewLayerContributor(buildMode string, layerTypes l
 	return GoUtilsLayerContributor{BuildMode: buildMode, LayerTypes: layerTypes, Context: context}
 }
 
+// Implementation of base.AdditionalLayersBuilder.AdditionalLayerContributors
+func (builder GoUtilsBuilder) AdditionalLayerContributors(buildMode string, context libcnb.BuildContext) []libcnb.LayerContributor {
+	return []libcnb.LayerContributor{GoBuildCacheLayerContributor{}, GoPathLayerContributor{Context: context}}
+}
+
 // Implementation of libcnb.LayerContributor.Name
 func (contrib GoUtilsLayerContributor) Name() string {
 	return BUILDPACK_NAME
@@ -67,41 +70,32 @@ func (contrib GoUtilsLayerContributor) Contribute(layer libcnb.Layer) (libcnb.La
 	} else {
 		modList = strings.Split(DEFAULT_GO_UTILS, " ")
 	}
-
-	// Generate and verify checksum
-	hashCheckBytes := []byte(fmt.Sprint(os.Getenv("GO_VERSION"), " ", modList))
-	hashGen := sha256.New()
-	currentHash := base64.StdEncoding.EncodeToString(hashGen.Sum(hashCheckBytes))
-	if layer.Metadata["sha256"] != nil {
-		if currentHash == fmt.Sprint(layer.Metadata["sha256"]) {
-			layer.LayerTypes = contrib.LayerTypes
-			return layer, nil
-		}
-	}
-	// Clean out layer folder in the event we invalidated the cache
-	if err := os.RemoveAll(layer.Path); err != nil {
-		log.Fatal("Unable to remove ", layer.Path, ". ", err)
+	// Add or remove tools using .devpacks/tools.toml
+	toolsFile, err := tools.LoadToolsFile(contrib.Context.Application.Path)
+	if err != nil {
+		return layer, err
 	}
+	goTools := toolsFile.ResolveTools(TOOLS_FILE_ECOSYSTEM, modList, splitModuleVersion)
 
 	// Make sure target path exists
-	if err := os.MkdirAll(filepath.Join(layer.Path, "bin"), 0755); err != nil {
-		log.Fatal("Unable to create layer folder: ", layer.Path, err)
+	if err := os.MkdirAll(filepath.Join(layer.Path, TOOLS_FOLDER_NAME), 0755); err != nil {
+		return layer, fmt.Errorf("unable to create layer folder %s: %w", layer.Path, err)
 	}
 	// Write devcontainer.json in all cases since its quick and we can avoid doing a checksum when caching
-	updatedBytes := bytes.ReplaceAll(devcontainerJsonBytes, []byte("{{layerDir}}"), []byte(layer.Path))
+	updatedBytes, err := tools.AddToolSettings(devcontainerJsonBytes, goTools)
+	if err != nil {
+		return layer, err
+	}
+	updatedBytes = bytes.ReplaceAll(updatedBytes, []byte("{{layerDir}}"), []byte(layer.Path))
 	if err := utils.WriteFile(path.Join(layer.Path, "devcontainer.json"), updatedBytes); err != nil {
-		log.Fatal("Failed to write devcontainer.json: ", err)
+		return layer, fmt.Errorf("failed to write devcontainer.json: %w", err)
 	}
 
-	// Install tools
-	goTmp := filepath.Join("/tmp", "tool-tmp")
-	os.Setenv("GOPATH", goTmp)
-	os.Setenv("GOCACHE", filepath.Join(goTmp, "cache"))
-	for _, mod := range modList {
-		utils.ExecCmd(layer.Path, false, "go", "install", mod)
+	// Each tool is in its own folder and only reinstalled if its pinned version or the Go version changed
+	pinnedTools, err := installGoTools(layer, goTools)
+	if err != nil {
+		return layer, err
 	}
-	// Move binaries (only)
-	utils.CpR(filepath.Join(goTmp, "bin"), layer.Path)
 
 	// Update devcontainer.json search path for finalize buildpack to pull in properties
 	layer.BuildEnvironment.Append(devcontainer.FINALIZE_JSON_SEARCH_PATH_ENV_VAR_NAME, string(filepath.ListSeparator), layer.Path)
@@ -109,7 +103,7 @@ func (contrib GoUtilsLayerContributor) Contribute(layer libcnb.Layer) (libcnb.La
 	// Set the layer types based on what was set for the contributor
 	layer.LayerTypes = contrib.LayerTypes
 	layer.Metadata = map[string]interface{}{
-		"sha256": currentHash,
+		base.PINNED_TOOLS_METADATA_NAME: base.PinnedToolsMetadata(pinnedTools),
 	}
 
 	return layer, nil
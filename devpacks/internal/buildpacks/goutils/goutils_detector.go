@@ -7,7 +7,9 @@ import (
 
 	"github.com/buildpacks/libcnb"
 	"github.com/chuxel/devpacks/internal/buildpacks/base"
+	"github.com/chuxel/devpacks/internal/buildpacks/golang"
 	"github.com/chuxel/devpacks/internal/common/devcontainer"
+	"github.com/chuxel/devpacks/internal/common/tools"
 )
 
 type GoUtilsDetector struct {
@@ -37,14 +39,24 @@ func (detector GoUtilsDetector) DoDetect(context libcnb.DetectContext) (bool, []
 		return false, nil, nil, nil
 	}
 
-	// This buildpack always requires the "go" buildpack
-	reqs := []libcnb.BuildPlanRequire{{Name: "go", Metadata: map[string]interface{}{
+	// This buildpack always requires go, which the golang buildpack provides
+	reqs := []libcnb.BuildPlanRequire{{Name: golang.PLAN_ENTRY_NAME, Metadata: map[string]interface{}{
 		"build":  true,
 		"launch": true,
 	}}}
 
 	// Can be specified in project.toml or pack command line
-	if os.Getenv("BP_GO_VERSION") != "" || os.Getenv("BP_GO_UTILS") != "" {
+	if os.Getenv(golang.GO_VERSION_ENV_VAR_NAME) != "" || os.Getenv("BP_GO_UTILS") != "" {
+		return true, reqs, nil, nil
+	}
+
+	// Or a tools section in .devpacks/tools.toml
+	toolsFile, err := tools.LoadToolsFile(context.Application.Path)
+	if err != nil {
+		return false, nil, nil, err
+	}
+	if toolsFile.HasEcosystem(TOOLS_FILE_ECOSYSTEM) {
+		log.Println("Detection passed.")
 		return true, reqs, nil, nil
 	}
 
@@ -0,0 +1,64 @@
+package goutils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chuxel/devpacks/internal/common/harness"
+)
+
+func TestGoUtilsDetector(t *testing.T) {
+	tests := []struct {
+		name           string
+		buildMode      string
+		goUtils        string
+		files          map[string]string
+		expectedToPass bool
+	}{
+		{name: "go.mod", buildMode: "devcontainer", files: map[string]string{"go.mod": "module test\n"}, expectedToPass: true},
+		{name: "production", buildMode: "production", files: map[string]string{"go.mod": "module test\n"}, expectedToPass: false},
+		{name: "BP_GO_UTILS", buildMode: "devcontainer", goUtils: "golang.org/x/tools/gopls@latest", expectedToPass: true},
+		{name: "tools.toml go section", buildMode: "devcontainer", files: map[string]string{".devpacks/tools.toml": "[go.tools.\"golang.org/x/tools/gopls\"]\n"}, expectedToPass: true},
+		{name: "tools.toml other section", buildMode: "devcontainer", files: map[string]string{".devpacks/tools.toml": "[node.tools.typescript]\n"}, expectedToPass: false},
+		{name: "no Go", buildMode: "devcontainer", files: map[string]string{"package.json": `{}`}, expectedToPass: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h, err := harness.NewHarness(BUILDPACK_NAME, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer h.Cleanup()
+			h.BuildMode = test.buildMode
+			h.Setenv("BP_GO_UTILS", test.goUtils)
+			h.Setenv("BP_GO_VERSION", "")
+			for filename, content := range test.files {
+				if err := os.MkdirAll(filepath.Dir(filepath.Join(h.ApplicationPath, filename)), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(h.ApplicationPath, filename), []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			result, err := h.Detect(GoUtilsDetector{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			// goutils is optional, so detection passes either way but only requires itself when detected
+			if !result.Pass {
+				t.Fatal("expected detection to pass")
+			}
+			if _, hasRequire := harness.PlanRequire(result, BUILDPACK_NAME); hasRequire != test.expectedToPass {
+				t.Fatalf("expected plan to require %s to be %v", BUILDPACK_NAME, test.expectedToPass)
+			}
+			if !test.expectedToPass {
+				return
+			}
+			if require, hasRequire := harness.PlanRequire(result, "go"); !hasRequire || require.Metadata["build"] != true {
+				t.Errorf("expected go to be required during the build, got %+v", require)
+			}
+		})
+	}
+}
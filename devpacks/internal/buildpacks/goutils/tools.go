@@ -0,0 +1,139 @@
+package goutils
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/buildpacks/libcnb"
+	"github.com/chuxel/devpacks/internal/buildpacks/base"
+	"github.com/chuxel/devpacks/internal/common/tools"
+	"github.com/chuxel/devpacks/internal/common/utils"
+)
+
+// Module versions like 0.11.0 from .devpacks/tools.toml that need a "v" prefix for go install
+var semverWithoutPrefixRegexp = regexp.MustCompile(`^[0-9]+\.[0-9]+(\.[0-9]+)?([-+].*)?$`)
+
+// Installs each tool (e.g. golang.org/x/tools/gopls with version "latest") into its own folder under the layer's tools
+// folder, reusing tools from a previous build when possible, and links their binaries into the layer's
+// bin folder. A version like "latest" is resolved once and then pinned in layer metadata.
+func installGoTools(layer libcnb.Layer, goTools []tools.Tool) ([]base.PinnedTool, error) {
+	previousTools := base.PinnedToolsFromLayer(layer)
+	toolsPath := filepath.Join(layer.Path, TOOLS_FOLDER_NAME)
+
+	// Tool modules are downloaded to a temp GOPATH so they do not end up in the project's module
+	// cache, while GOCACHE is the cache-only layer.
+	goTmp, err := os.MkdirTemp("", "tool-tmp-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp folder: %w", err)
+	}
+	defer os.RemoveAll(goTmp)
+	projectGoPath, projectGoModCache := os.Getenv("GOPATH"), os.Getenv("GOMODCACHE")
+	os.Setenv("GOPATH", goTmp)
+	os.Setenv("GOMODCACHE", filepath.Join(goTmp, "pkg", "mod"))
+	defer func() {
+		os.Setenv("GOPATH", projectGoPath)
+		os.Setenv("GOMODCACHE", projectGoModCache)
+		os.Unsetenv("GOBIN")
+	}()
+
+	pinnedTools := []base.PinnedTool{}
+	toolFolderNames := []string{}
+	for _, tool := range goTools {
+		name, requested := tool.Name, goToolVersion(tool.Version)
+		version := requested
+		previousTool, hasPrevious := previousTools[name]
+		if hasPrevious && previousTool.Requested == requested {
+			version = previousTool.Version
+		}
+		folderName := base.ToolFolderName(name)
+		toolFolderNames = append(toolFolderNames, folderName)
+		toolBinPath := filepath.Join(toolsPath, folderName, "bin")
+
+		cacheKey, err := goToolCacheKey(name, version)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := os.Stat(toolBinPath); err == nil && hasPrevious && previousTool.CacheKey == cacheKey {
+			log.Printf("Reusing %s@%s.\n", name, version)
+			pinnedTools = append(pinnedTools, previousTool)
+			continue
+		}
+
+		if err := os.RemoveAll(filepath.Join(toolsPath, folderName)); err != nil {
+			return nil, fmt.Errorf("failed to remove %s: %w", folderName, err)
+		}
+		os.Setenv("GOBIN", toolBinPath)
+		if _, err := utils.ExecCmd(layer.Path, false, "go", "install", "-modcacherw", name+"@"+version); err != nil {
+			return nil, err
+		}
+		resolvedVersion, err := installedGoToolVersion(toolBinPath)
+		if err != nil {
+			return nil, err
+		}
+		if resolvedVersion != version {
+			log.Printf("Pinned %s@%s to %s.\n", name, version, resolvedVersion)
+		}
+		if cacheKey, err = goToolCacheKey(name, resolvedVersion); err != nil {
+			return nil, err
+		}
+		pinnedTools = append(pinnedTools, base.PinnedTool{Name: name, Requested: requested, Version: resolvedVersion, CacheKey: cacheKey})
+	}
+
+	if err := base.RemoveUnlistedToolFolders(toolsPath, toolFolderNames); err != nil {
+		return nil, err
+	}
+	if err := base.LinkToolBinaries(layer.Path, toolsPath, toolFolderNames); err != nil {
+		return nil, err
+	}
+	return pinnedTools, nil
+}
+
+// Splits golang.org/x/tools/gopls@v0.11.0 into the package and version, defaulting to "latest"
+func splitModuleVersion(mod string) (string, string) {
+	if at := strings.LastIndex(mod, "@"); at > 0 {
+		return mod[:at], mod[at+1:]
+	}
+	return mod, "latest"
+}
+
+// Returns the version to use with go install. Empty is "latest", and versions from .devpacks/tools.toml
+// can be written without the "v" (e.g. 0.11.0 rather than v0.11.0).
+func goToolVersion(version string) string {
+	if version == "" {
+		return "latest"
+	}
+	if semverWithoutPrefixRegexp.MatchString(version) {
+		return "v" + version
+	}
+	return version
+}
+
+// Static binaries only depend on the Go version used to build them
+func goToolCacheKey(name string, version string) (string, error) {
+	cacheKey := base.CacheKey{EnvVarNames: []string{"GO_VERSION"}, Values: []string{name, version}}
+	return cacheKey.Sum()
+}
+
+// Uses "go version -m" to find the module version a binary was built from
+func installedGoToolVersion(toolBinPath string) (string, error) {
+	entries, err := os.ReadDir(toolBinPath)
+	if err != nil || len(entries) == 0 {
+		return "", fmt.Errorf("no binary installed in %s", toolBinPath)
+	}
+	output, err := utils.ExecCmd("", true, "go", "version", "-m", filepath.Join(toolBinPath, entries[0].Name()))
+	if err != nil {
+		return "", err
+	}
+	// The main module is on a line in the form "\tmod\t<path>\t<version>\t<sum>"
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 3 && fields[0] == "mod" {
+			return fields[2], nil
+		}
+	}
+	return "", fmt.Errorf("unable to determine the version of %s", entries[0].Name())
+}
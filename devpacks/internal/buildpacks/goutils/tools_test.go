@@ -0,0 +1,202 @@
+package goutils
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/chuxel/devpacks/internal/buildpacks/base"
+	"github.com/chuxel/devpacks/internal/common/harness"
+	"github.com/chuxel/devpacks/internal/common/tools"
+)
+
+func TestGoToolVersion(t *testing.T) {
+	tests := map[string]string{
+		"":                   "latest",
+		"latest":             "latest",
+		"v0.11.0":            "v0.11.0",
+		"0.11.0":             "v0.11.0",
+		"1.2":                "v1.2",
+		"0.12.0-pre.1":       "v0.12.0-pre.1",
+		"master":             "master",
+		"2023.1.6":           "v2023.1.6",
+		"4e6ef9cb8a4c":       "4e6ef9cb8a4c",
+		"1234567":            "1234567",
+		"v0.0.0-20230601000": "v0.0.0-20230601000",
+	}
+	for version, expected := range tests {
+		if actual := goToolVersion(version); actual != expected {
+			t.Errorf("expected %q for %q, got %q", expected, version, actual)
+		}
+	}
+}
+
+// Creates a harness with a fake go that records each command in go.log. Installed binaries contain the
+// output "go version -m" would have for them, with "latest" resolving to latestVersion.
+func newGoUtilsHarness(t *testing.T, latestVersion string) (*harness.Harness, string) {
+	t.Helper()
+	h, err := harness.NewHarness(BUILDPACK_NAME, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	logPath := filepath.Join(t.TempDir(), "go.log")
+	if err := h.AddFakeCommand("go", `case "$1" in
+install)
+	echo "$@" >> `+logPath+`
+	name="${3%@*}"
+	version="${3##*@}"
+	if [ "$version" = "latest" ]; then version="`+latestVersion+`"; fi
+	mkdir -p "$GOBIN"
+	printf '\tpath\t%s\n\tmod\t%s\t%s\th1:abc=\n' "$name" "$name" "$version" > "$GOBIN/$(basename "$name")"
+	;;
+version)
+	echo "$3: go1.21.3"
+	cat "$3"
+	;;
+mod)
+	echo "$@" >> `+logPath+`
+	mkdir -p "$GOMODCACHE/cache/download"
+	;;
+esac`); err != nil {
+		h.Cleanup()
+		t.Fatal(err)
+	}
+	// Set by the buildpack for the process, so have the harness restore them afterwards
+	for _, name := range []string{"GOPATH", "GOMODCACHE", "GOBIN", "GOCACHE"} {
+		h.Setenv(name, os.Getenv(name))
+	}
+	h.Setenv("GO_VERSION", "1.21.3")
+	return h, logPath
+}
+
+func readGoLog(t *testing.T, logPath string) []string {
+	t.Helper()
+	content, err := os.ReadFile(logPath)
+	if os.IsNotExist(err) {
+		return []string{}
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSpace(string(content)), "\n")
+}
+
+func TestInstallGoTools(t *testing.T) {
+	h, logPath := newGoUtilsHarness(t, "v0.11.0")
+	defer h.Cleanup()
+	layer, err := h.Layer(BUILDPACK_NAME)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(layer.Path, 0755); err != nil {
+		t.Fatal(err)
+	}
+	goTools := []tools.Tool{{Name: "golang.org/x/tools/gopls"}, {Name: "github.com/go-delve/delve/cmd/dlv", Version: "1.21.0"}}
+
+	// "latest" is pinned to the version it resolved to, and the tools.toml version gets a "v"
+	pinnedTools, err := installGoTools(layer, goTools)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []struct{ requested, version string }{{"latest", "v0.11.0"}, {"v1.21.0", "v1.21.0"}}
+	for i, tool := range pinnedTools {
+		if tool.Requested != expected[i].requested || tool.Version != expected[i].version {
+			t.Errorf("expected %s to be %s pinned to %s, got %+v", tool.Name, expected[i].requested, expected[i].version, tool)
+		}
+	}
+	for _, binary := range []string{"gopls", "dlv"} {
+		if _, err := os.Stat(filepath.Join(layer.Path, "bin", binary)); err != nil {
+			t.Errorf("expected %s to be linked into the layer's bin folder: %v", binary, err)
+		}
+	}
+	expectedLog := []string{"install -modcacherw golang.org/x/tools/gopls@latest", "install -modcacherw github.com/go-delve/delve/cmd/dlv@v1.21.0"}
+	if goLog := readGoLog(t, logPath); !reflect.DeepEqual(goLog, expectedLog) {
+		t.Errorf("expected %v, got %v", expectedLog, goLog)
+	}
+
+	// A second build reuses both tools
+	layer.Metadata = map[string]interface{}{base.PINNED_TOOLS_METADATA_NAME: base.PinnedToolsMetadata(pinnedTools)}
+	if pinnedTools, err = installGoTools(layer, goTools); err != nil {
+		t.Fatal(err)
+	}
+	if goLog := readGoLog(t, logPath); len(goLog) != 2 {
+		t.Errorf("expected tools to be reused, got %v", goLog[2:])
+	}
+
+	// A new Go version reinstalls the pinned versions rather than "latest"
+	h.Setenv("GO_VERSION", "1.22.0")
+	layer.Metadata = map[string]interface{}{base.PINNED_TOOLS_METADATA_NAME: base.PinnedToolsMetadata(pinnedTools)}
+	if pinnedTools, err = installGoTools(layer, goTools); err != nil {
+		t.Fatal(err)
+	}
+	expectedLog = append(expectedLog, "install -modcacherw golang.org/x/tools/gopls@v0.11.0", "install -modcacherw github.com/go-delve/delve/cmd/dlv@v1.21.0")
+	if goLog := readGoLog(t, logPath); !reflect.DeepEqual(goLog, expectedLog) {
+		t.Errorf("expected %v, got %v", expectedLog, goLog)
+	}
+
+	// Tools that are no longer listed are removed
+	layer.Metadata = map[string]interface{}{base.PINNED_TOOLS_METADATA_NAME: base.PinnedToolsMetadata(pinnedTools)}
+	if _, err = installGoTools(layer, goTools[:1]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(layer.Path, TOOLS_FOLDER_NAME, base.ToolFolderName(goTools[1].Name))); !os.IsNotExist(err) {
+		t.Errorf("expected dlv's folder to be removed, got %v", err)
+	}
+	if _, err := os.Lstat(filepath.Join(layer.Path, "bin", "dlv")); !os.IsNotExist(err) {
+		t.Errorf("expected dlv's link to be removed, got %v", err)
+	}
+	if goLog := readGoLog(t, logPath); len(goLog) != 4 {
+		t.Errorf("expected gopls to be reused, got %v", goLog[4:])
+	}
+}
+
+func TestInstalledGoToolVersion(t *testing.T) {
+	h, _ := newGoUtilsHarness(t, "")
+	defer h.Cleanup()
+	toolBinPath := filepath.Join(t.TempDir(), "bin")
+	if _, err := installedGoToolVersion(toolBinPath); err == nil {
+		t.Error("expected an error without a binary")
+	}
+	if err := os.MkdirAll(toolBinPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name            string
+		versionOutput   string
+		expectedVersion string
+	}{
+		{
+			name:            "module version",
+			versionOutput:   "\tpath\tgolang.org/x/tools/gopls\n\tmod\tgolang.org/x/tools/gopls\tv0.14.1\th1:abc=\n\tdep\tgolang.org/x/mod\tv0.14.0\th1:def=\n",
+			expectedVersion: "v0.14.1",
+		},
+		{
+			name:            "pseudo-version",
+			versionOutput:   "\tpath\thonnef.co/go/tools/cmd/staticcheck\n\tmod\thonnef.co/go/tools\tv0.4.7-0.20230101000000-abcdef123456\th1:abc=\n",
+			expectedVersion: "v0.4.7-0.20230101000000-abcdef123456",
+		},
+		{
+			name:          "no main module",
+			versionOutput: "\tpath\tcommand-line-arguments\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := os.WriteFile(filepath.Join(toolBinPath, "tool"), []byte(test.versionOutput), 0755); err != nil {
+				t.Fatal(err)
+			}
+			version, err := installedGoToolVersion(toolBinPath)
+			if test.expectedVersion == "" {
+				if err == nil {
+					t.Errorf("expected an error, got %s", version)
+				}
+				return
+			}
+			if err != nil || version != test.expectedVersion {
+				t.Errorf("expected %s, got %s (%v)", test.expectedVersion, version, err)
+			}
+		})
+	}
+}
@@ -1,3 +1,8 @@
 package nodejs
 
 const BUILDPACK_NAME = "nodejs"
+const NODE_RELEASE_BASE_URL = "https://nodejs.org/download/release"
+const NODE_SHASUMS_FILENAME = "SHASUMS256.txt"
+const COREPACK_HOME_FOLDER_NAME = "corepack"
+const NODE_VERSION_ENV_VAR_NAME = "BP_NODE_VERSION"
+const DEFAULT_NODE_VERSION = "^18.1.0"
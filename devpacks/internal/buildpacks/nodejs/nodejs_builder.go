@@ -10,11 +10,14 @@ import (
 	"path"
 	"path/filepath"
 	"runtime"
+	"strconv"
+	"strings"
 
 	"github.com/blang/semver/v4"
 	"github.com/buildpacks/libcnb"
 	"github.com/chuxel/devpacks/internal/buildpacks/base"
 	"github.com/chuxel/devpacks/internal/common/devcontainer"
+	"github.com/chuxel/devpacks/internal/common/downloads"
 	"github.com/chuxel/devpacks/internal/common/utils"
 )
 
@@ -54,6 +57,11 @@ func (builder NodeJsRuntimeBuilder) NewLayerContributor(buildMode string, layerT
 	return NodeJsRuntimeLayerContributor{BuildMode: buildMode, LayerTypes: layerTypes, Context: context}
 }
 
+// Implementation of base.AdditionalLayersBuilder.AdditionalLayerContributors
+func (builder NodeJsRuntimeBuilder) AdditionalLayerContributors(buildMode string, context libcnb.BuildContext) []libcnb.LayerContributor {
+	return []libcnb.LayerContributor{downloads.CacheLayerContributor{}}
+}
+
 // Implementation of libcnb.LayerContributor.Name
 func (contrib NodeJsRuntimeLayerContributor) Name() string {
 	return BUILDPACK_NAME
@@ -63,154 +71,255 @@ func (contrib NodeJsRuntimeLayerContributor) Name() string {
 func (contrib NodeJsRuntimeLayerContributor) Contribute(layer libcnb.Layer) (libcnb.Layer, error) {
 
 	// Version of Node.js to download
-	requestedVersion := "^18.1.0"
-
-	// Can be specified in project.toml or pack command line
-	if os.Getenv("BP_NODE_VERSION") != "" {
-		requestedVersion = os.Getenv("BP_NODE_VERSION")
-	} else {
-		// Otherwise look for version in a few common files
-		var candidateVersion string
-		var found bool
-		if candidateVersion, found = contrib.packageJsonVersion(); found {
-			requestedVersion = candidateVersion
-		} else if candidateVersion, found = contrib.versionInFile(".nvmrc"); found {
-			requestedVersion = candidateVersion
-		} else if candidateVersion, found = contrib.versionInFile(".node-version"); found {
-			requestedVersion = candidateVersion
-		}
+	request, err := ResolveNodeVersionRequest(contrib.Context.Application.Path)
+	if err != nil {
+		return layer, err
 	}
 
 	// Determine real node version to acquire (since requested could be a semver range)
-	nodeVersion := findRealNodeVersion(requestedVersion)
+	cache := downloads.NewDownloadCache(contrib.Context)
+	nodeVersion, err := findRealNodeVersion(request.Version, cache)
+	if err != nil {
+		return layer, err
+	}
 
 	installNode := true
+	digest := ""
 	// Check to see if a cached layer has already been restored and compare the version to see if we should recreate it
 	if layer.Metadata["node_version"] != nil {
 		if nodeVersion != fmt.Sprint(layer.Metadata["node_version"]) {
 			if err := os.RemoveAll(layer.Path); err != nil {
-				log.Fatal("Unable to remove ", layer.Path, ". ", err)
+				return layer, fmt.Errorf("unable to remove %s: %w", layer.Path, err)
 			}
 			installNode = true
 		} else {
 			log.Println("Reusing cached layer.")
 			installNode = false
+			// Keep the digest verified when the layer was created so cache reuse can be audited
+			if layer.Metadata["sha256"] != nil {
+				digest = fmt.Sprint(layer.Metadata["sha256"])
+			}
 		}
 	}
 
 	if installNode {
-		downloadAndUntarNode(nodeVersion, layer.Path)
-		// Add NODE_VERSION env var
-		layer.SharedEnvironment.Default("NODE_VERSION", nodeVersion)
+		if digest, err = downloadAndUntarNode(nodeVersion, layer.Path, cache); err != nil {
+			return layer, err
+		}
 		// Update lookup feature.json search path for finalize buildpack
 		layer.BuildEnvironment.Append(devcontainer.FINALIZE_JSON_SEARCH_PATH_ENV_VAR_NAME, string(filepath.ListSeparator), layer.Path)
 	}
 
+	// Add NODE_VERSION env var even when reusing the layer since later buildpacks use it in cache keys
+	layer.SharedEnvironment.Default("NODE_VERSION", nodeVersion)
+
+	// Use Corepack to make yarn / pnpm available if the application uses them
+	packageManager, err := DetectPackageManager(contrib.Context.Application.Path)
+	if err != nil {
+		return layer, err
+	}
+	if packageManager.UsesCorepack() {
+		if err := enableCorepack(packageManager, layer.Path, contrib.Context.Application.Path); err != nil {
+			return layer, err
+		}
+		layer.SharedEnvironment.Override("COREPACK_HOME", filepath.Join(layer.Path, COREPACK_HOME_FOLDER_NAME))
+		layer.SharedEnvironment.Override("COREPACK_ENABLE_DOWNLOAD_PROMPT", "0")
+	}
+
 	// Set the layer types based on what was set for the contributor
 	layer.LayerTypes = contrib.LayerTypes
 	layer.Metadata = map[string]interface{}{
 		"node_version": nodeVersion,
+		"sha256":       digest,
 	}
 	// Write devcontainer.json in all cases since its quick and we can avoid doing a checksum when caching
 	updatedBytes := bytes.ReplaceAll(devcontainerJsonBytes, []byte("{{layerDir}}"), []byte(layer.Path))
 	if err := utils.WriteFile(path.Join(layer.Path, "devcontainer.json"), updatedBytes); err != nil {
-		log.Fatal("Unable to write devcontainer.json. ", err)
+		return layer, fmt.Errorf("unable to write devcontainer.json: %w", err)
 	}
 
 	return layer, nil
 }
 
-func downloadAndUntarNode(nodeVersion string, targetPath string) {
+func downloadAndUntarNode(nodeVersion string, targetPath string, cache downloads.DownloadCache) (string, error) {
 	// Make sure target path exists
 	if err := os.MkdirAll(targetPath, 0755); err != nil {
-		log.Fatal(err)
+		return "", fmt.Errorf("unable to create %s: %w", targetPath, err)
 	}
 
-	// Download file into memory so we can do a checksum
+	// Download file to the cache first so we can do a checksum before expanding it
 	dlArch := runtime.GOARCH
 	if dlArch == "amd64" {
 		dlArch = "x64"
 	}
-	tgzBytes := utils.DownloadBytesFromUrl("https://nodejs.org/download/release/v" + nodeVersion + "/node-v" + nodeVersion + "-linux-" + dlArch + ".tar.gz")
-	// TODO: Verify checksum and signature -- download SHASUM256.txt from the same spot
+	releaseUrl := NODE_RELEASE_BASE_URL + "/v" + nodeVersion
+	filename := "node-v" + nodeVersion + "-linux-" + dlArch + ".tar.gz"
+	tgzPath, digest, err := cache.File(releaseUrl + "/" + filename)
+	if err != nil {
+		return "", err
+	}
+
+	// Verify checksum (and optionally the signature) using SHASUMS256.txt from the same spot
+	if err := verifyNodeChecksum(releaseUrl, filename, digest, cache); err != nil {
+		return "", err
+	}
+	log.Println("Verified sha256 of", filename, "is", digest)
 
 	// Untar into the target location
-	utils.UntarBytes(tgzBytes, targetPath, 1)
+	tgzFile, err := os.Open(tgzPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %w", tgzPath, err)
+	}
+	defer tgzFile.Close()
+	if err := utils.Untar(tgzFile, targetPath, 1); err != nil {
+		return "", err
+	}
+	return digest, nil
+}
+
+func verifyNodeChecksum(releaseUrl string, filename string, actualDigest string, cache downloads.DownloadCache) error {
+	shasumsBytes, err := cache.Bytes(releaseUrl + "/" + NODE_SHASUMS_FILENAME)
+	if err != nil {
+		return err
+	}
+	if os.Getenv("BP_NODE_VERIFY_SIGNATURE") == "true" {
+		if err := verifyNodeShasumsSignature(releaseUrl, shasumsBytes, cache); err != nil {
+			return err
+		}
+	}
+
+	// Each line is in the form "<sha256>  <filename>"
+	expectedDigest := ""
+	for _, line := range strings.Split(string(shasumsBytes), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[1] == filename {
+			expectedDigest = strings.ToLower(fields[0])
+			break
+		}
+	}
+	if expectedDigest == "" {
+		return fmt.Errorf("no checksum for %s found in %s/%s", filename, releaseUrl, NODE_SHASUMS_FILENAME)
+	}
+	if actualDigest != expectedDigest {
+		return fmt.Errorf("checksum mismatch for %s: expected sha256 %s, got %s", filename, expectedDigest, actualDigest)
+	}
+	return nil
+}
+
+func verifyNodeShasumsSignature(releaseUrl string, shasumsBytes []byte, cache downloads.DownloadCache) error {
+	// Uses the detached signature published alongside SHASUMS256.txt. The Node.js release
+	// keys need to be in the default keyring or the one specified by BP_NODE_GPG_KEYRING.
+	sigBytes, err := cache.Bytes(releaseUrl + "/" + NODE_SHASUMS_FILENAME + ".sig")
+	if err != nil {
+		return err
+	}
+	tmpDir, err := os.MkdirTemp("", "node-shasums")
+	if err != nil {
+		return fmt.Errorf("unable to create temp folder: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	shasumsPath := filepath.Join(tmpDir, NODE_SHASUMS_FILENAME)
+	sigPath := shasumsPath + ".sig"
+	if err := utils.WriteFile(shasumsPath, shasumsBytes); err != nil {
+		return fmt.Errorf("unable to write %s: %w", shasumsPath, err)
+	}
+	if err := utils.WriteFile(sigPath, sigBytes); err != nil {
+		return fmt.Errorf("unable to write %s: %w", sigPath, err)
+	}
+
+	args := []string{}
+	if keyring := os.Getenv("BP_NODE_GPG_KEYRING"); keyring != "" {
+		args = append(args, "--no-default-keyring", "--keyring", keyring)
+	}
+	args = append(args, "--verify", sigPath, shasumsPath)
+	log.Println("Verifying signature of", NODE_SHASUMS_FILENAME)
+	if _, err := utils.ExecCmd(tmpDir, false, "gpg", args...); err != nil {
+		return fmt.Errorf("signature verification of %s failed: %w", NODE_SHASUMS_FILENAME, err)
+	}
+	return nil
 }
 
-func findRealNodeVersion(requestedVersion string) string {
-	// Parse https://nodejs.org/download/release/index.json
-	// TODO: Should probably cache this file since it is partly used to identify if we have a cache hit, but its small too
-	nodeIndexJsonBytes := utils.DownloadBytesFromUrl("https://nodejs.org/download/release/index.json")
+// Finds the latest version in https://nodejs.org/download/release/index.json that matches the request. In
+// addition to semver ranges, the aliases supported by nvm in .nvmrc can be used: "node" (or "latest",
+// "stable", "current") for the latest version, "lts/*" for the latest LTS version, "lts/<codename>"
+// (e.g. "lts/hydrogen") for the latest version of an LTS line, and "lts/-<n>" for an older LTS line.
+func findRealNodeVersion(requestedVersion string, cache downloads.DownloadCache) (string, error) {
+	nodeIndexJsonBytes, err := cache.Bytes(NODE_RELEASE_BASE_URL + "/index.json")
+	if err != nil {
+		return "", err
+	}
 	type NodeIndexVersion struct {
 		Version string
+		// Either false or the LTS codename
+		Lts interface{}
 	}
 	nodeIndexVersions := []NodeIndexVersion{}
 	if err := json.Unmarshal(nodeIndexJsonBytes, &nodeIndexVersions); err != nil {
-		log.Fatal(err)
+		return "", fmt.Errorf("failed to parse Node.js index.json: %w", err)
 	}
 	versions := semver.Versions{}
+	ltsCodenames := map[string]string{}
 	for _, nodeIndexVersion := range nodeIndexVersions {
 		version, err := semver.ParseTolerant(nodeIndexVersion.Version)
 		if err != nil {
-			log.Fatal(err)
+			return "", fmt.Errorf("invalid version %s in Node.js index.json: %w", nodeIndexVersion.Version, err)
 		}
 		versions = append(versions, version)
+		if codename, isString := nodeIndexVersion.Lts.(string); isString && codename != "" {
+			ltsCodenames[version.String()] = strings.ToLower(codename)
+		}
+	}
+	if len(versions) == 0 {
+		return "", fmt.Errorf("no versions found in Node.js index.json")
 	}
 	semver.Sort(versions)
 
-	if requestedVersion != "latest" {
-		expectedRange := utils.NewSemverRange(requestedVersion)
-		// Sorted in ascending order, so run through in reverse order to get the latest matching
+	requestedVersion = strings.ToLower(strings.TrimSpace(requestedVersion))
+	switch {
+	case requestedVersion == "latest" || requestedVersion == "node" || requestedVersion == "stable" || requestedVersion == "current":
+		return versions[len(versions)-1].FinalizeVersion(), nil
+	case strings.HasPrefix(requestedVersion, "lts/"):
+		alias := strings.TrimPrefix(requestedVersion, "lts/")
+		// Codenames from newest to oldest LTS line
+		codenames := []string{}
 		for i := len(versions) - 1; i >= 0; i-- {
-			nodeVersion := versions[i]
-			if expectedRange(nodeVersion) {
-				return nodeVersion.FinalizeVersion()
+			codename, isLts := ltsCodenames[versions[i].String()]
+			if isLts && !utils.SliceContainsString(codenames, codename) {
+				codenames = append(codenames, codename)
 			}
 		}
-
-		log.Fatal("Unable to match node version", requestedVersion)
-	}
-
-	return versions[len(versions)-1].FinalizeVersion()
-}
-
-func (contrib NodeJsRuntimeLayerContributor) packageJsonVersion() (string, bool) {
-	packageJsonPath := filepath.Join(contrib.Context.Application.Path, "package.json")
-	// Get engine value for nodejs if it exists in package.json
-	if _, err := os.Stat(packageJsonPath); err == nil {
-		type PackageJson struct {
-			Engines map[string]string
+		if len(codenames) == 0 {
+			return "", fmt.Errorf("no LTS versions found in Node.js index.json")
 		}
-		var packageJson PackageJson
-
-		content, err := os.ReadFile(packageJsonPath)
-		if err != nil {
-			log.Fatal("Failed to read package.json. ", err)
+		codename := alias
+		if alias == "*" || alias == "latest" {
+			codename = codenames[0]
+		} else if strings.HasPrefix(alias, "-") {
+			offset, err := strconv.Atoi(alias[1:])
+			if err != nil || offset < 0 || offset >= len(codenames) {
+				return "", fmt.Errorf("invalid LTS alias %s", requestedVersion)
+			}
+			codename = codenames[offset]
 		}
-		if err := json.Unmarshal(content, &packageJson); err != nil {
-			log.Fatal("Failed to parse package.json. ", err)
+		for i := len(versions) - 1; i >= 0; i-- {
+			if ltsCodenames[versions[i].String()] == codename {
+				log.Printf("Resolved %s to LTS line %s.\n", requestedVersion, codename)
+				return versions[i].FinalizeVersion(), nil
+			}
 		}
-		version, hasKey := packageJson.Engines["node"]
-		return version, hasKey
+		return "", fmt.Errorf("unable to find Node.js LTS line %s", codename)
 	}
 
-	return "", false
-}
-
-func (contrib NodeJsRuntimeLayerContributor) versionInFile(name string) (string, bool) {
-	versionFilePath := filepath.Join(contrib.Context.Application.Path, name)
-	// Get engine value for nodejs if it exists in package.json
-	if _, err := os.Stat(versionFilePath); err == nil {
-		content, err := os.ReadFile(versionFilePath)
-		if err != nil {
-			log.Fatal("Failed to read ", name, ". ", err)
-		}
-		if content[0] == 'v' {
-			return fmt.Sprint(content[1:]), true
+	expectedRange, err := utils.NewSemverRange(requestedVersion)
+	if err != nil {
+		return "", err
+	}
+	// Sorted in ascending order, so run through in reverse order to get the latest matching
+	for i := len(versions) - 1; i >= 0; i-- {
+		nodeVersion := versions[i]
+		if expectedRange(nodeVersion) {
+			return nodeVersion.FinalizeVersion(), nil
 		}
-		return fmt.Sprint(content), true
 	}
-
-	return "", false
+	return "", fmt.Errorf("unable to match node version %s", requestedVersion)
 }
@@ -0,0 +1,183 @@
+package nodejs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/chuxel/devpacks/internal/common/downloads"
+	"github.com/chuxel/devpacks/internal/common/harness"
+	"github.com/chuxel/devpacks/internal/common/utils"
+)
+
+// Newest first like the real index.json
+const TEST_NODE_INDEX_JSON = `[
+	{"version": "v21.1.0", "lts": false},
+	{"version": "v20.9.0", "lts": "Iron"},
+	{"version": "v20.8.1", "lts": false},
+	{"version": "v18.18.2", "lts": "Hydrogen"},
+	{"version": "v18.18.1", "lts": "Hydrogen"},
+	{"version": "v16.20.2", "lts": "Gallium"}
+]`
+
+func TestFindRealNodeVersion(t *testing.T) {
+	transport := harness.NewStubTransport()
+	transport.AddBytes(NODE_RELEASE_BASE_URL+"/index.json", []byte(TEST_NODE_INDEX_JSON))
+	utils.SetHttpClient(&http.Client{Transport: transport})
+	defer utils.SetHttpClient(nil)
+	cache := downloads.DownloadCache{Path: t.TempDir()}
+
+	tests := []struct {
+		requestedVersion string
+		expected         string
+		expectError      bool
+	}{
+		{requestedVersion: "node", expected: "21.1.0"},
+		{requestedVersion: "latest", expected: "21.1.0"},
+		{requestedVersion: "lts/*", expected: "20.9.0"},
+		{requestedVersion: "lts/latest", expected: "20.9.0"},
+		{requestedVersion: "lts/hydrogen", expected: "18.18.2"},
+		{requestedVersion: "lts/Gallium", expected: "16.20.2"},
+		{requestedVersion: "lts/-0", expected: "20.9.0"},
+		{requestedVersion: "lts/-1", expected: "18.18.2"},
+		{requestedVersion: "lts/-2", expected: "16.20.2"},
+		{requestedVersion: "lts/-3", expectError: true},
+		{requestedVersion: "lts/--1", expectError: true},
+		{requestedVersion: "lts/-x", expectError: true},
+		{requestedVersion: "lts/argon", expectError: true},
+		{requestedVersion: "^18.0.0", expected: "18.18.2"},
+		{requestedVersion: "20", expected: "20.9.0"},
+		{requestedVersion: "^22.0.0", expectError: true},
+	}
+	for _, test := range tests {
+		t.Run(test.requestedVersion, func(t *testing.T) {
+			version, err := findRealNodeVersion(test.requestedVersion, cache)
+			if test.expectError {
+				if err == nil {
+					t.Errorf("expected an error, got %s", version)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if version != test.expected {
+				t.Errorf("expected %s, got %s", test.expected, version)
+			}
+		})
+	}
+}
+
+// Serves index.json, a stand-in Node.js tarball for 18.18.2 and a SHASUMS256.txt with the given digest
+// (or the real one if empty)
+func addNodeReleaseFixtures(t *testing.T, transport *harness.StubTransport, digest string) {
+	t.Helper()
+	dlArch := runtime.GOARCH
+	if dlArch == "amd64" {
+		dlArch = "x64"
+	}
+	folderName := "node-v18.18.2-linux-" + dlArch
+	tgzBytes, err := harness.TarGz(map[string]string{
+		folderName + "/bin/node":  "#!/bin/sh\necho v18.18.2\n",
+		folderName + "/README.md": "Node.js",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if digest == "" {
+		sum := sha256.Sum256(tgzBytes)
+		digest = hex.EncodeToString(sum[:])
+	}
+	releaseUrl := NODE_RELEASE_BASE_URL + "/v18.18.2"
+	transport.AddBytes(NODE_RELEASE_BASE_URL+"/index.json", []byte(TEST_NODE_INDEX_JSON))
+	transport.AddBytes(releaseUrl+"/"+folderName+".tar.gz", tgzBytes)
+	transport.AddBytes(releaseUrl+"/"+NODE_SHASUMS_FILENAME, []byte(digest+"  "+folderName+".tar.gz\n"))
+}
+
+func newNodeJsHarness(t *testing.T) *harness.Harness {
+	t.Helper()
+	h, err := harness.NewHarness(BUILDPACK_NAME, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	packageJson := `{"name": "test", "engines": {"node": "18"}}`
+	if err := os.WriteFile(filepath.Join(h.ApplicationPath, "package.json"), []byte(packageJson), 0644); err != nil {
+		h.Cleanup()
+		t.Fatal(err)
+	}
+	return h
+}
+
+func TestNodeJsRuntimeBuilder(t *testing.T) {
+	h := newNodeJsHarness(t)
+	defer h.Cleanup()
+	transport := harness.NewStubTransport()
+	addNodeReleaseFixtures(t, transport, "")
+	h.UseStubTransport(transport)
+	plan, err := h.DefaultPlan()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := h.Build(NodeJsRuntimeBuilder{}, plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	layer, hasLayer := output.Layer(BUILDPACK_NAME)
+	if !hasLayer {
+		t.Fatal("no nodejs layer contributed")
+	}
+	if _, err := os.Stat(filepath.Join(layer.Path, "bin", "node")); err != nil {
+		t.Errorf("expected bin/node in layer: %v", err)
+	}
+	if nodeVersion, err := harness.ReadLayerEnvFile(layer, "env", "NODE_VERSION.default"); err != nil || nodeVersion != "18.18.2" {
+		t.Errorf("expected NODE_VERSION 18.18.2, got %s (%v)", nodeVersion, err)
+	}
+	// Plan entries are build=false cache=true launch=false and build=true cache=false
+	if !layer.LayerTypes.Build || !layer.LayerTypes.Cache || layer.LayerTypes.Launch {
+		t.Errorf("unexpected layer types %+v", layer.LayerTypes)
+	}
+	if len(output.Result.Unmet) != 2 {
+		t.Errorf("expected some-unmet-dependency and devpack-finalize to be unmet, got %v", output.Result.Unmet)
+	}
+	devContainer, err := harness.LayerDevContainerJson(layer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, hasCustomizations := devContainer.Properties["customizations"]; !hasCustomizations {
+		t.Error("expected customizations in devcontainer.json")
+	}
+
+	// A second build reuses the layer and the cached index.json without any downloads
+	requestCount := len(transport.RequestedUrls())
+	output, err = h.Build(NodeJsRuntimeBuilder{}, plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if urls := transport.RequestedUrls(); len(urls) != requestCount {
+		t.Errorf("expected no downloads when reusing the layer, got %v", urls[requestCount:])
+	}
+	if layer, _ := output.Layer(BUILDPACK_NAME); layer.Metadata["node_version"] != "18.18.2" {
+		t.Errorf("expected node_version metadata 18.18.2, got %v", layer.Metadata)
+	}
+}
+
+func TestNodeJsRuntimeBuilderChecksumMismatch(t *testing.T) {
+	h := newNodeJsHarness(t)
+	defer h.Cleanup()
+	transport := harness.NewStubTransport()
+	addNodeReleaseFixtures(t, transport, strings.Repeat("0", 64))
+	h.UseStubTransport(transport)
+	plan, err := h.DefaultPlan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.Build(NodeJsRuntimeBuilder{}, plan); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("expected a checksum mismatch, got %v", err)
+	}
+}
@@ -32,7 +32,7 @@ func (detector NodeJsRuntimeDetector) AlwaysPass() bool {
 
 func (detector NodeJsRuntimeDetector) DoDetect(context libcnb.DetectContext) (bool, []libcnb.BuildPlanRequire, map[string]interface{}, error) {
 	// Can be specified in project.toml or pack command line
-	if os.Getenv("BP_NODE_VERSION") != "" {
+	if os.Getenv(NODE_VERSION_ENV_VAR_NAME) != "" {
 		return true, nil, nil, nil
 	}
 
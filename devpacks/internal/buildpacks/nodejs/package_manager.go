@@ -0,0 +1,227 @@
+package nodejs
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/chuxel/devpacks/internal/common/utils"
+)
+
+// Supported package managers
+const NPM = "npm"
+const YARN = "yarn"
+const PNPM = "pnpm"
+
+// Lockfiles in the order they are checked when package.json does not have a packageManager property
+var PACKAGE_MANAGER_LOCKFILES = []struct {
+	Name     string
+	Lockfile string
+}{
+	{PNPM, "pnpm-lock.yaml"},
+	{YARN, "yarn.lock"},
+	{NPM, "package-lock.json"},
+	{NPM, "npm-shrinkwrap.json"},
+}
+
+type PackageManager struct {
+	Name string
+	// Version from the packageManager property in package.json, if set
+	Version string
+	// Lockfile name if one exists in the application folder
+	Lockfile string
+	// True for Yarn 2+ ("berry"), which uses different install options than Yarn 1
+	YarnBerry bool
+	// True if "yarn workspaces focus" is available, which is built into Yarn 4 and needs the
+	// workspace-tools plugin in Yarn 2 and 3
+	YarnWorkspaceFocus bool
+}
+
+// Determines the package manager from the packageManager property in package.json
+// (e.g. "yarn@3.2.1"), falling back to whichever lockfile is present, and then npm.
+func DetectPackageManager(appPath string) (PackageManager, error) {
+	packageManager := PackageManager{Name: NPM}
+	fromPackageJson := false
+	packageJsonPath := filepath.Join(appPath, "package.json")
+	if _, err := os.Stat(packageJsonPath); err == nil {
+		type PackageJson struct {
+			PackageManager string `json:"packageManager"`
+		}
+		var packageJson PackageJson
+		content, err := os.ReadFile(packageJsonPath)
+		if err != nil {
+			return packageManager, fmt.Errorf("failed to read package.json: %w", err)
+		}
+		if err := json.Unmarshal(content, &packageJson); err != nil {
+			return packageManager, fmt.Errorf("failed to parse package.json: %w", err)
+		}
+		if packageJson.PackageManager != "" {
+			nameVersion := strings.SplitN(packageJson.PackageManager, "@", 2)
+			if nameVersion[0] != NPM && nameVersion[0] != YARN && nameVersion[0] != PNPM {
+				return packageManager, fmt.Errorf("unsupported packageManager %s in package.json", packageJson.PackageManager)
+			}
+			packageManager.Name = nameVersion[0]
+			fromPackageJson = true
+			if len(nameVersion) == 2 {
+				packageManager.Version = nameVersion[1]
+			}
+		}
+	}
+
+	for _, candidate := range PACKAGE_MANAGER_LOCKFILES {
+		if fromPackageJson && candidate.Name != packageManager.Name {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(appPath, candidate.Lockfile)); err == nil {
+			packageManager.Name = candidate.Name
+			packageManager.Lockfile = candidate.Lockfile
+			break
+		}
+	}
+	if packageManager.Name == YARN {
+		packageManager.YarnBerry = isYarnBerry(appPath, packageManager)
+		packageManager.YarnWorkspaceFocus = packageManager.YarnBerry && hasYarnWorkspaceFocus(appPath, packageManager)
+	}
+	return packageManager, nil
+}
+
+// Uses the major version from packageManager in package.json if set. Otherwise a .yarnrc.yml file
+// or a yarn.lock with a __metadata entry means Yarn 2+, since Yarn 1 uses .yarnrc and has neither.
+func isYarnBerry(appPath string, packageManager PackageManager) bool {
+	if packageManager.Version != "" {
+		return !strings.HasPrefix(packageManager.Version, "1.")
+	}
+	if _, err := os.Stat(filepath.Join(appPath, ".yarnrc.yml")); err == nil {
+		return true
+	}
+	if packageManager.Lockfile != "" {
+		if content, err := os.ReadFile(filepath.Join(appPath, packageManager.Lockfile)); err == nil {
+			return strings.Contains(string(content), "\n__metadata:")
+		}
+	}
+	return false
+}
+
+// Checks for Yarn 4+ in packageManager in package.json, or the workspace-tools plugin in the
+// .yarn/plugins folder or .yarnrc.yml
+func hasYarnWorkspaceFocus(appPath string, packageManager PackageManager) bool {
+	if major, err := strconv.Atoi(strings.SplitN(packageManager.Version, ".", 2)[0]); err == nil && major >= 4 {
+		return true
+	}
+	if _, err := os.Stat(filepath.Join(appPath, ".yarn", "plugins", "@yarnpkg", "plugin-workspace-tools.cjs")); err == nil {
+		return true
+	}
+	if content, err := os.ReadFile(filepath.Join(appPath, ".yarnrc.yml")); err == nil {
+		return strings.Contains(string(content), "plugin-workspace-tools")
+	}
+	return false
+}
+
+// Env vars to use when installing and running with the package manager. Yarn 2+ defaults to
+// Plug'n'Play, which does not create a node_modules folder, so the node-modules linker is used instead.
+func (packageManager PackageManager) Env() map[string]string {
+	if packageManager.YarnBerry {
+		return map[string]string{"YARN_NODE_LINKER": "node-modules"}
+	}
+	return map[string]string{}
+}
+
+// Yarn and pnpm are acquired using Corepack, which ships with Node.js
+func (packageManager PackageManager) UsesCorepack() bool {
+	return packageManager.Name != NPM
+}
+
+func (packageManager PackageManager) InstallCommand() []string {
+	return []string{packageManager.Name, "install"}
+}
+
+// Install command that uses the lockfile as-is when there is one (e.g. npm ci)
+func (packageManager PackageManager) CleanInstallCommand() []string {
+	if packageManager.Lockfile == "" {
+		return packageManager.InstallCommand()
+	}
+	switch packageManager.Name {
+	case NPM:
+		return []string{NPM, "ci"}
+	case PNPM:
+		return []string{PNPM, "install", "--frozen-lockfile"}
+	case YARN:
+		if packageManager.YarnBerry {
+			return []string{YARN, "install", "--immutable"}
+		}
+		return []string{YARN, "install", "--frozen-lockfile"}
+	}
+	return packageManager.InstallCommand()
+}
+
+// Install command that skips devDependencies
+func (packageManager PackageManager) ProductionInstallCommand() ([]string, error) {
+	switch packageManager.Name {
+	case NPM:
+		if packageManager.Lockfile != "" {
+			return []string{NPM, "ci", "--omit=dev"}, nil
+		}
+		return []string{NPM, "install", "--omit=dev"}, nil
+	case PNPM:
+		if packageManager.Lockfile != "" {
+			return []string{PNPM, "install", "--prod", "--frozen-lockfile"}, nil
+		}
+		return []string{PNPM, "install", "--prod"}, nil
+	}
+	// Yarn 2+ removed --production, so focus all workspaces on production dependencies instead
+	if packageManager.YarnBerry {
+		if !packageManager.YarnWorkspaceFocus {
+			return nil, fmt.Errorf("installing production dependencies with Yarn 2 or 3 needs the workspace-tools plugin. Run \"yarn plugin import workspace-tools\", upgrade to Yarn 4, or set BP_NODE_PRUNE_DEV_DEPENDENCIES to false")
+		}
+		return []string{YARN, "workspaces", "focus", "--all", "--production"}, nil
+	}
+	if packageManager.Lockfile != "" {
+		return []string{YARN, "install", "--production", "--frozen-lockfile"}, nil
+	}
+	return []string{YARN, "install", "--production"}, nil
+}
+
+func (packageManager PackageManager) RunScriptCommand(script string) []string {
+	return []string{packageManager.Name, "run", script}
+}
+
+func (packageManager PackageManager) StartCommand() []string {
+	return []string{packageManager.Name, "start"}
+}
+
+// Path to the file that determines installed dependencies, used to decide if they can be reused
+func (packageManager PackageManager) DependencyFilePath(appPath string) string {
+	if packageManager.Lockfile != "" {
+		return filepath.Join(appPath, packageManager.Lockfile)
+	}
+	return filepath.Join(appPath, "package.json")
+}
+
+// Adds yarn / pnpm shims to the bin folder of the Node.js install and sets COREPACK_HOME so
+// the package manager itself is downloaded into the layer
+func enableCorepack(packageManager PackageManager, nodePath string, workingDir string) error {
+	nodeBinPath := filepath.Join(nodePath, "bin")
+	corepackPath := filepath.Join(nodeBinPath, "corepack")
+	if _, err := os.Stat(corepackPath); err != nil {
+		return fmt.Errorf("corepack not found in Node.js install. Use Node.js 16.9.0 or later to use %s", packageManager.Name)
+	}
+	corepackHome := filepath.Join(nodePath, COREPACK_HOME_FOLDER_NAME)
+	os.Setenv("COREPACK_HOME", corepackHome)
+	os.Setenv("COREPACK_ENABLE_DOWNLOAD_PROMPT", "0")
+	nodeBinary := filepath.Join(nodeBinPath, "node")
+
+	log.Println("Enabling Corepack for", packageManager.Name)
+	if _, err := utils.ExecCmd(workingDir, false, nodeBinary, corepackPath, "enable", "--install-directory", nodeBinPath, packageManager.Name); err != nil {
+		return fmt.Errorf("failed to enable corepack: %w", err)
+	}
+	if packageManager.Version != "" {
+		if _, err := utils.ExecCmd(workingDir, false, nodeBinary, corepackPath, "prepare", packageManager.Name+"@"+packageManager.Version, "--activate"); err != nil {
+			return fmt.Errorf("failed to prepare %s@%s: %w", packageManager.Name, packageManager.Version, err)
+		}
+	}
+	return nil
+}
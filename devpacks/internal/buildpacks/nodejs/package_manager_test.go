@@ -0,0 +1,140 @@
+package nodejs
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDetectPackageManager(t *testing.T) {
+	tests := []struct {
+		name                      string
+		files                     map[string]string
+		expected                  PackageManager
+		expectedCleanInstall      []string
+		expectedProductionInstall []string
+	}{
+		{
+			name:                      "npm without lockfile",
+			files:                     map[string]string{"package.json": `{}`},
+			expected:                  PackageManager{Name: NPM},
+			expectedCleanInstall:      []string{"npm", "install"},
+			expectedProductionInstall: []string{"npm", "install", "--omit=dev"},
+		},
+		{
+			name:                      "npm lockfile",
+			files:                     map[string]string{"package.json": `{}`, "package-lock.json": `{}`},
+			expected:                  PackageManager{Name: NPM, Lockfile: "package-lock.json"},
+			expectedCleanInstall:      []string{"npm", "ci"},
+			expectedProductionInstall: []string{"npm", "ci", "--omit=dev"},
+		},
+		{
+			name:                      "pnpm lockfile",
+			files:                     map[string]string{"package.json": `{}`, "pnpm-lock.yaml": "lockfileVersion: '6.0'\n"},
+			expected:                  PackageManager{Name: PNPM, Lockfile: "pnpm-lock.yaml"},
+			expectedCleanInstall:      []string{"pnpm", "install", "--frozen-lockfile"},
+			expectedProductionInstall: []string{"pnpm", "install", "--prod", "--frozen-lockfile"},
+		},
+		{
+			name:                      "yarn 1 lockfile",
+			files:                     map[string]string{"package.json": `{}`, "yarn.lock": "# yarn lockfile v1\n\nleft-pad@^1.3.0:\n  version \"1.3.0\"\n"},
+			expected:                  PackageManager{Name: YARN, Lockfile: "yarn.lock"},
+			expectedCleanInstall:      []string{"yarn", "install", "--frozen-lockfile"},
+			expectedProductionInstall: []string{"yarn", "install", "--production", "--frozen-lockfile"},
+		},
+		{
+			name:                      "yarn 1 from packageManager",
+			files:                     map[string]string{"package.json": `{"packageManager": "yarn@1.22.19"}`, "yarn.lock": ""},
+			expected:                  PackageManager{Name: YARN, Version: "1.22.19", Lockfile: "yarn.lock"},
+			expectedCleanInstall:      []string{"yarn", "install", "--frozen-lockfile"},
+			expectedProductionInstall: []string{"yarn", "install", "--production", "--frozen-lockfile"},
+		},
+		{
+			name:                      "yarn 4 from packageManager",
+			files:                     map[string]string{"package.json": `{"packageManager": "yarn@4.0.2"}`, "yarn.lock": ""},
+			expected:                  PackageManager{Name: YARN, Version: "4.0.2", Lockfile: "yarn.lock", YarnBerry: true, YarnWorkspaceFocus: true},
+			expectedCleanInstall:      []string{"yarn", "install", "--immutable"},
+			expectedProductionInstall: []string{"yarn", "workspaces", "focus", "--all", "--production"},
+		},
+		{
+			// No workspace-tools plugin, so there is no production install command
+			name:                 "yarn 3 from packageManager",
+			files:                map[string]string{"package.json": `{"packageManager": "yarn@3.6.4"}`, "yarn.lock": ""},
+			expected:             PackageManager{Name: YARN, Version: "3.6.4", Lockfile: "yarn.lock", YarnBerry: true},
+			expectedCleanInstall: []string{"yarn", "install", "--immutable"},
+		},
+		{
+			name: "yarn 3 with workspace-tools in .yarnrc.yml",
+			files: map[string]string{"package.json": `{"packageManager": "yarn@3.6.4"}`, "yarn.lock": "",
+				".yarnrc.yml": "plugins:\n  - path: .yarn/plugins/@yarnpkg/plugin-workspace-tools.cjs\n    spec: \"@yarnpkg/plugin-workspace-tools\"\n"},
+			expected:                  PackageManager{Name: YARN, Version: "3.6.4", Lockfile: "yarn.lock", YarnBerry: true, YarnWorkspaceFocus: true},
+			expectedCleanInstall:      []string{"yarn", "install", "--immutable"},
+			expectedProductionInstall: []string{"yarn", "workspaces", "focus", "--all", "--production"},
+		},
+		{
+			name:                      "yarn berry from .yarnrc.yml with workspace-tools plugin file",
+			files:                     map[string]string{"package.json": `{}`, "yarn.lock": "", ".yarnrc.yml": "nodeLinker: pnp\n", ".yarn/plugins/@yarnpkg/plugin-workspace-tools.cjs": ""},
+			expected:                  PackageManager{Name: YARN, Lockfile: "yarn.lock", YarnBerry: true, YarnWorkspaceFocus: true},
+			expectedCleanInstall:      []string{"yarn", "install", "--immutable"},
+			expectedProductionInstall: []string{"yarn", "workspaces", "focus", "--all", "--production"},
+		},
+		{
+			name:                 "yarn berry from lockfile metadata",
+			files:                map[string]string{"package.json": `{}`, "yarn.lock": "# This file is generated by running \"yarn install\"\n\n__metadata:\n  version: 6\n"},
+			expected:             PackageManager{Name: YARN, Lockfile: "yarn.lock", YarnBerry: true},
+			expectedCleanInstall: []string{"yarn", "install", "--immutable"},
+		},
+		{
+			name:                      "yarn 1 from packageManager without lockfile",
+			files:                     map[string]string{"package.json": `{"packageManager": "yarn@1.22.19"}`, "package-lock.json": `{}`},
+			expected:                  PackageManager{Name: YARN, Version: "1.22.19"},
+			expectedCleanInstall:      []string{"yarn", "install"},
+			expectedProductionInstall: []string{"yarn", "install", "--production"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			appPath := t.TempDir()
+			for filename, content := range test.files {
+				if err := os.MkdirAll(filepath.Dir(filepath.Join(appPath, filename)), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(appPath, filename), []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			packageManager, err := DetectPackageManager(appPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if packageManager != test.expected {
+				t.Errorf("expected %+v, got %+v", test.expected, packageManager)
+			}
+			if command := packageManager.CleanInstallCommand(); !reflect.DeepEqual(command, test.expectedCleanInstall) {
+				t.Errorf("expected clean install command %v, got %v", test.expectedCleanInstall, command)
+			}
+			command, err := packageManager.ProductionInstallCommand()
+			if test.expectedProductionInstall == nil {
+				if err == nil {
+					t.Errorf("expected an error for the production install command, got %v", command)
+				}
+			} else if err != nil || !reflect.DeepEqual(command, test.expectedProductionInstall) {
+				t.Errorf("expected production install command %v, got %v (%v)", test.expectedProductionInstall, command, err)
+			}
+			if linker, hasLinker := packageManager.Env()["YARN_NODE_LINKER"]; packageManager.YarnBerry != hasLinker || (hasLinker && linker != "node-modules") {
+				t.Errorf("expected the node-modules linker for Yarn 2+ only, got %v", packageManager.Env())
+			}
+		})
+	}
+}
+
+func TestDetectPackageManagerUnsupported(t *testing.T) {
+	appPath := t.TempDir()
+	if err := os.WriteFile(filepath.Join(appPath, "package.json"), []byte(`{"packageManager": "bun@1.0.0"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DetectPackageManager(appPath); err == nil {
+		t.Error("expected an error for an unsupported package manager")
+	}
+}
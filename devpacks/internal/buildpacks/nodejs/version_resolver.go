@@ -0,0 +1,141 @@
+package nodejs
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// A requested Node.js version (a semver range or an alias like "lts/*") and where it came from
+type NodeVersionRequest struct {
+	Version string
+	Source  string
+}
+
+// Finds the requested Node.js version by looking at BP_NODE_VERSION, engines.node in package.json,
+// .nvmrc, .node-version and then asdf's .tool-versions, in that order. Logs which source was used.
+func ResolveNodeVersionRequest(appPath string) (NodeVersionRequest, error) {
+	sources := []struct {
+		name   string
+		lookup func(appPath string) (string, bool, error)
+	}{
+		{NODE_VERSION_ENV_VAR_NAME, func(string) (string, bool, error) {
+			version := os.Getenv(NODE_VERSION_ENV_VAR_NAME)
+			return version, version != "", nil
+		}},
+		{"engines.node in package.json", packageJsonVersion},
+		{".nvmrc", func(appPath string) (string, bool, error) { return versionInFile(appPath, ".nvmrc") }},
+		{".node-version", func(appPath string) (string, bool, error) { return versionInFile(appPath, ".node-version") }},
+		{".tool-versions", toolVersionsVersion},
+	}
+
+	var request *NodeVersionRequest
+	skipped := []string{}
+	for _, source := range sources {
+		version, found, err := source.lookup(appPath)
+		if err != nil {
+			return NodeVersionRequest{}, err
+		}
+		if !found {
+			continue
+		}
+		if request == nil {
+			request = &NodeVersionRequest{Version: version, Source: source.name}
+		} else {
+			skipped = append(skipped, fmt.Sprintf("%s (%s)", source.name, version))
+		}
+	}
+	if request == nil {
+		log.Println("No Node.js version specified, using default", DEFAULT_NODE_VERSION)
+		return NodeVersionRequest{Version: DEFAULT_NODE_VERSION, Source: "default"}, nil
+	}
+	log.Printf("Using Node.js version %s from %s.\n", request.Version, request.Source)
+	if len(skipped) > 0 {
+		log.Println("Ignoring lower priority versions from:", strings.Join(skipped, ", "))
+	}
+	return *request, nil
+}
+
+func packageJsonVersion(appPath string) (string, bool, error) {
+	packageJsonPath := filepath.Join(appPath, "package.json")
+	// Get engine value for nodejs if it exists in package.json
+	if _, err := os.Stat(packageJsonPath); err == nil {
+		type PackageJson struct {
+			Engines map[string]string
+		}
+		var packageJson PackageJson
+
+		content, err := os.ReadFile(packageJsonPath)
+		if err != nil {
+			return "", false, fmt.Errorf("failed to read package.json: %w", err)
+		}
+		if err := json.Unmarshal(content, &packageJson); err != nil {
+			return "", false, fmt.Errorf("failed to parse package.json: %w", err)
+		}
+		version, hasKey := packageJson.Engines["node"]
+		return strings.TrimSpace(version), hasKey && strings.TrimSpace(version) != "", nil
+	}
+
+	return "", false, nil
+}
+
+// Reads the first non-comment line of a file like .nvmrc or .node-version
+func versionInFile(appPath string, name string) (string, bool, error) {
+	versionFilePath := filepath.Join(appPath, name)
+	if _, err := os.Stat(versionFilePath); err != nil {
+		return "", false, nil
+	}
+	content, err := os.ReadFile(versionFilePath)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		if commentStart := strings.Index(line, "#"); commentStart >= 0 {
+			line = line[:commentStart]
+		}
+		if version := normalizeNodeVersion(line); version != "" {
+			return version, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// Finds the nodejs entry in asdf's .tool-versions (e.g. "nodejs 18.12.1 16.18.1"), using the first version
+func toolVersionsVersion(appPath string) (string, bool, error) {
+	toolVersionsPath := filepath.Join(appPath, ".tool-versions")
+	if _, err := os.Stat(toolVersionsPath); err != nil {
+		return "", false, nil
+	}
+	content, err := os.ReadFile(toolVersionsPath)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read .tool-versions: %w", err)
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		if commentStart := strings.Index(line, "#"); commentStart >= 0 {
+			line = line[:commentStart]
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 || (fields[0] != "nodejs" && fields[0] != "node") {
+			continue
+		}
+		for _, version := range fields[1:] {
+			// Skip asdf specific values like "system", "ref:<git ref>" and "path:<path>"
+			if version == "system" || strings.Contains(version, ":") {
+				continue
+			}
+			return normalizeNodeVersion(version), true, nil
+		}
+	}
+	return "", false, nil
+}
+
+func normalizeNodeVersion(version string) string {
+	version = strings.TrimSpace(version)
+	if len(version) > 1 && version[0] == 'v' && version[1] >= '0' && version[1] <= '9' {
+		version = version[1:]
+	}
+	return version
+}
@@ -0,0 +1,10 @@
+package nodeutils
+
+const BUILDPACK_NAME = "nodeutils"
+const DEFAULT_NODE_UTILS = "typescript eslint prettier nodemon"
+
+// Folder in the layer with a sub-folder (npm global prefix) for each tool. Binaries are linked into the layer's bin folder.
+const TOOLS_FOLDER_NAME = "tools"
+
+// Section of .devpacks/tools.toml with tools for this buildpack
+const TOOLS_FILE_ECOSYSTEM = "node"
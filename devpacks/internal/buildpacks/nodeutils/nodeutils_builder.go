@@ -0,0 +1,132 @@
+package nodeutils
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/buildpacks/libcnb"
+	"github.com/chuxel/devpacks/internal/buildpacks/base"
+	"github.com/chuxel/devpacks/internal/common/devcontainer"
+	"github.com/chuxel/devpacks/internal/common/tools"
+	"github.com/chuxel/devpacks/internal/common/utils"
+)
+
+//go:embed assets/devcontainer.json
+var devcontainerJsonBytes []byte
+
+// VS Code settings added to devcontainer.json when one of the default tools is installed
+var defaultToolSettings = map[string]map[string]interface{}{
+	"typescript": {"typescript.tsdk": "{{layerDir}}/" + TOOLS_FOLDER_NAME + "/typescript/lib/node_modules/typescript/lib"},
+	"eslint":     {"eslint.nodePath": "{{layerDir}}/" + TOOLS_FOLDER_NAME + "/eslint/lib/node_modules"},
+	"prettier":   {"prettier.prettierPath": "{{layerDir}}/" + TOOLS_FOLDER_NAME + "/prettier/lib/node_modules/prettier"},
+}
+
+type NodeUtilsBuilder struct {
+	// Implements base.DefaultBuilder
+
+	// Build(context libcnb.BuildContext) (libcnb.BuildResult, error)
+	// Name() string
+	// NewLayerContributor(buildMode string, layerTypes libcnb.LayerTypes, context libcnb.BuildContext) libcnb.BaseLayerContributor
+}
+
+type NodeUtilsLayerContributor struct {
+	// Implements libcnb.LayerContributor
+
+	// Contribute(context libcnb.ContributeContext) (libcnb.Layer, error)
+	// Name() string
+
+	LayerTypes libcnb.LayerTypes
+	Context    libcnb.BuildContext
+	BuildMode  string
+}
+
+func (builder NodeUtilsBuilder) Build(context libcnb.BuildContext) (libcnb.BuildResult, error) {
+	return base.DefaultBuild(builder, context)
+}
+
+// Implementation of base.BaseBuilder.Name
+func (builder NodeUtilsBuilder) Name() string {
+	return BUILDPACK_NAME
+}
+
+// Implementation of base.BaseBuilder.NewLayerContributor
+func (builder NodeUtilsBuilder) NewLayerContributor(buildMode string, layerTypes libcnb.LayerTypes, context libcnb.BuildContext) libcnb.LayerContributor {
+	return NodeUtilsLayerContributor{BuildMode: buildMode, LayerTypes: layerTypes, Context: context}
+}
+
+// Implementation of libcnb.LayerContributor.Name
+func (contrib NodeUtilsLayerContributor) Name() string {
+	return BUILDPACK_NAME
+}
+
+// Implementation of libcnb.LayerContributor.Contribute
+func (contrib NodeUtilsLayerContributor) Contribute(layer libcnb.Layer) (libcnb.Layer, error) {
+	var pkgList []string
+	if os.Getenv("BP_NODE_UTILS") != "" {
+		pkgList = strings.Split(os.Getenv("BP_NODE_UTILS"), " ")
+	} else {
+		pkgList = strings.Split(DEFAULT_NODE_UTILS, " ")
+	}
+	// Add or remove tools using .devpacks/tools.toml
+	toolsFile, err := tools.LoadToolsFile(contrib.Context.Application.Path)
+	if err != nil {
+		return layer, err
+	}
+	nodeTools := toolsFile.ResolveTools(TOOLS_FILE_ECOSYSTEM, pkgList, splitPackageVersion)
+	nodeTools = addDefaultToolSettings(nodeTools)
+
+	// Make sure target path exists
+	if err := os.MkdirAll(filepath.Join(layer.Path, TOOLS_FOLDER_NAME), 0755); err != nil {
+		return layer, fmt.Errorf("unable to create layer folder %s: %w", layer.Path, err)
+	}
+	// Write devcontainer.json in all cases since its quick and we can avoid doing a checksum when caching
+	updatedBytes, err := tools.AddToolSettings(devcontainerJsonBytes, nodeTools)
+	if err != nil {
+		return layer, err
+	}
+	updatedBytes = bytes.ReplaceAll(updatedBytes, []byte("{{layerDir}}"), []byte(layer.Path))
+	if err := utils.WriteFile(path.Join(layer.Path, "devcontainer.json"), updatedBytes); err != nil {
+		return layer, fmt.Errorf("failed to write devcontainer.json: %w", err)
+	}
+
+	// Each tool is in its own npm prefix and only reinstalled if its pinned version or the Node.js version changed
+	pinnedTools, err := installNodeTools(layer, nodeTools)
+	if err != nil {
+		return layer, err
+	}
+
+	// Update devcontainer.json search path for finalize buildpack to pull in properties
+	layer.BuildEnvironment.Append(devcontainer.FINALIZE_JSON_SEARCH_PATH_ENV_VAR_NAME, string(filepath.ListSeparator), layer.Path)
+
+	// Set the layer types based on what was set for the contributor
+	layer.LayerTypes = contrib.LayerTypes
+	layer.Metadata = map[string]interface{}{
+		base.PINNED_TOOLS_METADATA_NAME: base.PinnedToolsMetadata(pinnedTools),
+	}
+
+	return layer, nil
+}
+
+// Adds the default settings for tools like typescript, with settings from .devpacks/tools.toml taking precedence
+func addDefaultToolSettings(nodeTools []tools.Tool) []tools.Tool {
+	for i, tool := range nodeTools {
+		defaultSettings, hasDefaults := defaultToolSettings[tool.Name]
+		if !hasDefaults {
+			continue
+		}
+		settings := make(map[string]interface{}, len(defaultSettings)+len(tool.Settings))
+		for key, value := range defaultSettings {
+			settings[key] = value
+		}
+		for key, value := range tool.Settings {
+			settings[key] = value
+		}
+		nodeTools[i].Settings = settings
+	}
+	return nodeTools
+}
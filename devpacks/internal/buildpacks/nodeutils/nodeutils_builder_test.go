@@ -0,0 +1,126 @@
+package nodeutils
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/chuxel/devpacks/internal/buildpacks/base"
+	"github.com/chuxel/devpacks/internal/common/harness"
+	"github.com/chuxel/devpacks/internal/common/tools"
+)
+
+func TestAddDefaultToolSettings(t *testing.T) {
+	nodeTools := addDefaultToolSettings([]tools.Tool{
+		{Name: "typescript"},
+		{Name: "eslint", Settings: map[string]interface{}{"eslint.nodePath": "/custom", "eslint.enable": true}},
+		{Name: "nodemon"},
+	})
+	expected := []map[string]interface{}{
+		{"typescript.tsdk": "{{layerDir}}/tools/typescript/lib/node_modules/typescript/lib"},
+		{"eslint.nodePath": "/custom", "eslint.enable": true},
+		nil,
+	}
+	for i, tool := range nodeTools {
+		if !reflect.DeepEqual(tool.Settings, expected[i]) {
+			t.Errorf("expected settings %v for %s, got %v", expected[i], tool.Name, tool.Settings)
+		}
+	}
+}
+
+// Creates a devcontainer mode harness with a fake npm that records each install in npm.log. Packages
+// are installed like "npm install --global --prefix" would, with "latest" resolving to 15.2.0.
+func newNodeUtilsHarness(t *testing.T) (*harness.Harness, string) {
+	t.Helper()
+	h, err := harness.NewHarness(BUILDPACK_NAME, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.BuildMode = "devcontainer"
+	logPath := filepath.Join(t.TempDir(), "npm.log")
+	if err := h.AddFakeCommand("npm", `prefix="$4"
+spec="$(eval echo \${$#})"
+echo "$spec" >> `+logPath+`
+name="${spec%@*}"
+version="${spec##*@}"
+if [ "$version" = "latest" ]; then version="15.2.0"; fi
+mkdir -p "$prefix/lib/node_modules/$name" "$prefix/bin"
+echo "{\"name\": \"$name\", \"version\": \"$version\"}" > "$prefix/lib/node_modules/$name/package.json"
+touch "$prefix/bin/$(basename "$name")"
+chmod +x "$prefix/bin/$(basename "$name")"`); err != nil {
+		h.Cleanup()
+		t.Fatal(err)
+	}
+	h.Setenv("NODE_VERSION", "18.18.2")
+	h.Setenv("BP_NODE_UTILS", "typescript @angular/cli@15.1.0")
+	return h, logPath
+}
+
+func readNpmLog(t *testing.T, logPath string) []string {
+	t.Helper()
+	content, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSpace(string(content)), "\n")
+}
+
+func TestNodeUtilsBuilder(t *testing.T) {
+	h, logPath := newNodeUtilsHarness(t)
+	defer h.Cleanup()
+	plan, err := h.DefaultPlan()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := h.Build(NodeUtilsBuilder{}, plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	layer, hasLayer := output.Layer(BUILDPACK_NAME)
+	if !hasLayer {
+		t.Fatal("no nodeutils layer contributed")
+	}
+	// "latest" is pinned to the version it resolved to
+	pinnedTools := base.PinnedToolsFromLayer(layer)
+	if tool := pinnedTools["typescript"]; tool.Requested != "latest" || tool.Version != "15.2.0" {
+		t.Errorf("expected typescript latest to be pinned to 15.2.0, got %+v", tool)
+	}
+	if tool := pinnedTools["@angular/cli"]; tool.Requested != "15.1.0" || tool.Version != "15.1.0" {
+		t.Errorf("expected @angular/cli 15.1.0, got %+v", tool)
+	}
+	for _, binary := range []string{"typescript", "cli"} {
+		if _, err := os.Stat(filepath.Join(layer.Path, "bin", binary)); err != nil {
+			t.Errorf("expected %s to be linked into the layer's bin folder: %v", binary, err)
+		}
+	}
+	devContainer, err := harness.LayerDevContainerJson(layer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings := devContainer.Properties["customizations"].(map[string]interface{})["vscode"].(map[string]interface{})["settings"].(map[string]interface{})
+	if tsdk := settings["typescript.tsdk"]; tsdk != filepath.Join(layer.Path, "tools", "typescript", "lib", "node_modules", "typescript", "lib") {
+		t.Errorf("expected typescript.tsdk in the layer, got %v", tsdk)
+	}
+
+	// A second build reuses both tools
+	if _, err := h.Build(NodeUtilsBuilder{}, plan); err != nil {
+		t.Fatal(err)
+	}
+	expectedLog := []string{"typescript@latest", "@angular/cli@15.1.0"}
+	if npmLog := readNpmLog(t, logPath); !reflect.DeepEqual(npmLog, expectedLog) {
+		t.Errorf("expected %v, got %v", expectedLog, npmLog)
+	}
+
+	// A new Node.js version reinstalls the pinned versions rather than "latest"
+	h.Setenv("NODE_VERSION", "20.9.0")
+	if _, err := h.Build(NodeUtilsBuilder{}, plan); err != nil {
+		t.Fatal(err)
+	}
+	expectedLog = append(expectedLog, "typescript@15.2.0", "@angular/cli@15.1.0")
+	if npmLog := readNpmLog(t, logPath); !reflect.DeepEqual(npmLog, expectedLog) {
+		t.Errorf("expected %v, got %v", expectedLog, npmLog)
+	}
+}
@@ -0,0 +1,71 @@
+package nodeutils
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/buildpacks/libcnb"
+	"github.com/chuxel/devpacks/internal/buildpacks/base"
+	"github.com/chuxel/devpacks/internal/buildpacks/nodejs"
+	"github.com/chuxel/devpacks/internal/common/devcontainer"
+	"github.com/chuxel/devpacks/internal/common/tools"
+)
+
+type NodeUtilsDetector struct {
+	// Implements base.DefaultDetector
+
+	// Detect(context libcnb.DetectContext) (libcnb.DetectResult, error)
+	// DoDetect(context libcnb.DetectContext) (bool, map[string]interface{}, error)
+	// Name() string
+	// AlwaysPass() bool
+}
+
+func (detector NodeUtilsDetector) Detect(context libcnb.DetectContext) (libcnb.DetectResult, error) {
+	return base.DefaultDetect(detector, context)
+}
+
+func (detector NodeUtilsDetector) Name() string {
+	return BUILDPACK_NAME
+}
+
+func (detector NodeUtilsDetector) AlwaysPass() bool {
+	return false
+}
+
+func (detector NodeUtilsDetector) DoDetect(context libcnb.DetectContext) (bool, []libcnb.BuildPlanRequire, map[string]interface{}, error) {
+	if devcontainer.ContainerImageBuildMode() != "devcontainer" {
+		log.Println("Skipping since not in devcontainer mode.")
+		return false, nil, nil, nil
+	}
+
+	// This buildpack always requires nodejs to install and run the tools
+	reqs := []libcnb.BuildPlanRequire{{Name: nodejs.BUILDPACK_NAME, Metadata: map[string]interface{}{
+		"build":  true,
+		"launch": true,
+	}}}
+
+	// Can be specified in project.toml or pack command line
+	if os.Getenv(nodejs.NODE_VERSION_ENV_VAR_NAME) != "" || os.Getenv("BP_NODE_UTILS") != "" {
+		return true, reqs, nil, nil
+	}
+
+	// Or a tools section in .devpacks/tools.toml
+	toolsFile, err := tools.LoadToolsFile(context.Application.Path)
+	if err != nil {
+		return false, nil, nil, err
+	}
+	if toolsFile.HasEcosystem(TOOLS_FILE_ECOSYSTEM) {
+		log.Println("Detection passed.")
+		return true, reqs, nil, nil
+	}
+
+	// Look for package.json in the root
+	if _, err := os.Stat(filepath.Join(context.Application.Path, "package.json")); err == nil {
+		log.Println("Detection passed.")
+		return true, reqs, nil, nil
+	}
+
+	log.Println("Node.js not detected.")
+	return false, nil, nil, nil
+}
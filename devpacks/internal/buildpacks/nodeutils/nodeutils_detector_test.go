@@ -0,0 +1,63 @@
+package nodeutils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chuxel/devpacks/internal/common/harness"
+)
+
+func TestNodeUtilsDetector(t *testing.T) {
+	tests := []struct {
+		name           string
+		buildMode      string
+		nodeUtils      string
+		files          map[string]string
+		expectedToPass bool
+	}{
+		{name: "package.json", buildMode: "devcontainer", files: map[string]string{"package.json": `{}`}, expectedToPass: true},
+		{name: "production", buildMode: "production", files: map[string]string{"package.json": `{}`}, expectedToPass: false},
+		{name: "BP_NODE_UTILS", buildMode: "devcontainer", nodeUtils: "typescript", expectedToPass: true},
+		{name: "tools.toml node section", buildMode: "devcontainer", files: map[string]string{".devpacks/tools.toml": "[node.tools.typescript]\n"}, expectedToPass: true},
+		{name: "tools.toml other section", buildMode: "devcontainer", files: map[string]string{".devpacks/tools.toml": "[go.tools.gopls]\n"}, expectedToPass: false},
+		{name: "no Node.js", buildMode: "devcontainer", files: map[string]string{"go.mod": "module test\n"}, expectedToPass: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h, err := harness.NewHarness(BUILDPACK_NAME, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer h.Cleanup()
+			h.BuildMode = test.buildMode
+			h.Setenv("BP_NODE_UTILS", test.nodeUtils)
+			h.Setenv("BP_NODE_VERSION", "")
+			for filename, content := range test.files {
+				if err := os.MkdirAll(filepath.Dir(filepath.Join(h.ApplicationPath, filename)), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(h.ApplicationPath, filename), []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			result, err := h.Detect(NodeUtilsDetector{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Pass != test.expectedToPass {
+				t.Fatalf("expected detection to pass to be %v", test.expectedToPass)
+			}
+			if !result.Pass {
+				return
+			}
+			if !harness.PlanProvides(result, BUILDPACK_NAME) {
+				t.Errorf("expected plan to provide %s", BUILDPACK_NAME)
+			}
+			if require, hasRequire := harness.PlanRequire(result, "nodejs"); !hasRequire || require.Metadata["build"] != true {
+				t.Errorf("expected nodejs to be required during the build, got %+v", require)
+			}
+		})
+	}
+}
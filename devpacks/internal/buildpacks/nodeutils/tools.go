@@ -0,0 +1,116 @@
+package nodeutils
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/buildpacks/libcnb"
+	"github.com/chuxel/devpacks/internal/buildpacks/base"
+	"github.com/chuxel/devpacks/internal/common/tools"
+	"github.com/chuxel/devpacks/internal/common/utils"
+)
+
+// Installs each package (e.g. typescript@4.9.5 or eslint) globally into its own npm prefix under the
+// layer's tools folder, reusing tools from a previous build when possible, and links their binaries
+// into the layer's bin folder. A version like "latest" is resolved once and then pinned in layer metadata.
+func installNodeTools(layer libcnb.Layer, nodeTools []tools.Tool) ([]base.PinnedTool, error) {
+	previousTools := base.PinnedToolsFromLayer(layer)
+	toolsPath := filepath.Join(layer.Path, TOOLS_FOLDER_NAME)
+
+	// Use a temp npm cache so it does not end up in the layer or the home folder
+	npmCache, err := os.MkdirTemp("", "npm-cache-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp folder: %w", err)
+	}
+	defer os.RemoveAll(npmCache)
+
+	pinnedTools := []base.PinnedTool{}
+	toolFolderNames := []string{}
+	for _, tool := range nodeTools {
+		name, requested := tool.Name, tool.Version
+		if requested == "" {
+			requested = "latest"
+		}
+		version := requested
+		previousTool, hasPrevious := previousTools[name]
+		if hasPrevious && previousTool.Requested == requested {
+			version = previousTool.Version
+		}
+		folderName := base.ToolFolderName(name)
+		toolFolderNames = append(toolFolderNames, folderName)
+		toolPrefix := filepath.Join(toolsPath, folderName)
+
+		cacheKey, err := nodeToolCacheKey(name, version)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := os.Stat(filepath.Join(toolPrefix, "lib", "node_modules", name)); err == nil && hasPrevious && previousTool.CacheKey == cacheKey {
+			log.Printf("Reusing %s@%s.\n", name, version)
+			pinnedTools = append(pinnedTools, previousTool)
+			continue
+		}
+
+		if err := os.RemoveAll(toolPrefix); err != nil {
+			return nil, fmt.Errorf("failed to remove %s: %w", folderName, err)
+		}
+		if _, err := utils.ExecCmd(layer.Path, false, "npm", "install", "--global", "--prefix", toolPrefix, "--cache", npmCache, "--no-audit", "--no-fund", name+"@"+version); err != nil {
+			return nil, err
+		}
+		resolvedVersion, err := installedNodeToolVersion(toolPrefix, name)
+		if err != nil {
+			return nil, err
+		}
+		if resolvedVersion != version {
+			log.Printf("Pinned %s@%s to %s.\n", name, version, resolvedVersion)
+		}
+		if cacheKey, err = nodeToolCacheKey(name, resolvedVersion); err != nil {
+			return nil, err
+		}
+		pinnedTools = append(pinnedTools, base.PinnedTool{Name: name, Requested: requested, Version: resolvedVersion, CacheKey: cacheKey})
+	}
+
+	if err := base.RemoveUnlistedToolFolders(toolsPath, toolFolderNames); err != nil {
+		return nil, err
+	}
+	if err := base.LinkToolBinaries(layer.Path, toolsPath, toolFolderNames); err != nil {
+		return nil, err
+	}
+	return pinnedTools, nil
+}
+
+// Splits typescript@4.9.5 or @angular/cli@15 into the package and version, defaulting to "latest"
+func splitPackageVersion(pkg string) (string, string) {
+	if at := strings.LastIndex(pkg, "@"); at > 0 {
+		return pkg[:at], pkg[at+1:]
+	}
+	return pkg, "latest"
+}
+
+// Packages can include native modules, so tools are reinstalled if the Node.js version changes
+func nodeToolCacheKey(name string, version string) (string, error) {
+	cacheKey := base.CacheKey{EnvVarNames: []string{"NODE_VERSION"}, Values: []string{name, version}}
+	return cacheKey.Sum()
+}
+
+// Reads the version from the package.json of the installed package
+func installedNodeToolVersion(toolPrefix string, name string) (string, error) {
+	packageJsonPath := filepath.Join(toolPrefix, "lib", "node_modules", name, "package.json")
+	packageJsonBytes, err := os.ReadFile(packageJsonPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", packageJsonPath, err)
+	}
+	var packageJson struct {
+		Version string
+	}
+	if err := json.Unmarshal(packageJsonBytes, &packageJson); err != nil {
+		return "", fmt.Errorf("failed to parse %s: %w", packageJsonPath, err)
+	}
+	if packageJson.Version == "" {
+		return "", fmt.Errorf("unable to determine the version of %s", name)
+	}
+	return packageJson.Version, nil
+}
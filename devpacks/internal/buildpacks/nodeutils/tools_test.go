@@ -0,0 +1,37 @@
+package nodeutils
+
+import (
+	"testing"
+
+	"github.com/chuxel/devpacks/internal/buildpacks/base"
+)
+
+func TestSplitPackageVersion(t *testing.T) {
+	tests := map[string][2]string{
+		"typescript":         {"typescript", "latest"},
+		"typescript@4.9.5":   {"typescript", "4.9.5"},
+		"eslint@^8":          {"eslint", "^8"},
+		"@angular/cli":       {"@angular/cli", "latest"},
+		"@angular/cli@15":    {"@angular/cli", "15"},
+		"@vue/cli@next":      {"@vue/cli", "next"},
+		"@types/node@18.0.0": {"@types/node", "18.0.0"},
+	}
+	for pkg, expected := range tests {
+		if name, version := splitPackageVersion(pkg); name != expected[0] || version != expected[1] {
+			t.Errorf("expected %s to split into %v, got %s and %s", pkg, expected, name, version)
+		}
+	}
+}
+
+func TestToolFolderNameScoped(t *testing.T) {
+	tests := map[string]string{
+		"typescript":   "typescript",
+		"@angular/cli": "_angular_cli",
+		"@vue/cli":     "_vue_cli",
+	}
+	for name, expected := range tests {
+		if folderName := base.ToolFolderName(name); folderName != expected {
+			t.Errorf("expected folder %s for %s, got %s", expected, name, folderName)
+		}
+	}
+}
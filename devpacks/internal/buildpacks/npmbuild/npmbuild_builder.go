@@ -5,6 +5,7 @@ import (
 
 	"github.com/buildpacks/libcnb"
 	"github.com/chuxel/devpacks/internal/buildpacks/base"
+	"github.com/chuxel/devpacks/internal/buildpacks/nodejs"
 	"github.com/chuxel/devpacks/internal/common/utils"
 )
 
@@ -50,8 +51,16 @@ func (contrib NpmBuildLayerContributor) Name() string {
 func (contrib NpmBuildLayerContributor) Contribute(layer libcnb.Layer) (libcnb.Layer, error) {
 	// TODO: Implement caching scheme, archive off copies - right now this is dumb and just invokes npm run build
 
-	// Execute npm install
-	utils.ExecCmd(contrib.Context.Application.Path, false, "npm", "run", "build")
+	packageManager, err := nodejs.DetectPackageManager(contrib.Context.Application.Path)
+	if err != nil {
+		return layer, err
+	}
+
+	// Execute npm run build (or yarn / pnpm equivalent)
+	buildCommand := packageManager.RunScriptCommand("build")
+	if _, err := utils.ExecCmd(contrib.Context.Application.Path, false, buildCommand[0], buildCommand[1:]...); err != nil {
+		return layer, err
+	}
 
 	// Only keep the layer around for caching purposes since the
 	// node_modules folder is in the workspace folder in this scenario
@@ -2,6 +2,7 @@ package npmbuild
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"os"
 	"path/filepath"
@@ -35,10 +36,18 @@ func (detector NpmBuildDetector) AlwaysPass() bool {
 }
 
 func (detector NpmBuildDetector) DoDetect(context libcnb.DetectContext) (bool, []libcnb.BuildPlanRequire, map[string]interface{}, error) {
-	if devcontainer.ContainerImageBuildMode() == "devcontainer" || !detector.hasNpmBuild(context.Application.Path) {
+	if devcontainer.ContainerImageBuildMode() == "devcontainer" {
 		log.Println("Skipping. Detected devcontainer build mode.")
 		return false, nil, nil, nil
 	}
+	hasNpmBuild, err := detector.hasNpmBuild(context.Application.Path)
+	if err != nil {
+		return false, nil, nil, err
+	}
+	if !hasNpmBuild {
+		log.Println("Skipping. Did not find npm build script.")
+		return false, nil, nil, nil
+	}
 
 	// This buildpack always requires nodejs
 	reqs := []libcnb.BuildPlanRequire{
@@ -54,7 +63,7 @@ func (detector NpmBuildDetector) DoDetect(context libcnb.DetectContext) (bool, [
 	return true, reqs, nil, nil
 }
 
-func (contrib NpmBuildDetector) hasNpmBuild(appPath string) bool {
+func (contrib NpmBuildDetector) hasNpmBuild(appPath string) (bool, error) {
 	packageJsonPath := filepath.Join(appPath, "package.json")
 	// Get engine value for nodejs if it exists in package.json
 	if _, err := os.Stat(packageJsonPath); err == nil {
@@ -64,14 +73,14 @@ func (contrib NpmBuildDetector) hasNpmBuild(appPath string) bool {
 		var packageJson PackageJson
 		content, err := os.ReadFile(packageJsonPath)
 		if err != nil {
-			log.Fatal("Failed to read package.json", err)
+			return false, fmt.Errorf("failed to read package.json: %w", err)
 		}
 		if err := json.Unmarshal(content, &packageJson); err != nil {
-			log.Fatal("Failed to parse package.json", err)
+			return false, fmt.Errorf("failed to parse package.json: %w", err)
 		}
 		_, hasKey := packageJson.Scripts["build"]
-		return hasKey
+		return hasKey, nil
 	}
 
-	return false
+	return false, nil
 }
@@ -0,0 +1,71 @@
+package npmbuild
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chuxel/devpacks/internal/common/harness"
+)
+
+func TestNpmBuildDetector(t *testing.T) {
+	tests := []struct {
+		name           string
+		buildMode      string
+		packageJson    string
+		expectedToPass bool
+	}{
+		{name: "build script", buildMode: "production", packageJson: `{"scripts": {"build": "tsc"}}`, expectedToPass: true},
+		{name: "devcontainer", buildMode: "devcontainer", packageJson: `{"scripts": {"build": "tsc"}}`, expectedToPass: false},
+		{name: "no build script", buildMode: "production", packageJson: `{"scripts": {"start": "node index.js"}}`, expectedToPass: false},
+		{name: "no package.json", buildMode: "production", expectedToPass: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h, err := harness.NewHarness(BUILDPACK_NAME, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer h.Cleanup()
+			h.BuildMode = test.buildMode
+			if test.packageJson != "" {
+				if err := os.WriteFile(filepath.Join(h.ApplicationPath, "package.json"), []byte(test.packageJson), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			result, err := h.Detect(NpmBuildDetector{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Pass != test.expectedToPass {
+				t.Fatalf("expected detection to pass to be %v", test.expectedToPass)
+			}
+			if !result.Pass {
+				return
+			}
+			if !harness.PlanProvides(result, BUILDPACK_NAME) {
+				t.Errorf("expected plan to provide %s", BUILDPACK_NAME)
+			}
+			for _, name := range []string{"nodejs", "npminstall"} {
+				if require, hasRequire := harness.PlanRequire(result, name); !hasRequire || require.Metadata["build"] != true {
+					t.Errorf("expected %s to be required during the build, got %+v", name, require)
+				}
+			}
+		})
+	}
+}
+
+func TestNpmBuildDetectorInvalidPackageJson(t *testing.T) {
+	h, err := harness.NewHarness(BUILDPACK_NAME, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Cleanup()
+	if err := os.WriteFile(filepath.Join(h.ApplicationPath, "package.json"), []byte(`{"scripts":`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.Detect(NpmBuildDetector{}); err == nil {
+		t.Error("expected an error for an invalid package.json")
+	}
+}
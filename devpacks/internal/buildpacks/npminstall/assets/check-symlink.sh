@@ -1,3 +0,0 @@
-if [ ! -e "${WORKSPACE_FOLDER}/node_modules" ]; then
-    ln -s "${NPM_INSTALL_LAYER}/node_modules" "${WORKSPACE_FOLDER}/node_modules"
-fi
\ No newline at end of file
@@ -1,3 +1,17 @@
 package npminstall
 
 const BUILDPACK_NAME = "npminstall"
+
+// Cache-only layer for the npm (or yarn / pnpm) package cache
+const CACHE_LAYER_NAME = "npm-cache"
+
+// Files in the workspace that determine the installed dependencies, used in the cache key
+var PACKAGE_MANAGER_FILES = []string{"package.json", "package-lock.json", "npm-shrinkwrap.json", "yarn.lock", "pnpm-lock.yaml", "pnpm-workspace.yaml", ".npmrc", ".yarnrc", ".yarnrc.yml", ".pnpmfile.cjs"}
+
+// Set to "false" to keep devDependencies in production images. Otherwise the npmprune buildpack
+// adds a launch layer without them and the layer from this buildpack is only used during the build.
+const PRUNE_DEV_DEPENDENCIES_ENV_VAR_NAME = "BP_NODE_PRUNE_DEV_DEPENDENCIES"
+
+// Set in the metadata of npmprune's plan requirement so this buildpack knows another layer will
+// provide node_modules at launch
+const PRUNE_PLAN_METADATA_NAME = "prune"
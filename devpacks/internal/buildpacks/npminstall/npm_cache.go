@@ -0,0 +1,63 @@
+package npminstall
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/buildpacks/libcnb"
+	"github.com/chuxel/devpacks/internal/buildpacks/base"
+	"github.com/chuxel/devpacks/internal/buildpacks/nodejs"
+)
+
+type NpmCacheLayerContributor struct {
+	// Implements libcnb.LayerContributor
+
+	// Contribute(context libcnb.ContributeContext) (libcnb.Layer, error)
+	// Name() string
+}
+
+// Path to the package cache in the cache layer
+func NpmCachePath(context libcnb.BuildContext) string {
+	return filepath.Join(context.Layers.Path, CACHE_LAYER_NAME)
+}
+
+// Env vars that point npm, yarn and pnpm at the package cache
+func npmCacheEnv(cachePath string) map[string]string {
+	return map[string]string{
+		"npm_config_cache":     filepath.Join(cachePath, "npm"),
+		"YARN_CACHE_FOLDER":    filepath.Join(cachePath, "yarn"),
+		"npm_config_store_dir": filepath.Join(cachePath, "pnpm"),
+	}
+}
+
+// Implementation of libcnb.LayerContributor.Name
+func (contrib NpmCacheLayerContributor) Name() string {
+	return CACHE_LAYER_NAME
+}
+
+// Implementation of libcnb.LayerContributor.Contribute
+func (contrib NpmCacheLayerContributor) Contribute(layer libcnb.Layer) (libcnb.Layer, error) {
+	if err := os.MkdirAll(layer.Path, 0755); err != nil {
+		return layer, fmt.Errorf("unable to create layer folder %s: %w", layer.Path, err)
+	}
+	layer.LayerTypes = libcnb.LayerTypes{
+		Build:  false,
+		Cache:  true,
+		Launch: false,
+	}
+	return layer, nil
+}
+
+// Cache key for installed node_modules. Native modules are built for a specific Node.js version, so
+// NODE_VERSION from the nodejs buildpack is included along with the package manager files.
+func NpmCacheKey(appPath string, packageManager nodejs.PackageManager) (string, error) {
+	cacheKey := base.CacheKey{
+		EnvVarNames: []string{"NODE_VERSION", "NODE_ENV", "npm_config_registry"},
+		Values:      []string{packageManager.Name + "@" + packageManager.Version},
+	}
+	for _, filename := range PACKAGE_MANAGER_FILES {
+		cacheKey.Files = append(cacheKey.Files, filepath.Join(appPath, filename))
+	}
+	return cacheKey.Sum()
+}
@@ -2,17 +2,17 @@ package npminstall
 
 import (
 	"bytes"
-	"crypto/sha256"
 	_ "embed"
-	"encoding/base64"
 	"fmt"
 	"log"
 	"os"
 	"path"
 	"path/filepath"
+	"strings"
 
 	"github.com/buildpacks/libcnb"
 	"github.com/chuxel/devpacks/internal/buildpacks/base"
+	"github.com/chuxel/devpacks/internal/buildpacks/nodejs"
 	"github.com/chuxel/devpacks/internal/common/devcontainer"
 	"github.com/chuxel/devpacks/internal/common/utils"
 )
@@ -20,9 +20,6 @@ import (
 //go:embed assets/devcontainer.json
 var devcontainerJsonBytes []byte
 
-//go:embed assets/check-symlink.sh
-var symlinkScript []byte
-
 type NpmInstallBuilder struct {
 	// Implements base.DefaultBuilder
 
@@ -56,6 +53,15 @@ func (builder NpmInstallBuilder) NewLayerContributor(buildMode string, layerType
 	return NpmInstallLayerContributor{BuildMode: buildMode, LayerTypes: layerTypes, Context: context}
 }
 
+// Implementation of base.AdditionalLayersBuilder.AdditionalLayerContributors
+func (builder NpmInstallBuilder) AdditionalLayerContributors(buildMode string, context libcnb.BuildContext) []libcnb.LayerContributor {
+	// Dependencies are only installed during the build in production mode
+	if buildMode == "devcontainer" {
+		return nil
+	}
+	return []libcnb.LayerContributor{NpmCacheLayerContributor{}}
+}
+
 // Implementation of libcnb.LayerContributor.Name
 func (contrib NpmInstallLayerContributor) Name() string {
 	return BUILDPACK_NAME
@@ -63,15 +69,22 @@ func (contrib NpmInstallLayerContributor) Name() string {
 
 // Implementation of libcnb.LayerContributor.Contribute
 func (contrib NpmInstallLayerContributor) Contribute(layer libcnb.Layer) (libcnb.Layer, error) {
+	packageManager, err := nodejs.DetectPackageManager(contrib.Context.Application.Path)
+	if err != nil {
+		return layer, err
+	}
+	installCommand := packageManager.InstallCommand()
+
 	// Just add a post create command in the devcontainer mode
 	if devcontainer.ContainerImageBuildMode() == "devcontainer" {
 		log.Println("Detected devcontainer build mode - adding devcontainer.json contents.")
 		if err := os.MkdirAll(layer.Path, 0755); err != nil {
-			log.Fatal("Unable to create layer folder. ", err)
+			return layer, fmt.Errorf("unable to create layer folder: %w", err)
 		}
 		updatedBytes := bytes.ReplaceAll(devcontainerJsonBytes, []byte("{{layerDir}}"), []byte(layer.Path))
+		updatedBytes = bytes.ReplaceAll(updatedBytes, []byte("{{installCommand}}"), []byte(strings.Join(installCommand, " ")))
 		if err := utils.WriteFile(path.Join(layer.Path, "devcontainer.json"), updatedBytes); err != nil {
-			log.Fatal("Unable to write devcontainer.json. ", err)
+			return layer, fmt.Errorf("unable to write devcontainer.json: %w", err)
 		}
 		// Update devcontainer.json search path for finalize buildpack to pull in properties
 		layer.BuildEnvironment.Append(devcontainer.FINALIZE_JSON_SEARCH_PATH_ENV_VAR_NAME, string(filepath.ListSeparator), layer.Path)
@@ -84,66 +97,112 @@ func (contrib NpmInstallLayerContributor) Contribute(layer libcnb.Layer) (libcnb
 
 	}
 
-	// Determine sha256 of package-lock.json
-	packageLockBytes, err := os.ReadFile(filepath.Join(contrib.Context.Application.Path, "package-lock.json"))
+	// Determine the cache key from the package manager files, Node.js version and package manager
+	currentHash, err := NpmCacheKey(contrib.Context.Application.Path, packageManager)
 	if err != nil {
-		log.Fatal("Failed to load package-lock.json. Be sure this file is in your repository. ", err)
+		return layer, err
 	}
-	hashGen := sha256.New()
-	currentHash := base64.StdEncoding.EncodeToString(hashGen.Sum(packageLockBytes))
-	// Use sha256 to see if layer already exists and is the same so we can reuse
+
+	// node_modules lives in the layer and is symlinked into the workspace, so the layer is needed at launch
+	// unless npmprune is in the plan and will be adding one without devDependencies
 	layerNodeModules := filepath.Join(layer.Path, "node_modules")
-	if layer.Metadata["sha256"] != nil {
-		if currentHash == fmt.Sprint(layer.Metadata["sha256"]) {
-			log.Println("Reusing cached layer.")
-			// Reuse contents from cache by setting up a symlink
-			// The symlink created here will only apply to the build image and we need it in the launch image.
-			// So add a profile.d script that the launcher will fire to verify, but we need to add some paths
-			script := fmt.Sprintf("#!/bin/bash\nWORKSPACE_FOLDER=\"%s\"\nNPM_INSTALL_LAYER=\"%s\"\n%s", contrib.Context.Application.Path, layer.Path, symlinkScript)
-			layer.Profile.Add("check-symlink", script)
-			layer.LayerTypes = libcnb.LayerTypes{
-				Build:  true,
-				Cache:  true,
-				Launch: true,
-			}
-			return layer, nil
-		} else {
-			// Otherwise remove layer node_modules since we'll need to recreate
-			if err := os.RemoveAll(layerNodeModules); err != nil {
-				log.Fatal("Failed to remove ", layerNodeModules, ". ", err)
-			}
-			if err := os.MkdirAll(layer.Path, 0755); err != nil {
-				log.Fatal("Unable to create layer folder. ", err)
-			}
-		}
+	layer.LayerTypes = libcnb.LayerTypes{
+		Build:  true,
+		Cache:  true,
+		Launch: !npmPruneInPlan(contrib.Context.Plan),
 	}
+	layer.LaunchEnvironment.Override("NODE_PATH", layerNodeModules)
 
-	// Remove existing node_modules folder if found - we're going to symlink it
-	appNodeModules := filepath.Join(contrib.Context.Application.Path, "node_modules")
-	if _, err := os.Stat(appNodeModules); err != nil {
-		if err := os.RemoveAll(appNodeModules); err != nil {
-			log.Fatal("Failed to remove ", appNodeModules, ". ", err)
-		}
+	// Make the package cache available to later buildpacks (e.g. npmprune)
+	for name, value := range npmCacheEnv(NpmCachePath(contrib.Context)) {
+		os.Setenv(name, value)
+		layer.BuildEnvironment.Override(name, value)
+	}
+	// Keep Yarn 2+ from using Plug'n'Play so there is a node_modules folder for the build and at launch
+	for name, value := range packageManager.Env() {
+		os.Setenv(name, value)
+		layer.BuildEnvironment.Override(name, value)
+		layer.LaunchEnvironment.Override(name, value)
 	}
 
-	// Execute npm install
-	utils.ExecCmd(contrib.Context.Application.Path, false, "npm", "install")
+	// Use the cache key to see if layer already exists and is the same so we can reuse
+	if base.LayerMatchesCacheKey(layer, currentHash) {
+		log.Println("Reusing cached layer.")
+		if err := LinkNodeModules(contrib.Context.Application.Path, layerNodeModules); err != nil {
+			return layer, err
+		}
+		return layer, nil
+	}
 
-	// Unfortunately, a "move" doesn't work  since we're across storage devices, so
-	// copy node_modules to layer for future reuse, but mark the layer for caching only
-	utils.CpR(appNodeModules, layer.Path)
+	// Otherwise install in the workspace so workspaces, local dependencies and scripts can use the rest of
+	// the source tree, then move node_modules into the layer and symlink it back
+	if err := os.RemoveAll(layer.Path); err != nil {
+		return layer, fmt.Errorf("failed to remove %s: %w", layer.Path, err)
+	}
+	if err := os.MkdirAll(layer.Path, 0755); err != nil {
+		return layer, fmt.Errorf("unable to create layer folder: %w", err)
+	}
 
-	// Only keep the layer around for caching purposes since the
-	// node_modules folder is in the workspace folder in this scenario
-	layer.LayerTypes = libcnb.LayerTypes{
-		Build:  false,
-		Cache:  true,
-		Launch: false,
+	// Execute npm ci if there is a lockfile, otherwise npm install (or yarn / pnpm equivalents). The
+	// package cache is in a separate cache-only layer so a changed lockfile avoids re-downloading everything.
+	cleanInstallCommand := packageManager.CleanInstallCommand()
+	if err := InstallNodeModules(contrib.Context.Application.Path, layerNodeModules, cleanInstallCommand); err != nil {
+		return layer, err
 	}
+
 	// Add layer metadata (e.g. hash)
 	layer.Metadata = map[string]interface{}{
-		"sha256": currentHash,
+		base.CACHE_KEY_METADATA_NAME: currentHash,
 	}
 
 	return layer, nil
 }
+
+// Runs the install command in the workspace without an existing node_modules folder or symlink,
+// and then moves the resulting node_modules folder to layerNodeModules and symlinks it back
+func InstallNodeModules(appPath string, layerNodeModules string, installCommand []string) error {
+	appNodeModules := filepath.Join(appPath, "node_modules")
+	if err := os.RemoveAll(appNodeModules); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", appNodeModules, err)
+	}
+	if _, err := utils.ExecCmd(appPath, false, installCommand[0], installCommand[1:]...); err != nil {
+		return err
+	}
+	// Package managers skip node_modules if there is nothing to install
+	if _, err := os.Lstat(appNodeModules); err != nil {
+		if err := os.MkdirAll(layerNodeModules, 0755); err != nil {
+			return fmt.Errorf("unable to create %s: %w", layerNodeModules, err)
+		}
+	} else if err := utils.MoveFolder(appNodeModules, layerNodeModules); err != nil {
+		return err
+	}
+	return LinkNodeModules(appPath, layerNodeModules)
+}
+
+// Replaces any node_modules folder in the workspace with a symlink to the one in the layer
+func LinkNodeModules(appPath string, layerNodeModules string) error {
+	appNodeModules := filepath.Join(appPath, "node_modules")
+	if err := os.RemoveAll(appNodeModules); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", appNodeModules, err)
+	}
+	if err := os.Symlink(layerNodeModules, appNodeModules); err != nil {
+		return fmt.Errorf("failed to link %s to %s: %w", appNodeModules, layerNodeModules, err)
+	}
+	return nil
+}
+
+// Checks for the requirement from npmprune rather than PruneDevDependencies since npmprune also
+// needs a package.json and can be left out of the builder
+func npmPruneInPlan(plan libcnb.BuildpackPlan) bool {
+	for _, entry := range plan.Entries {
+		if prune, isBool := entry.Metadata[PRUNE_PLAN_METADATA_NAME].(bool); entry.Name == BUILDPACK_NAME && isBool && prune {
+			return true
+		}
+	}
+	return false
+}
+
+// Production images do not include devDependencies unless BP_NODE_PRUNE_DEV_DEPENDENCIES is "false"
+func PruneDevDependencies() bool {
+	return devcontainer.ContainerImageBuildMode() != "devcontainer" && os.Getenv(PRUNE_DEV_DEPENDENCIES_ENV_VAR_NAME) != "false"
+}
@@ -0,0 +1,231 @@
+package npminstall
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/buildpacks/libcnb"
+	"github.com/chuxel/devpacks/internal/common/devcontainer"
+	"github.com/chuxel/devpacks/internal/common/harness"
+)
+
+// Creates a harness for an app with a lockfile and a fake npm that records each run in npm.log
+func newNpmInstallHarness(t *testing.T) (*harness.Harness, string) {
+	t.Helper()
+	h, err := harness.NewHarness(BUILDPACK_NAME, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"package.json":      `{"name": "test", "dependencies": {"left-pad": "^1.3.0"}}`,
+		"package-lock.json": `{"name": "test", "lockfileVersion": 3}`,
+	}
+	for filename, content := range files {
+		if err := os.WriteFile(filepath.Join(h.ApplicationPath, filename), []byte(content), 0644); err != nil {
+			h.Cleanup()
+			t.Fatal(err)
+		}
+	}
+	logPath := filepath.Join(t.TempDir(), "npm.log")
+	if err := h.AddFakeCommand("npm", `echo "$@" >> `+logPath+`
+mkdir -p node_modules/left-pad`); err != nil {
+		h.Cleanup()
+		t.Fatal(err)
+	}
+	h.Setenv("NODE_VERSION", "18.18.2")
+	return h, logPath
+}
+
+func TestNpmInstallBuilder(t *testing.T) {
+	h, logPath := newNpmInstallHarness(t)
+	defer h.Cleanup()
+	plan, err := h.DefaultPlan()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := h.Build(NpmInstallBuilder{}, plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	layer, hasLayer := output.Layer(BUILDPACK_NAME)
+	if !hasLayer {
+		t.Fatal("no npminstall layer contributed")
+	}
+	if _, hasCacheLayer := output.Layer(CACHE_LAYER_NAME); !hasCacheLayer {
+		t.Error("expected a package cache layer in production mode")
+	}
+	// npmprune is not in the plan, so the layer is needed at launch
+	if !layer.LayerTypes.Build || !layer.LayerTypes.Cache || !layer.LayerTypes.Launch {
+		t.Errorf("unexpected layer types %+v", layer.LayerTypes)
+	}
+	if nodeModules, err := os.Readlink(filepath.Join(h.ApplicationPath, "node_modules")); err != nil || nodeModules != filepath.Join(layer.Path, "node_modules") {
+		t.Errorf("expected node_modules to link to the layer, got %s (%v)", nodeModules, err)
+	}
+	if _, err := os.Stat(filepath.Join(h.ApplicationPath, "node_modules", "left-pad")); err != nil {
+		t.Errorf("expected installed packages through the link: %v", err)
+	}
+	if nodePath, err := harness.ReadLayerEnvFile(layer, "env.launch", "NODE_PATH.override"); err != nil || nodePath != filepath.Join(layer.Path, "node_modules") {
+		t.Errorf("expected NODE_PATH to be the layer's node_modules, got %s (%v)", nodePath, err)
+	}
+
+	// The same lockfile reuses the layer, and a changed one installs again
+	if _, err := h.Build(NpmInstallBuilder{}, plan); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(h.ApplicationPath, "package-lock.json"), []byte(`{"name": "test", "lockfileVersion": 3, "packages": {}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.Build(NpmInstallBuilder{}, plan); err != nil {
+		t.Fatal(err)
+	}
+	npmLog, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(npmLog) != "ci\nci\n" {
+		t.Errorf("expected npm ci to run for the first and changed lockfile only, got %q", npmLog)
+	}
+}
+
+func TestNpmInstallBuilderWithPrune(t *testing.T) {
+	h, _ := newNpmInstallHarness(t)
+	defer h.Cleanup()
+	plan, err := h.DefaultPlan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan.Entries = append(plan.Entries, libcnb.BuildpackPlanEntry{Name: BUILDPACK_NAME, Metadata: map[string]interface{}{"build": true, PRUNE_PLAN_METADATA_NAME: true}})
+
+	output, err := h.Build(NpmInstallBuilder{}, plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// npmprune adds a launch layer without devDependencies instead
+	if layer, _ := output.Layer(BUILDPACK_NAME); !layer.LayerTypes.Build || layer.LayerTypes.Launch {
+		t.Errorf("unexpected layer types %+v", layer.LayerTypes)
+	}
+}
+
+func TestNpmInstallBuilderDevContainer(t *testing.T) {
+	h, logPath := newNpmInstallHarness(t)
+	defer h.Cleanup()
+	h.BuildMode = "devcontainer"
+	plan, err := h.DefaultPlan()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := h.Build(NpmInstallBuilder{}, plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, hasCacheLayer := output.Layer(CACHE_LAYER_NAME); hasCacheLayer {
+		t.Error("expected no package cache layer in devcontainer mode")
+	}
+	layer, _ := output.Layer(BUILDPACK_NAME)
+	devContainer, err := harness.LayerDevContainerJson(layer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if command := devContainer.Properties["postCreateCommand"]; command != "npm install" {
+		t.Errorf("expected postCreateCommand npm install, got %v", command)
+	}
+	if searchPath, err := harness.ReadLayerEnvFile(layer, "env.build", devcontainer.FINALIZE_JSON_SEARCH_PATH_ENV_VAR_NAME+".append"); err != nil || !strings.Contains(searchPath, layer.Path) {
+		t.Errorf("expected layer in finalize search path, got %s (%v)", searchPath, err)
+	}
+	if _, err := os.Stat(logPath); err == nil {
+		t.Error("expected npm not to run in devcontainer mode")
+	}
+}
+
+func TestNpmInstallBuilderYarnBerry(t *testing.T) {
+	h, err := harness.NewHarness(BUILDPACK_NAME, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Cleanup()
+	files := map[string]string{
+		"package.json": `{"name": "test", "packageManager": "yarn@4.0.2"}`,
+		"yarn.lock":    "__metadata:\n  version: 8\n",
+	}
+	for filename, content := range files {
+		if err := os.WriteFile(filepath.Join(h.ApplicationPath, filename), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Like Yarn 2+, only create node_modules when the node-modules linker is set
+	logPath := filepath.Join(t.TempDir(), "yarn.log")
+	if err := h.AddFakeCommand("yarn", `echo "$@" >> `+logPath+`
+if [ "$YARN_NODE_LINKER" = "node-modules" ]; then mkdir -p node_modules/left-pad; else touch .pnp.cjs; fi`); err != nil {
+		t.Fatal(err)
+	}
+	// The builder sets this for the process, so have the harness restore it afterwards
+	h.Setenv("YARN_NODE_LINKER", "")
+	plan, err := h.DefaultPlan()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := h.Build(NpmInstallBuilder{}, plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	layer, _ := output.Layer(BUILDPACK_NAME)
+	if _, err := os.Stat(filepath.Join(h.ApplicationPath, "node_modules", "left-pad")); err != nil {
+		t.Errorf("expected node_modules from the node-modules linker: %v", err)
+	}
+	for _, envFolder := range []string{"env.build", "env.launch"} {
+		if linker, err := harness.ReadLayerEnvFile(layer, envFolder, "YARN_NODE_LINKER.override"); err != nil || linker != "node-modules" {
+			t.Errorf("expected YARN_NODE_LINKER in %s, got %s (%v)", envFolder, linker, err)
+		}
+	}
+	if yarnLog, err := os.ReadFile(logPath); err != nil || string(yarnLog) != "install --immutable\n" {
+		t.Errorf("expected yarn install --immutable, got %q (%v)", yarnLog, err)
+	}
+}
+
+func TestNpmInstallBuilderWorkspaces(t *testing.T) {
+	h, err := harness.NewHarness(BUILDPACK_NAME, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Cleanup()
+	files := map[string]string{
+		"package.json":            `{"name": "test", "workspaces": ["packages/*"], "dependencies": {"a": "file:packages/a"}}`,
+		"package-lock.json":       `{"name": "test", "lockfileVersion": 3}`,
+		"packages/a/package.json": `{"name": "a", "scripts": {"postinstall": "node ../../scripts/postinstall.js"}}`,
+		"scripts/postinstall.js":  `console.log("installed")`,
+	}
+	for filename, content := range files {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(h.ApplicationPath, filename)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(h.ApplicationPath, filename), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Like npm, fail without the workspace's files and link the workspace package into node_modules
+	if err := h.AddFakeCommand("npm", `test -f packages/a/package.json && test -f scripts/postinstall.js || exit 1
+mkdir -p node_modules && ln -s ../packages/a node_modules/a`); err != nil {
+		t.Fatal(err)
+	}
+	plan, err := h.DefaultPlan()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := h.Build(NpmInstallBuilder{}, plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	layer, _ := output.Layer(BUILDPACK_NAME)
+	if _, err := os.Stat(filepath.Join(layer.Path, "node_modules", "a", "package.json")); err != nil {
+		t.Errorf("expected the workspace package to resolve from the layer: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(h.ApplicationPath, "node_modules", "a", "package.json")); err != nil {
+		t.Errorf("expected the workspace package to resolve from the workspace: %v", err)
+	}
+}
@@ -0,0 +1,25 @@
+package npminstall
+
+import (
+	"testing"
+
+	"github.com/chuxel/devpacks/internal/common/harness"
+)
+
+func TestNpmInstallDetector(t *testing.T) {
+	h, err := harness.NewHarness(BUILDPACK_NAME, "test/test-project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Cleanup()
+	result, err := h.Detect(NpmInstallDetector{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Pass || !harness.PlanProvides(result, BUILDPACK_NAME) {
+		t.Errorf("expected detection to pass and provide %s", BUILDPACK_NAME)
+	}
+	if require, hasRequire := harness.PlanRequire(result, "nodejs"); !hasRequire || require.Metadata["launch"] != true {
+		t.Errorf("expected nodejs to be required at launch, got %+v", require)
+	}
+}
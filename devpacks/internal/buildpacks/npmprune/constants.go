@@ -0,0 +1,3 @@
+package npmprune
+
+const BUILDPACK_NAME = "npmprune"
@@ -0,0 +1,111 @@
+package npmprune
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/buildpacks/libcnb"
+	"github.com/chuxel/devpacks/internal/buildpacks/base"
+	"github.com/chuxel/devpacks/internal/buildpacks/nodejs"
+	"github.com/chuxel/devpacks/internal/buildpacks/npminstall"
+)
+
+type NpmPruneBuilder struct {
+	// Implements base.DefaultBuilder
+
+	// Build(context libcnb.BuildContext) (libcnb.BuildResult, error)
+	// Name() string
+	// NewLayerContributor(buildMode string, layerTypes libcnb.LayerTypes, context libcnb.BuildContext) libcnb.BaseLayerContributor
+}
+
+type NpmPruneLayerContributor struct {
+	// Implements libcnb.LayerContributor
+
+	// Contribute(context libcnb.ContributeContext) (libcnb.Layer, error)
+	// Name() string
+
+	Context   libcnb.BuildContext
+	BuildMode string
+}
+
+func (builder NpmPruneBuilder) Build(context libcnb.BuildContext) (libcnb.BuildResult, error) {
+	return base.DefaultBuild(builder, context)
+}
+
+// Implementation of base.BaseBuilder.Name
+func (builder NpmPruneBuilder) Name() string {
+	return BUILDPACK_NAME
+}
+
+// Implementation of base.BaseBuilder.NewLayerContributor
+func (builder NpmPruneBuilder) NewLayerContributor(buildMode string, layerTypes libcnb.LayerTypes, context libcnb.BuildContext) libcnb.LayerContributor {
+	return NpmPruneLayerContributor{BuildMode: buildMode, Context: context}
+}
+
+// Implementation of libcnb.LayerContributor.Name
+func (contrib NpmPruneLayerContributor) Name() string {
+	return BUILDPACK_NAME
+}
+
+// Implementation of libcnb.LayerContributor.Contribute
+func (contrib NpmPruneLayerContributor) Contribute(layer libcnb.Layer) (libcnb.Layer, error) {
+	packageManager, err := nodejs.DetectPackageManager(contrib.Context.Application.Path)
+	if err != nil {
+		return layer, err
+	}
+
+	// Determine the cache key from the package manager files, Node.js version and package manager
+	currentHash, err := npminstall.NpmCacheKey(contrib.Context.Application.Path, packageManager)
+	if err != nil {
+		return layer, err
+	}
+
+	// Only needed at launch since npminstall's layer with all dependencies is used during the build
+	layerNodeModules := filepath.Join(layer.Path, "node_modules")
+	layer.LayerTypes = libcnb.LayerTypes{
+		Build:  false,
+		Cache:  true,
+		Launch: true,
+	}
+	layer.LaunchEnvironment.Override("NODE_PATH", layerNodeModules)
+	for name, value := range packageManager.Env() {
+		layer.LaunchEnvironment.Override(name, value)
+	}
+
+	// Use the cache key to see if layer already exists and is the same so we can reuse
+	if base.LayerMatchesCacheKey(layer, currentHash) {
+		log.Println("Reusing cached layer.")
+		if err := npminstall.LinkNodeModules(contrib.Context.Application.Path, layerNodeModules); err != nil {
+			return layer, err
+		}
+		return layer, nil
+	}
+
+	// Otherwise install production dependencies in the workspace like npminstall and move them into the layer
+	if err := os.RemoveAll(layer.Path); err != nil {
+		return layer, fmt.Errorf("failed to remove %s: %w", layer.Path, err)
+	}
+	if err := os.MkdirAll(layer.Path, 0755); err != nil {
+		return layer, fmt.Errorf("unable to create layer folder: %w", err)
+	}
+
+	// Execute npm ci --omit=dev (or yarn / pnpm equivalent). The package cache from npminstall is
+	// set in the environment, so packages should not need to be downloaded again. This also points the
+	// workspace at the pruned node_modules rather than the full set.
+	installCommand, err := packageManager.ProductionInstallCommand()
+	if err != nil {
+		return layer, err
+	}
+	if err := npminstall.InstallNodeModules(contrib.Context.Application.Path, layerNodeModules, installCommand); err != nil {
+		return layer, err
+	}
+
+	// Add layer metadata (e.g. hash)
+	layer.Metadata = map[string]interface{}{
+		base.CACHE_KEY_METADATA_NAME: currentHash,
+	}
+
+	return layer, nil
+}
@@ -0,0 +1,114 @@
+package npmprune
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chuxel/devpacks/internal/common/harness"
+)
+
+func TestNpmPruneBuilder(t *testing.T) {
+	tests := []struct {
+		name            string
+		files           map[string]string
+		command         string
+		expectedArgs    string
+		expectedLinker  string
+		expectedToError bool
+	}{
+		{
+			name:         "npm",
+			files:        map[string]string{"package.json": `{}`, "package-lock.json": `{}`},
+			command:      "npm",
+			expectedArgs: "ci --omit=dev",
+		},
+		{
+			name:         "pnpm",
+			files:        map[string]string{"package.json": `{}`, "pnpm-lock.yaml": "lockfileVersion: '6.0'\n"},
+			command:      "pnpm",
+			expectedArgs: "install --prod --frozen-lockfile",
+		},
+		{
+			name:         "yarn 1",
+			files:        map[string]string{"package.json": `{}`, "yarn.lock": "# yarn lockfile v1\n"},
+			command:      "yarn",
+			expectedArgs: "install --production --frozen-lockfile",
+		},
+		{
+			name:           "yarn 4",
+			files:          map[string]string{"package.json": `{"packageManager": "yarn@4.0.2"}`, "yarn.lock": "__metadata:\n  version: 8\n"},
+			command:        "yarn",
+			expectedArgs:   "workspaces focus --all --production",
+			expectedLinker: "node-modules",
+		},
+		{
+			name:            "yarn 3 without workspace-tools",
+			files:           map[string]string{"package.json": `{"packageManager": "yarn@3.6.4"}`, "yarn.lock": "__metadata:\n  version: 6\n"},
+			command:         "yarn",
+			expectedToError: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h, err := harness.NewHarness(BUILDPACK_NAME, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer h.Cleanup()
+			for filename, content := range test.files {
+				if err := os.WriteFile(filepath.Join(h.ApplicationPath, filename), []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			logPath := filepath.Join(t.TempDir(), test.command+".log")
+			if err := h.AddFakeCommand(test.command, `echo "$@" >> `+logPath+`
+mkdir -p node_modules/left-pad`); err != nil {
+				t.Fatal(err)
+			}
+			plan, err := h.DefaultPlan()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			output, err := h.Build(NpmPruneBuilder{}, plan)
+			if test.expectedToError {
+				if err == nil {
+					t.Error("expected the build to fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			layer, hasLayer := output.Layer(BUILDPACK_NAME)
+			if !hasLayer {
+				t.Fatal("no npmprune layer contributed")
+			}
+			if layer.LayerTypes.Build || !layer.LayerTypes.Cache || !layer.LayerTypes.Launch {
+				t.Errorf("unexpected layer types %+v", layer.LayerTypes)
+			}
+			layerNodeModules := filepath.Join(layer.Path, "node_modules")
+			if nodeModules, err := os.Readlink(filepath.Join(h.ApplicationPath, "node_modules")); err != nil || nodeModules != layerNodeModules {
+				t.Errorf("expected node_modules to link to the layer, got %s (%v)", nodeModules, err)
+			}
+			if _, err := os.Stat(filepath.Join(layerNodeModules, "left-pad")); err != nil {
+				t.Errorf("expected installed packages in the layer: %v", err)
+			}
+			if nodePath, err := harness.ReadLayerEnvFile(layer, "env.launch", "NODE_PATH.override"); err != nil || nodePath != layerNodeModules {
+				t.Errorf("expected NODE_PATH to be the layer's node_modules, got %s (%v)", nodePath, err)
+			}
+			if linker, _ := harness.ReadLayerEnvFile(layer, "env.launch", "YARN_NODE_LINKER.override"); linker != test.expectedLinker {
+				t.Errorf("expected YARN_NODE_LINKER %q at launch, got %q", test.expectedLinker, linker)
+			}
+
+			// The same lockfile reuses the layer
+			if _, err := h.Build(NpmPruneBuilder{}, plan); err != nil {
+				t.Fatal(err)
+			}
+			if commandLog, err := os.ReadFile(logPath); err != nil || string(commandLog) != test.expectedArgs+"\n" {
+				t.Errorf("expected %s %s to run once, got %q (%v)", test.command, test.expectedArgs, commandLog, err)
+			}
+		})
+	}
+}
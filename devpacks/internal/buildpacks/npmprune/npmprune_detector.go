@@ -0,0 +1,66 @@
+package npmprune
+
+import (
+	"log"
+	"os"
+	"path"
+
+	"github.com/buildpacks/libcnb"
+	"github.com/chuxel/devpacks/internal/buildpacks/base"
+	"github.com/chuxel/devpacks/internal/buildpacks/nodejs"
+	"github.com/chuxel/devpacks/internal/buildpacks/npminstall"
+)
+
+type NpmPruneDetector struct {
+	// Implements base.DefaultDetector
+
+	// Detect(context libcnb.DetectContext) (libcnb.DetectResult, error)
+	// DoDetect(context libcnb.DetectContext) (bool, map[string]interface{}, error)
+	// Name() string
+	// AlwaysPass() bool
+}
+
+func (detector NpmPruneDetector) Detect(context libcnb.DetectContext) (libcnb.DetectResult, error) {
+	return base.DefaultDetect(detector, context)
+}
+
+func (detector NpmPruneDetector) Name() string {
+	return BUILDPACK_NAME
+}
+
+func (detector NpmPruneDetector) AlwaysPass() bool {
+	return false
+}
+
+func (detector NpmPruneDetector) DoDetect(context libcnb.DetectContext) (bool, []libcnb.BuildPlanRequire, map[string]interface{}, error) {
+	// Only applies to production mode, and can be turned off
+	if !npminstall.PruneDevDependencies() {
+		log.Println("Skipping. Not in production mode or", npminstall.PRUNE_DEV_DEPENDENCIES_ENV_VAR_NAME, "is false.")
+		return false, nil, nil, nil
+	}
+	if _, err := os.Stat(path.Join(context.Application.Path, "package.json")); err != nil {
+		log.Println("No package.json found in ", context.Application.Path)
+		return false, nil, nil, nil
+	}
+	packageManager, err := nodejs.DetectPackageManager(context.Application.Path)
+	if err != nil {
+		return false, nil, nil, err
+	}
+	if packageManager.YarnBerry && !packageManager.YarnWorkspaceFocus {
+		log.Println("Warning: Skipping. Yarn 2 and 3 need the workspace-tools plugin to install production dependencies, so devDependencies will be kept. Run \"yarn plugin import workspace-tools\" or upgrade to Yarn 4 to remove them.")
+		return false, nil, nil, nil
+	}
+
+	// This buildpack always requires nodejs and the full set of dependencies from npminstall
+	reqs := []libcnb.BuildPlanRequire{
+		{Name: nodejs.BUILDPACK_NAME, Metadata: map[string]interface{}{
+			"build":  true,
+			"launch": true,
+		}}, {Name: npminstall.BUILDPACK_NAME, Metadata: map[string]interface{}{
+			"build":                             true,
+			npminstall.PRUNE_PLAN_METADATA_NAME: true,
+		}}}
+
+	log.Println("Detection passed.")
+	return true, reqs, nil, nil
+}
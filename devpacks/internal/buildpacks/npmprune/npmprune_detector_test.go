@@ -0,0 +1,64 @@
+package npmprune
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chuxel/devpacks/internal/buildpacks/npminstall"
+	"github.com/chuxel/devpacks/internal/common/harness"
+)
+
+func TestNpmPruneDetector(t *testing.T) {
+	tests := []struct {
+		name           string
+		buildMode      string
+		pruneEnv       string
+		packageJson    string
+		expectedToPass bool
+	}{
+		{name: "production", buildMode: "production", packageJson: `{}`, expectedToPass: true},
+		{name: "devcontainer", buildMode: "devcontainer", packageJson: `{}`, expectedToPass: false},
+		{name: "turned off", buildMode: "production", pruneEnv: "false", packageJson: `{}`, expectedToPass: false},
+		{name: "no package.json", buildMode: "production", expectedToPass: false},
+		{name: "yarn 4", buildMode: "production", packageJson: `{"packageManager": "yarn@4.0.2"}`, expectedToPass: true},
+		{name: "yarn 3 without workspace-tools", buildMode: "production", packageJson: `{"packageManager": "yarn@3.6.4"}`, expectedToPass: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h, err := harness.NewHarness(BUILDPACK_NAME, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer h.Cleanup()
+			h.BuildMode = test.buildMode
+			h.Setenv(npminstall.PRUNE_DEV_DEPENDENCIES_ENV_VAR_NAME, test.pruneEnv)
+			if test.packageJson != "" {
+				if err := os.WriteFile(filepath.Join(h.ApplicationPath, "package.json"), []byte(test.packageJson), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			result, err := h.Detect(NpmPruneDetector{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Pass != test.expectedToPass {
+				t.Fatalf("expected detection to pass to be %v", test.expectedToPass)
+			}
+			if !result.Pass {
+				return
+			}
+			if !harness.PlanProvides(result, BUILDPACK_NAME) {
+				t.Errorf("expected plan to provide %s", BUILDPACK_NAME)
+			}
+			// npminstall uses this to leave its layer out of the launch image
+			if require, hasRequire := harness.PlanRequire(result, npminstall.BUILDPACK_NAME); !hasRequire || require.Metadata[npminstall.PRUNE_PLAN_METADATA_NAME] != true {
+				t.Errorf("expected npminstall to be required with %s metadata, got %+v", npminstall.PRUNE_PLAN_METADATA_NAME, require)
+			}
+			if require, hasRequire := harness.PlanRequire(result, "nodejs"); !hasRequire || require.Metadata["launch"] != true {
+				t.Errorf("expected nodejs to be required at launch, got %+v", require)
+			}
+		})
+	}
+}
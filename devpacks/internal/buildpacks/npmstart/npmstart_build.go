@@ -6,6 +6,7 @@ import (
 	"os"
 
 	"github.com/buildpacks/libcnb"
+	"github.com/chuxel/devpacks/internal/buildpacks/nodejs"
 	"github.com/chuxel/devpacks/internal/common/devcontainer"
 )
 
@@ -22,12 +23,18 @@ func (builder NpmStartBuilder) Build(context libcnb.BuildContext) (libcnb.BuildR
 	log.Println("Number of plan entries:", len(context.Plan.Entries))
 	log.Println("Env:", os.Environ())
 
+	packageManager, err := nodejs.DetectPackageManager(context.Application.Path)
+	if err != nil {
+		return libcnb.NewBuildResult(), err
+	}
+	startCommand := packageManager.StartCommand()
+
 	result := libcnb.NewBuildResult()
 
 	result.Processes = append(result.Processes, libcnb.Process{
 		Type:      "web",
-		Command:   "npm",
-		Arguments: []string{"start"},
+		Command:   startCommand[0],
+		Arguments: startCommand[1:],
 		Default:   true,
 	})
 
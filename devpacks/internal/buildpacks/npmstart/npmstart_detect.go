@@ -2,6 +2,7 @@ package npmstart
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"os"
 	"path/filepath"
@@ -27,7 +28,12 @@ func (detector NpmStartDetector) Detect(context libcnb.DetectContext) (libcnb.De
 	if buildMode == "devcontainer" {
 		log.Println("Skipping. Detected devcontainer build mode.")
 		return libcnb.DetectResult{Pass: false}, nil
-	} else if detector.hasNpmStart(context.Application.Path) {
+	}
+	hasNpmStart, err := detector.hasNpmStart(context.Application.Path)
+	if err != nil {
+		return libcnb.DetectResult{Pass: false}, err
+	}
+	if hasNpmStart {
 		log.Println("Detection passed.")
 		return libcnb.DetectResult{
 			Pass: true,
@@ -47,7 +53,7 @@ func (detector NpmStartDetector) Detect(context libcnb.DetectContext) (libcnb.De
 	return libcnb.DetectResult{Pass: false}, nil
 }
 
-func (contrib NpmStartDetector) hasNpmStart(appPath string) bool {
+func (contrib NpmStartDetector) hasNpmStart(appPath string) (bool, error) {
 	packageJsonPath := filepath.Join(appPath, "package.json")
 	// Get engine value for nodejs if it exists in package.json
 	if _, err := os.Stat(packageJsonPath); err == nil {
@@ -57,14 +63,14 @@ func (contrib NpmStartDetector) hasNpmStart(appPath string) bool {
 		var packageJson PackageJson
 		content, err := os.ReadFile(packageJsonPath)
 		if err != nil {
-			log.Fatal("Failed to read package.json", err)
+			return false, fmt.Errorf("failed to read package.json: %w", err)
 		}
 		if err := json.Unmarshal(content, &packageJson); err != nil {
-			log.Fatal("Failed to parse package.json", err)
+			return false, fmt.Errorf("failed to parse package.json: %w", err)
 		}
 		_, hasKey := packageJson.Scripts["start"]
-		return hasKey
+		return hasKey, nil
 	}
 
-	return false
+	return false, nil
 }
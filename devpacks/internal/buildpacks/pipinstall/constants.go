@@ -1,3 +1,13 @@
 package pipinstall
 
 const BUILDPACK_NAME = "pipinstall"
+
+// Packages installed to a temp folder to export poetry.lock or Pipfile.lock to a requirements file
+var POETRY_PACKAGES = []string{"poetry", "poetry-plugin-export"}
+var PIPENV_PACKAGES = []string{"pipenv"}
+
+// Folder in the layer for the venv dependencies are installed into
+const VENV_FOLDER_NAME = "venv"
+
+// Cache-only layer for PIP_CACHE_DIR
+const CACHE_LAYER_NAME = "pip-cache"
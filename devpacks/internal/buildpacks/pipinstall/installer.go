@@ -0,0 +1,149 @@
+package pipinstall
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/chuxel/devpacks/internal/common/utils"
+)
+
+// Supported installers
+const PIP = "pip"
+const POETRY = "poetry"
+const PIPENV = "pipenv"
+
+type Installer struct {
+	Name string
+	// File in the application folder that determines installed dependencies
+	DependencyFile string
+}
+
+// Determines how to install dependencies by looking for poetry.lock, Pipfile.lock, requirements.txt
+// and then a PEP 621 pyproject.toml (one with a [project] table), in that order. Returns false if
+// none of them exist.
+func DetectInstaller(appPath string) (Installer, bool, error) {
+	for _, candidate := range []Installer{{POETRY, "poetry.lock"}, {PIPENV, "Pipfile.lock"}, {PIP, "requirements.txt"}} {
+		if _, err := os.Stat(filepath.Join(appPath, candidate.DependencyFile)); err == nil {
+			return candidate, true, nil
+		}
+	}
+	pyprojectPath := filepath.Join(appPath, "pyproject.toml")
+	if _, err := os.Stat(pyprojectPath); err != nil {
+		return Installer{}, false, nil
+	}
+	var pyproject map[string]interface{}
+	if _, err := toml.DecodeFile(pyprojectPath, &pyproject); err != nil {
+		return Installer{}, false, fmt.Errorf("failed to parse pyproject.toml: %w", err)
+	}
+	if _, hasProject := pyproject["project"]; hasProject {
+		return Installer{PIP, "pyproject.toml"}, true, nil
+	}
+	return Installer{}, false, nil
+}
+
+func (installer Installer) DependencyFilePath(appPath string) string {
+	return filepath.Join(appPath, installer.DependencyFile)
+}
+
+// Command used in postCreateCommand to install dependencies in a dev container. The venv is activated
+// using remoteEnv, so pip, poetry and pipenv all install into it.
+func (installer Installer) DevContainerInstallCommand() string {
+	switch installer.Name {
+	case POETRY:
+		return "pip install poetry && poetry install"
+	case PIPENV:
+		return "pip install pipenv && pipenv install --dev"
+	}
+	if installer.DependencyFile == "pyproject.toml" {
+		return "pip install -e ."
+	}
+	return "pip install -r requirements.txt"
+}
+
+// Installs dependencies into the venv. Poetry and Pipenv are only used to turn their lockfile into a
+// requirements file, so they are installed into a temp venv rather than the layer.
+func (installer Installer) Install(appPath string, venvPath string) error {
+	switch installer.Name {
+	case POETRY:
+		return installer.installExported(appPath, venvPath, POETRY_PACKAGES, POETRY, "export", "--format", "requirements.txt", "--only", "main", "--without-hashes")
+	case PIPENV:
+		return installer.installExported(appPath, venvPath, PIPENV_PACKAGES, PIPENV, "requirements")
+	}
+	if installer.DependencyFile == "pyproject.toml" {
+		return installer.installProjectDependencies(appPath, venvPath)
+	}
+	_, err := utils.ExecCmd(appPath, false, filepath.Join(venvPath, "bin", "pip"), "install", "-r", installer.DependencyFile)
+	return err
+}
+
+// Installs the dependencies listed in [project] rather than the project itself. The venv is reused
+// while pyproject.toml is unchanged, so installing the project would leave a stale copy of the
+// application code in it.
+func (installer Installer) installProjectDependencies(appPath string, venvPath string) error {
+	type PyprojectToml struct {
+		Project struct {
+			Dependencies []string
+		}
+	}
+	var pyproject PyprojectToml
+	if _, err := toml.DecodeFile(installer.DependencyFilePath(appPath), &pyproject); err != nil {
+		return fmt.Errorf("failed to parse pyproject.toml: %w", err)
+	}
+	if len(pyproject.Project.Dependencies) == 0 {
+		log.Println("No dependencies in pyproject.toml.")
+		return nil
+	}
+
+	requirementsFile, err := os.CreateTemp("", "requirements-*.txt")
+	if err != nil {
+		return fmt.Errorf("failed to create temp requirements file: %w", err)
+	}
+	requirementsPath := requirementsFile.Name()
+	requirementsFile.Close()
+	defer os.Remove(requirementsPath)
+	if err := utils.WriteFile(requirementsPath, []byte(strings.Join(pyproject.Project.Dependencies, "\n")+"\n")); err != nil {
+		return err
+	}
+	_, err = utils.ExecCmd(appPath, false, filepath.Join(venvPath, "bin", "pip"), "install", "-r", requirementsPath)
+	return err
+}
+
+// Installs the tool to a temp venv, has it print the lockfile as requirements, and installs those
+func (installer Installer) installExported(appPath string, venvPath string, toolPackages []string, toolCommand string, exportArgs ...string) error {
+	toolPath, err := os.MkdirTemp("", installer.Name+"-")
+	if err != nil {
+		return fmt.Errorf("failed to create temp folder for %s: %w", installer.Name, err)
+	}
+	defer os.RemoveAll(toolPath)
+
+	log.Printf("Installing %s to export %s.\n", installer.Name, installer.DependencyFile)
+	if err := CreateVenv(toolPath); err != nil {
+		return err
+	}
+	if _, err := utils.ExecCmd(appPath, false, filepath.Join(toolPath, "bin", "pip"), append([]string{"install"}, toolPackages...)...); err != nil {
+		return fmt.Errorf("failed to install %s: %w", installer.Name, err)
+	}
+	requirements, err := utils.ExecCmd(appPath, true, filepath.Join(toolPath, "bin", toolCommand), exportArgs...)
+	if err != nil {
+		return fmt.Errorf("failed to export %s: %w", installer.DependencyFile, err)
+	}
+	requirementsPath := filepath.Join(toolPath, "requirements.txt")
+	if err := utils.WriteFile(requirementsPath, requirements); err != nil {
+		return err
+	}
+
+	_, err = utils.ExecCmd(appPath, false, filepath.Join(venvPath, "bin", "pip"), "install", "-r", requirementsPath)
+	return err
+}
+
+// Creates a venv using the python3 from the cpython buildpack
+func CreateVenv(venvPath string) error {
+	if _, err := utils.ExecCmd("", false, "python3", "-m", "venv", venvPath); err != nil {
+		return fmt.Errorf("failed to create venv in %s: %w", venvPath, err)
+	}
+	return nil
+}
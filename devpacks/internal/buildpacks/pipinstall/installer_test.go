@@ -0,0 +1,76 @@
+package pipinstall
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDetectInstaller(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    []string
+		expected Installer
+		found    bool
+	}{
+		{"poetry", []string{"poetry.lock", "requirements.txt"}, Installer{POETRY, "poetry.lock"}, true},
+		{"pipenv", []string{"Pipfile.lock", "requirements.txt"}, Installer{PIPENV, "Pipfile.lock"}, true},
+		{"requirements.txt", []string{"requirements.txt", "pyproject.toml"}, Installer{PIP, "requirements.txt"}, true},
+		{"pyproject.toml", []string{"pyproject.toml"}, Installer{PIP, "pyproject.toml"}, true},
+		{"none", []string{"Pipfile"}, Installer{}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			appPath := t.TempDir()
+			for _, filename := range test.files {
+				content := ""
+				if filename == "pyproject.toml" {
+					content = "[project]\nname = \"app\"\n"
+				}
+				if err := os.WriteFile(filepath.Join(appPath, filename), []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			installer, found, err := DetectInstaller(appPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if installer != test.expected || found != test.found {
+				t.Errorf("expected %+v (%t), got %+v (%t)", test.expected, test.found, installer, found)
+			}
+		})
+	}
+}
+
+func TestInstallProjectDependencies(t *testing.T) {
+	appPath := t.TempDir()
+	pyproject := "[project]\nname = \"app\"\ndependencies = [\"flask>=2.0\", \"requests==2.31.0\"]\n"
+	if err := os.WriteFile(filepath.Join(appPath, "pyproject.toml"), []byte(pyproject), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Stand-in for the venv's pip that records its arguments and the requirements it was given
+	venvPath := t.TempDir()
+	outputPath := filepath.Join(t.TempDir(), "pip-output")
+	pipScript := "#!/bin/sh\necho \"$@\" > " + outputPath + "\ncat \"$3\" >> " + outputPath + "\n"
+	if err := os.MkdirAll(filepath.Join(venvPath, "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(venvPath, "bin", "pip"), []byte(pipScript), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := (Installer{PIP, "pyproject.toml"}).Install(appPath, venvPath); err != nil {
+		t.Fatal(err)
+	}
+	output, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(output), "install -r ") {
+		t.Errorf("expected pip install -r, got %s", output)
+	}
+	if expected := "flask>=2.0\nrequests==2.31.0\n"; !strings.HasSuffix(string(output), expected) {
+		t.Errorf("expected requirements %q, got %q", expected, output)
+	}
+}
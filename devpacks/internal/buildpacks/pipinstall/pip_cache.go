@@ -0,0 +1,40 @@
+package pipinstall
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/buildpacks/libcnb"
+)
+
+type PipCacheLayerContributor struct {
+	// Implements libcnb.LayerContributor
+
+	// Contribute(context libcnb.ContributeContext) (libcnb.Layer, error)
+	// Name() string
+}
+
+// Path to the pip wheel and http cache in the cache layer. It is not tied to the dependency file hash,
+// so changing one requirement only downloads the packages that changed.
+func PipCachePath(context libcnb.BuildContext) string {
+	return filepath.Join(context.Layers.Path, CACHE_LAYER_NAME)
+}
+
+// Implementation of libcnb.LayerContributor.Name
+func (contrib PipCacheLayerContributor) Name() string {
+	return CACHE_LAYER_NAME
+}
+
+// Implementation of libcnb.LayerContributor.Contribute
+func (contrib PipCacheLayerContributor) Contribute(layer libcnb.Layer) (libcnb.Layer, error) {
+	if err := os.MkdirAll(layer.Path, 0755); err != nil {
+		return layer, fmt.Errorf("unable to create layer folder %s: %w", layer.Path, err)
+	}
+	layer.LayerTypes = libcnb.LayerTypes{
+		Build:  false,
+		Cache:  true,
+		Launch: false,
+	}
+	return layer, nil
+}
@@ -2,9 +2,8 @@ package pipinstall
 
 import (
 	"bytes"
-	"crypto/sha256"
 	_ "embed"
-	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"log"
 	"os"
@@ -53,6 +52,15 @@ func (builder PipInstallBuilder) NewLayerContributor(buildMode string, layerType
 	return PipInstallLayerContributor{BuildMode: buildMode, LayerTypes: layerTypes, Context: context}
 }
 
+// Implementation of base.AdditionalLayersBuilder.AdditionalLayerContributors
+func (builder PipInstallBuilder) AdditionalLayerContributors(buildMode string, context libcnb.BuildContext) []libcnb.LayerContributor {
+	// Dependencies are only installed during the build in production mode
+	if buildMode == "devcontainer" {
+		return nil
+	}
+	return []libcnb.LayerContributor{PipCacheLayerContributor{}}
+}
+
 // Implementation of libcnb.LayerContributor.Name
 func (contrib PipInstallLayerContributor) Name() string {
 	return BUILDPACK_NAME
@@ -60,67 +68,86 @@ func (contrib PipInstallLayerContributor) Name() string {
 
 // Implementation of libcnb.LayerContributor.Contribute
 func (contrib PipInstallLayerContributor) Contribute(layer libcnb.Layer) (libcnb.Layer, error) {
-	// Just add a post create command in the devcontainer mode
+	installer, found, err := DetectInstaller(contrib.Context.Application.Path)
+	if err != nil {
+		return layer, err
+	}
+	if !found {
+		return layer, fmt.Errorf("no requirements.txt, poetry.lock, Pipfile.lock or pyproject.toml found. Be sure one is in your repository")
+	}
+	log.Printf("Installing dependencies from %s using %s.\n", installer.DependencyFile, installer.Name)
+
+	// Activate the venv for later buildpacks, at launch, and in the dev container
+	venvPath := filepath.Join(layer.Path, VENV_FOLDER_NAME)
+	layer.SharedEnvironment.Override("VIRTUAL_ENV", venvPath)
+	layer.SharedEnvironment.Prepend("PATH", string(filepath.ListSeparator), filepath.Join(venvPath, "bin"))
+	// Update devcontainer.json search path for finalize buildpack to pull in properties
+	layer.BuildEnvironment.Append(devcontainer.FINALIZE_JSON_SEARCH_PATH_ENV_VAR_NAME, string(filepath.ListSeparator), layer.Path)
+
+	// Just create an empty venv and add a post create command in the devcontainer mode
 	if devcontainer.ContainerImageBuildMode() == "devcontainer" {
 		log.Println("Detected devcontainer build mode - adding devcontainer.json contents.")
-		if err := os.MkdirAll(layer.Path, 0755); err != nil {
-			log.Fatal("Unable to create layer folder. ", err)
+		if err := os.RemoveAll(layer.Path); err != nil {
+			return layer, fmt.Errorf("failed to remove %s: %w", layer.Path, err)
+		}
+		if err := CreateVenv(venvPath); err != nil {
+			return layer, err
 		}
-		updatedBytes := bytes.ReplaceAll(devcontainerJsonBytes, []byte("{{layerDir}}"), []byte(layer.Path))
-		if err := utils.WriteFile(path.Join(layer.Path, "devcontainer.json"), updatedBytes); err != nil {
-			log.Fatal("Unable to write devcontainer.json. ", err)
+		if err := writeDevContainerJson(layer.Path, venvPath, installer.DevContainerInstallCommand()); err != nil {
+			return layer, err
 		}
-		// Update devcontainer.json search path for finalize buildpack to pull in properties
-		layer.BuildEnvironment.Append(devcontainer.FINALIZE_JSON_SEARCH_PATH_ENV_VAR_NAME, string(filepath.ListSeparator), layer.Path)
 		layer.LayerTypes = libcnb.LayerTypes{
 			Build:  true,
 			Cache:  false,
-			Launch: false,
+			Launch: true,
 		}
 		return layer, nil
 	}
 
-	// Determine sha256 of requirements.txt
-	requirementsTxtBytes, err := os.ReadFile(filepath.Join(contrib.Context.Application.Path, "requirements.txt"))
+	// Determine the cache key from the lockfile or requirements file and the Python version the venv uses
+	dependencyFilePath := installer.DependencyFilePath(contrib.Context.Application.Path)
+	if _, err := os.Stat(dependencyFilePath); err != nil {
+		return layer, fmt.Errorf("failed to load %s: %w", installer.DependencyFile, err)
+	}
+	cacheKey := base.CacheKey{
+		Files:       []string{dependencyFilePath},
+		EnvVarNames: []string{"PYTHON_VERSION", "PIP_INDEX_URL", "PIP_EXTRA_INDEX_URL"},
+		Values:      []string{installer.Name},
+	}
+	currentHash, err := cacheKey.Sum()
 	if err != nil {
-		log.Fatal("Failed to load requirements.txt. Be sure this file is in your repository. ", err)
-	}
-	hashGen := sha256.New()
-	currentHash := base64.StdEncoding.EncodeToString(hashGen.Sum(requirementsTxtBytes))
-	// Use sha256 to see if layer already exists and is the same so we can reuse
-	if layer.Metadata["sha256"] != nil {
-		if currentHash == fmt.Sprint(layer.Metadata["sha256"]) {
-			log.Println("Reusing cached layer.")
-			layer.LayerTypes = libcnb.LayerTypes{
-				Build:  true,
-				Cache:  true,
-				Launch: true,
-			}
-			return layer, nil
-		} else {
-			// Otherwise remove layer node_modules since we'll need to recreate
-			if err := os.RemoveAll(layer.Path); err != nil {
-				log.Fatal("Failed to remove ", layer.Path, ". ", err)
-			}
-			if err := os.MkdirAll(layer.Path, 0755); err != nil {
-				log.Fatal("Unable to create layer folder. ", err)
-			}
+		return layer, err
+	}
+	// Use the cache key to see if layer already exists and is the same so we can reuse
+	if base.LayerMatchesCacheKey(layer, currentHash) {
+		log.Println("Reusing cached layer.")
+		layer.LayerTypes = libcnb.LayerTypes{
+			Build:  true,
+			Cache:  true,
+			Launch: true,
 		}
+		return layer, nil
+	}
+	// Otherwise remove the layer since we'll need to recreate the venv
+	if err := os.RemoveAll(layer.Path); err != nil {
+		return layer, fmt.Errorf("failed to remove %s: %w", layer.Path, err)
 	}
 
-	// Execute pip install
-	cacheTmp := filepath.Join(layer.Path, "tmp-cache")
-	os.Setenv("PYTHONUSERBASE", layer.Path)
-	os.Setenv("PIP_CACHE_DIR", cacheTmp)
-	utils.ExecCmd(contrib.Context.Application.Path, false, "pip3", "install", "--user", "-r", "requirements.txt")
-	if err := os.RemoveAll(cacheTmp); err != nil {
-		log.Fatal("Unable to remove tmp folder: ", cacheTmp, err)
+	// Install dependencies into a venv in the layer
+	if err := CreateVenv(venvPath); err != nil {
+		return layer, err
+	}
+	os.Setenv("PIP_CACHE_DIR", PipCachePath(contrib.Context))
+	if err := installer.Install(contrib.Context.Application.Path, venvPath); err != nil {
+		return layer, err
+	}
+	if err := writeDevContainerJson(layer.Path, venvPath, ""); err != nil {
+		return layer, err
 	}
 
 	// Add layer metadata (e.g. hash)
-	layer.SharedEnvironment.Override("PYTHONUSERBASE", layer.Path)
 	layer.Metadata = map[string]interface{}{
-		"sha256": currentHash,
+		base.CACHE_KEY_METADATA_NAME: currentHash,
 	}
 	layer.LayerTypes = libcnb.LayerTypes{
 		Build:  true,
@@ -130,3 +157,22 @@ func (contrib PipInstallLayerContributor) Contribute(layer libcnb.Layer) (libcnb
 
 	return layer, nil
 }
+
+// Writes devcontainer.json with the venv as the default interpreter and, if set, a postCreateCommand
+func writeDevContainerJson(layerPath string, venvPath string, installCommand string) error {
+	var properties map[string]interface{}
+	if err := json.Unmarshal(bytes.ReplaceAll(devcontainerJsonBytes, []byte("{{venvDir}}"), []byte(venvPath)), &properties); err != nil {
+		return fmt.Errorf("failed to parse devcontainer.json asset: %w", err)
+	}
+	if installCommand != "" {
+		properties["postCreateCommand"] = installCommand
+	}
+	updatedBytes, err := json.MarshalIndent(properties, "", "    ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal devcontainer.json: %w", err)
+	}
+	if err := utils.WriteFile(path.Join(layerPath, "devcontainer.json"), updatedBytes); err != nil {
+		return fmt.Errorf("unable to write devcontainer.json: %w", err)
+	}
+	return nil
+}
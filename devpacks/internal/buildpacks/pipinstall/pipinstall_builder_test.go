@@ -0,0 +1,121 @@
+package pipinstall
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chuxel/devpacks/internal/common/harness"
+)
+
+// Creates a harness for an app with a requirements.txt and a fake python3 whose venvs have a pip that
+// records each run in pip.log
+func newPipInstallHarness(t *testing.T) (*harness.Harness, string) {
+	t.Helper()
+	h, err := harness.NewHarness(BUILDPACK_NAME, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(h.ApplicationPath, "requirements.txt"), []byte("Flask==2.0.2\n"), 0644); err != nil {
+		h.Cleanup()
+		t.Fatal(err)
+	}
+	logPath := filepath.Join(t.TempDir(), "pip.log")
+	python3 := `if [ "$1" = "-m" ] && [ "$2" = "venv" ]; then
+	mkdir -p "$3/bin"
+	printf '#!/bin/sh\necho "$@" >> ` + logPath + `\n' > "$3/bin/pip"
+	chmod +x "$3/bin/pip"
+fi`
+	if err := h.AddFakeCommand("python3", python3); err != nil {
+		h.Cleanup()
+		t.Fatal(err)
+	}
+	h.Setenv("PYTHON_VERSION", "3.11.7")
+	return h, logPath
+}
+
+func TestPipInstallBuilder(t *testing.T) {
+	h, logPath := newPipInstallHarness(t)
+	defer h.Cleanup()
+	plan, err := h.DefaultPlan()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := h.Build(PipInstallBuilder{}, plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	layer, hasLayer := output.Layer(BUILDPACK_NAME)
+	if !hasLayer {
+		t.Fatal("no pipinstall layer contributed")
+	}
+	if _, hasCacheLayer := output.Layer(CACHE_LAYER_NAME); !hasCacheLayer {
+		t.Error("expected a pip cache layer in production mode")
+	}
+	if !layer.LayerTypes.Build || !layer.LayerTypes.Cache || !layer.LayerTypes.Launch {
+		t.Errorf("unexpected layer types %+v", layer.LayerTypes)
+	}
+	venvPath := filepath.Join(layer.Path, VENV_FOLDER_NAME)
+	if virtualEnv, err := harness.ReadLayerEnvFile(layer, "env", "VIRTUAL_ENV.override"); err != nil || virtualEnv != venvPath {
+		t.Errorf("expected VIRTUAL_ENV %s, got %s (%v)", venvPath, virtualEnv, err)
+	}
+	devContainer, err := harness.LayerDevContainerJson(layer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings := devContainer.Properties["customizations"].(map[string]interface{})["vscode"].(map[string]interface{})["settings"].(map[string]interface{})
+	if interpreterPath := settings["python.defaultInterpreterPath"]; interpreterPath != filepath.Join(venvPath, "bin", "python") {
+		t.Errorf("expected the venv to be the default interpreter, got %v", interpreterPath)
+	}
+
+	// The same requirements.txt reuses the venv, and a changed one installs again
+	if _, err := h.Build(PipInstallBuilder{}, plan); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(h.ApplicationPath, "requirements.txt"), []byte("Flask==2.0.3\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.Build(PipInstallBuilder{}, plan); err != nil {
+		t.Fatal(err)
+	}
+	pipLog, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "install -r requirements.txt\ninstall -r requirements.txt\n"; string(pipLog) != expected {
+		t.Errorf("expected pip to run for the first and changed requirements.txt only, got %q", pipLog)
+	}
+}
+
+func TestPipInstallBuilderDevContainer(t *testing.T) {
+	h, logPath := newPipInstallHarness(t)
+	defer h.Cleanup()
+	h.BuildMode = "devcontainer"
+	plan, err := h.DefaultPlan()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := h.Build(PipInstallBuilder{}, plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, hasCacheLayer := output.Layer(CACHE_LAYER_NAME); hasCacheLayer {
+		t.Error("expected no pip cache layer in devcontainer mode")
+	}
+	layer, _ := output.Layer(BUILDPACK_NAME)
+	if _, err := os.Stat(filepath.Join(layer.Path, VENV_FOLDER_NAME, "bin", "pip")); err != nil {
+		t.Errorf("expected an empty venv in the layer: %v", err)
+	}
+	devContainer, err := harness.LayerDevContainerJson(layer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if command := devContainer.Properties["postCreateCommand"]; command != "pip install -r requirements.txt" {
+		t.Errorf("expected postCreateCommand to install requirements.txt, got %v", command)
+	}
+	if _, err := os.Stat(logPath); err == nil {
+		t.Error("expected pip not to run in devcontainer mode")
+	}
+}
@@ -2,8 +2,6 @@ package pipinstall
 
 import (
 	"log"
-	"os"
-	"path/filepath"
 
 	"github.com/buildpacks/libcnb"
 	"github.com/chuxel/devpacks/internal/buildpacks/base"
@@ -34,16 +32,21 @@ func (detector PipInstallDetector) AlwaysPass() bool {
 func (detector PipInstallDetector) DoDetect(context libcnb.DetectContext) (bool, []libcnb.BuildPlanRequire, map[string]interface{}, error) {
 	// This buildpack always requires cpython
 	reqs := []libcnb.BuildPlanRequire{{Name: cpython.BUILDPACK_NAME, Metadata: map[string]interface{}{
-		"build":  true,
-		"launch": true,
+		"build":                         true,
+		"launch":                        true,
+		cpython.VENV_PLAN_METADATA_NAME: true,
 	}}}
 
-	// Check for requirements.txt - can't pip install otherwise
-	if _, err := os.Stat(filepath.Join(context.Application.Path, "requirements.txt")); err == nil {
-		log.Println("Detection passed.")
+	// Check for a lockfile, requirements.txt or pyproject.toml - can't install otherwise
+	installer, found, err := DetectInstaller(context.Application.Path)
+	if err != nil {
+		return false, nil, nil, err
+	}
+	if found {
+		log.Printf("Detection passed. Using %s with %s.\n", installer.Name, installer.DependencyFile)
 		return true, reqs, nil, nil
 	}
 
-	log.Println("requirements.txt not detected.")
+	log.Println("No requirements.txt, poetry.lock, Pipfile.lock or pyproject.toml detected.")
 	return false, nil, nil, nil
 }
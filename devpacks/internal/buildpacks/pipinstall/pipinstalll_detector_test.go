@@ -0,0 +1,27 @@
+package pipinstall
+
+import (
+	"testing"
+
+	"github.com/chuxel/devpacks/internal/buildpacks/cpython"
+	"github.com/chuxel/devpacks/internal/common/harness"
+)
+
+func TestPipInstallDetector(t *testing.T) {
+	h, err := harness.NewHarness(BUILDPACK_NAME, "test/test-project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Cleanup()
+	result, err := h.Detect(PipInstallDetector{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Pass || !harness.PlanProvides(result, BUILDPACK_NAME) {
+		t.Errorf("expected detection to pass and provide %s", BUILDPACK_NAME)
+	}
+	// The venv replaces the cpython install as the interpreter in devcontainer.json
+	if require, hasRequire := harness.PlanRequire(result, cpython.BUILDPACK_NAME); !hasRequire || require.Metadata[cpython.VENV_PLAN_METADATA_NAME] != true {
+		t.Errorf("expected cpython to be required with a venv, got %+v", require)
+	}
+}
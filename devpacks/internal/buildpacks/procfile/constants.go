@@ -1,3 +1,7 @@
 package procfile
 
 const BUILDPACK_NAME = "procfile"
+
+const PROCFILE_NAME = "Procfile"
+const PROCESS_TYPE_ENV_VAR_NAME = "BP_PROCESS_TYPE"
+const DEFAULT_PROCESS_TYPE = "web"
@@ -0,0 +1,88 @@
+package procfile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Process types follow the same rules as Heroku (https://devcenter.heroku.com/articles/procfile)
+var procfileLineRegexp = regexp.MustCompile(`^([A-Za-z0-9_-]+):\s*(.*)$`)
+
+type ProcfileEntry struct {
+	Type    string
+	Command string
+}
+
+// Reads the Procfile in the application folder. Returns nil entries if there is no Procfile.
+func ReadProcfile(applicationFolder string) ([]ProcfileEntry, error) {
+	procfilePath := filepath.Join(applicationFolder, PROCFILE_NAME)
+	content, err := os.ReadFile(procfilePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", procfilePath, err)
+	}
+	entries, err := ParseProcfile(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", procfilePath, err)
+	}
+	return entries, nil
+}
+
+// Parses Procfile content into entries in the order they appear. Blank lines and lines starting
+// with "#" are skipped, and Windows line endings are supported.
+func ParseProcfile(content string) ([]ProcfileEntry, error) {
+	entries := make([]ProcfileEntry, 0)
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		match := procfileLineRegexp.FindStringSubmatch(line)
+		if match == nil {
+			return nil, fmt.Errorf("line %d is not in the form <process type>: <command>", i+1)
+		}
+		command := strings.TrimSpace(match[2])
+		if command == "" {
+			return nil, fmt.Errorf("line %d has no command for process type %s", i+1, match[1])
+		}
+		for _, entry := range entries {
+			if entry.Type == match[1] {
+				return nil, fmt.Errorf("line %d has duplicate process type %s", i+1, match[1])
+			}
+		}
+		entries = append(entries, ProcfileEntry{Type: match[1], Command: command})
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no process types found")
+	}
+	return entries, nil
+}
+
+// Returns the process type that should be the default: BP_PROCESS_TYPE if set, otherwise "web" if
+// present, otherwise the first entry.
+func DefaultProcessType(entries []ProcfileEntry) (string, error) {
+	processType := os.Getenv(PROCESS_TYPE_ENV_VAR_NAME)
+	if processType == "" {
+		if len(entries) > 0 {
+			processType = entries[0].Type
+		}
+		for _, entry := range entries {
+			if entry.Type == DEFAULT_PROCESS_TYPE {
+				processType = DEFAULT_PROCESS_TYPE
+			}
+		}
+		return processType, nil
+	}
+	for _, entry := range entries {
+		if entry.Type == processType {
+			return processType, nil
+		}
+	}
+	return "", fmt.Errorf("process type %s from %s not found in %s", processType, PROCESS_TYPE_ENV_VAR_NAME, PROCFILE_NAME)
+}
@@ -2,10 +2,9 @@ package procfile
 
 import (
 	_ "embed"
+	"fmt"
 	"log"
 	"os"
-	"path/filepath"
-	"strings"
 
 	"github.com/buildpacks/libcnb"
 	"github.com/chuxel/devpacks/internal/common/devcontainer"
@@ -24,31 +23,30 @@ func (builder ProcfileBuilder) Build(context libcnb.BuildContext) (libcnb.BuildR
 	log.Println("Number of plan entries:", len(context.Plan.Entries))
 	log.Println("Env:", os.Environ())
 
-	process := os.Getenv("BP_PROCESS_TYPE")
-	if process == "" {
-		process = "web"
-	}
-
-	content, err := os.ReadFile(filepath.Join(context.Application.Path, "Procfile"))
+	entries, err := ReadProcfile(context.Application.Path)
 	if err != nil {
-		log.Fatal("Failed to read Procfile:", err)
+		return libcnb.NewBuildResult(), err
 	}
-	command := ""
-	lines := strings.Split(string(content), "\n")
-	for _, line := range lines {
-		if strings.HasPrefix(line, process+":") {
-			command = strings.TrimSpace(strings.TrimPrefix(line, process+":"))
-		}
+	if entries == nil {
+		return libcnb.NewBuildResult(), fmt.Errorf("no %s found in %s", PROCFILE_NAME, context.Application.Path)
+	}
+	defaultProcessType, err := DefaultProcessType(entries)
+	if err != nil {
+		return libcnb.NewBuildResult(), err
 	}
 
 	// TODO: Apply environment variables to command if web (see https://devcenter.heroku.com/articles/procfile#the-web-process-type)
 	result := libcnb.NewBuildResult()
-	result.Processes = append(result.Processes, libcnb.Process{
-		Type:      "web",
-		Command:   "bash",
-		Arguments: []string{"-c", command},
-		Default:   true,
-	})
+	for _, entry := range entries {
+		log.Printf("Adding process type %s: %s\n", entry.Type, entry.Command)
+		result.Processes = append(result.Processes, libcnb.Process{
+			Type:      entry.Type,
+			Command:   "bash",
+			Arguments: []string{"-c", entry.Command},
+			Default:   entry.Type == defaultProcessType,
+		})
+	}
+	log.Println("Default process type:", defaultProcessType)
 
 	return result, nil
 }
@@ -0,0 +1,42 @@
+package procfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chuxel/devpacks/internal/common/harness"
+)
+
+func TestProcfileBuilder(t *testing.T) {
+	h, err := harness.NewHarness(BUILDPACK_NAME, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Cleanup()
+	if err := os.WriteFile(filepath.Join(h.ApplicationPath, PROCFILE_NAME), []byte("worker: python worker.py\r\nweb: flask app.py\r\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	h.Setenv(PROCESS_TYPE_ENV_VAR_NAME, "")
+	plan, err := h.DefaultPlan()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := h.Build(ProcfileBuilder{}, plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	processes := output.Result.Processes
+	if len(processes) != 2 {
+		t.Fatalf("expected a process for each Procfile entry, got %+v", processes)
+	}
+	for i, expectedType := range []string{"worker", "web"} {
+		if processes[i].Type != expectedType || processes[i].Command != "bash" || processes[i].Default != (expectedType == "web") {
+			t.Errorf("unexpected process %+v", processes[i])
+		}
+	}
+	if arguments := processes[1].Arguments; len(arguments) != 2 || arguments[1] != "flask app.py" {
+		t.Errorf("expected the command to be run with bash -c, got %v", arguments)
+	}
+}
@@ -3,7 +3,6 @@ package procfile
 import (
 	"log"
 	"os"
-	"path/filepath"
 
 	"github.com/buildpacks/libcnb"
 	"github.com/chuxel/devpacks/internal/common/devcontainer"
@@ -25,11 +24,14 @@ func (detector ProcfileDetector) Detect(context libcnb.DetectContext) (libcnb.De
 		log.Println("Skipping. Detected devcontainer build mode.")
 		return libcnb.DetectResult{Pass: false}, nil
 	}
-	if _, err := os.Stat(filepath.Join(context.Application.Path, "Procfile")); err != nil {
+	entries, err := ReadProcfile(context.Application.Path)
+	if err != nil {
+		return libcnb.DetectResult{Pass: false}, err
+	}
+	if entries == nil {
 		log.Println("Skipping. Did not find Procfile.")
 		return libcnb.DetectResult{Pass: false}, nil
 	}
-	// TODO Allow other process types
 	log.Println("Detection passed.")
 	return libcnb.DetectResult{
 		Pass: true,
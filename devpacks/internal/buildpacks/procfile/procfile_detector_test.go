@@ -0,0 +1,59 @@
+package procfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chuxel/devpacks/internal/common/harness"
+)
+
+func TestProcfileDetector(t *testing.T) {
+	h, err := harness.NewHarness(BUILDPACK_NAME, harness.TEST_PROJECT_RELATIVE_PATH)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Cleanup()
+	result, err := h.Detect(ProcfileDetector{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Pass || !harness.PlanProvides(result, BUILDPACK_NAME) {
+		t.Errorf("expected detection to pass and provide %s", BUILDPACK_NAME)
+	}
+	if _, hasRequire := harness.PlanRequire(result, BUILDPACK_NAME); !hasRequire {
+		t.Errorf("expected plan to require %s", BUILDPACK_NAME)
+	}
+
+	// Not used in devcontainer mode
+	h.BuildMode = "devcontainer"
+	if result, err = h.Detect(ProcfileDetector{}); err != nil || result.Pass {
+		t.Errorf("expected detection not to pass in devcontainer mode, got %v (%v)", result.Pass, err)
+	}
+}
+
+func TestProcfileDetectorWithoutProcfile(t *testing.T) {
+	h, err := harness.NewHarness(BUILDPACK_NAME, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Cleanup()
+	if result, err := h.Detect(ProcfileDetector{}); err != nil || result.Pass {
+		t.Errorf("expected detection not to pass without a Procfile, got %v (%v)", result.Pass, err)
+	}
+}
+
+func TestProcfileDetectorMalformed(t *testing.T) {
+	h, err := harness.NewHarness(BUILDPACK_NAME, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Cleanup()
+	if err := os.WriteFile(filepath.Join(h.ApplicationPath, PROCFILE_NAME), []byte("web: flask app.py\nworker python worker.py\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Fails with an error rather than passing over the buildpack so the problem is reported
+	if result, err := h.Detect(ProcfileDetector{}); err == nil || result.Pass {
+		t.Errorf("expected detection to fail with an error for a malformed Procfile, got %v (%v)", result.Pass, err)
+	}
+}
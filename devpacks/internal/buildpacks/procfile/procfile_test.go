@@ -0,0 +1,113 @@
+package procfile
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseProcfile(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		expected    []ProcfileEntry
+		expectError bool
+	}{
+		{
+			name:     "single entry",
+			content:  "web: flask app.py",
+			expected: []ProcfileEntry{{Type: "web", Command: "flask app.py"}},
+		},
+		{
+			name:    "multiple entries in order",
+			content: "worker: python worker.py\nweb: gunicorn app:app --bind 0.0.0.0:$PORT\nrelease_1: ./migrate.sh\n",
+			expected: []ProcfileEntry{
+				{Type: "worker", Command: "python worker.py"},
+				{Type: "web", Command: "gunicorn app:app --bind 0.0.0.0:$PORT"},
+				{Type: "release_1", Command: "./migrate.sh"},
+			},
+		},
+		{
+			name:     "comments and blank lines",
+			content:  "# Processes\n\n  \nweb: npm start\n   # indented comment\n\n",
+			expected: []ProcfileEntry{{Type: "web", Command: "npm start"}},
+		},
+		{
+			name:    "CRLF line endings",
+			content: "web: npm start\r\nworker: node worker.js\r\n",
+			expected: []ProcfileEntry{
+				{Type: "web", Command: "npm start"},
+				{Type: "worker", Command: "node worker.js"},
+			},
+		},
+		{
+			name:     "no space after colon and extra whitespace",
+			content:  "  web:npm start   \n",
+			expected: []ProcfileEntry{{Type: "web", Command: "npm start"}},
+		},
+		{
+			name:     "colons in the command",
+			content:  "web: echo a:b:c",
+			expected: []ProcfileEntry{{Type: "web", Command: "echo a:b:c"}},
+		},
+		{name: "missing colon", content: "web npm start", expectError: true},
+		{name: "invalid process type", content: "web app: npm start", expectError: true},
+		{name: "no command", content: "web:", expectError: true},
+		{name: "whitespace command", content: "web:   \nworker: node worker.js", expectError: true},
+		{name: "duplicate process type", content: "web: npm start\nweb: node server.js", expectError: true},
+		{name: "only comments", content: "# nothing here\n\n", expectError: true},
+		{name: "empty", content: "", expectError: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			entries, err := ParseProcfile(test.content)
+			if test.expectError {
+				if err == nil {
+					t.Errorf("expected an error, got %+v", entries)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(entries, test.expected) {
+				t.Errorf("expected %+v, got %+v", test.expected, entries)
+			}
+		})
+	}
+}
+
+func TestDefaultProcessType(t *testing.T) {
+	withWeb := []ProcfileEntry{{Type: "worker", Command: "python worker.py"}, {Type: "web", Command: "flask app.py"}}
+	withoutWeb := []ProcfileEntry{{Type: "worker", Command: "python worker.py"}, {Type: "clock", Command: "python clock.py"}}
+	tests := []struct {
+		name        string
+		processType string
+		entries     []ProcfileEntry
+		expected    string
+		expectError bool
+	}{
+		{name: "web", entries: withWeb, expected: "web"},
+		{name: "first entry", entries: withoutWeb, expected: "worker"},
+		{name: "env var", processType: "worker", entries: withWeb, expected: "worker"},
+		{name: "env var without web", processType: "clock", entries: withoutWeb, expected: "clock"},
+		{name: "env var not in Procfile", processType: "release", entries: withWeb, expectError: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv(PROCESS_TYPE_ENV_VAR_NAME, test.processType)
+			processType, err := DefaultProcessType(test.entries)
+			if test.expectError {
+				if err == nil {
+					t.Errorf("expected an error, got %s", processType)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if processType != test.expected {
+				t.Errorf("expected %s, got %s", test.expected, processType)
+			}
+		})
+	}
+}
@@ -2,3 +2,6 @@ package pythonutils
 
 const BUILDPACK_NAME = "pythonutils"
 const DEFAULT_PYTHON_UTILS = "pylint==2.13.9 flake8==4.0.1 autopep8==1.6.0 black==22.3.0 yapf==0.32.0 mypy==0.950 pydocstyle==6.1.1 pycodestyle==2.8.0 bandit==1.7.4 pipenv==2022.5.2 virtualenv==20.14.1"
+
+// Section of .devpacks/tools.toml with tools for this buildpack
+const TOOLS_FILE_ECOSYSTEM = "python"
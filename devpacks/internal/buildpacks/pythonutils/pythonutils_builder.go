@@ -2,9 +2,7 @@ package pythonutils
 
 import (
 	"bytes"
-	"crypto/sha256"
 	_ "embed"
-	"encoding/base64"
 	"fmt"
 	"log"
 	"os"
@@ -15,6 +13,7 @@ import (
 	"github.com/buildpacks/libcnb"
 	"github.com/chuxel/devpacks/internal/buildpacks/base"
 	"github.com/chuxel/devpacks/internal/common/devcontainer"
+	"github.com/chuxel/devpacks/internal/common/tools"
 	"github.com/chuxel/devpacks/internal/common/utils"
 )
 
@@ -67,48 +66,41 @@ func (contrib PythonUtilsLayerContributor) Contribute(layer libcnb.Layer) (libcn
 	} else {
 		pkgList = strings.Split(DEFAULT_PYTHON_UTILS, " ")
 	}
-
-	// Generate and verify checksum
-	hashCheckBytes := []byte(fmt.Sprint(os.Getenv("PYTHON_VERSION"), " ", pkgList))
-	hashGen := sha256.New()
-	currentHash := base64.StdEncoding.EncodeToString(hashGen.Sum(hashCheckBytes))
-	if layer.Metadata["sha256"] != nil {
-		if currentHash == fmt.Sprint(layer.Metadata["sha256"]) {
-			layer.LayerTypes = contrib.LayerTypes
-			return layer, nil
-		}
+	// Add or remove tools using .devpacks/tools.toml
+	toolsFile, err := tools.LoadToolsFile(contrib.Context.Application.Path)
+	if err != nil {
+		return layer, err
 	}
-	// Clean out layer folder in the event we invalidated the cache
-	if err := os.RemoveAll(layer.Path); err != nil {
-		log.Fatal("Unable to remove ", layer.Path, ". ", err)
+	pythonTools := toolsFile.ResolveTools(TOOLS_FILE_ECOSYSTEM, pkgList, splitPackageSpec)
+
+	// pipx venvs use the Python from the cpython buildpack, so start over if its version changed
+	pythonVersion := os.Getenv("PYTHON_VERSION")
+	if layer.Metadata["python_version"] != nil && fmt.Sprint(layer.Metadata["python_version"]) != pythonVersion {
+		log.Println("Python version changed, reinstalling all tools.")
+		layer.Metadata = map[string]interface{}{}
+		if err := os.RemoveAll(layer.Path); err != nil {
+			return layer, fmt.Errorf("unable to remove %s: %w", layer.Path, err)
+		}
 	}
 
 	// Make sure target path exists
 	if err := os.MkdirAll(layer.Path, 0755); err != nil {
-		log.Fatal("Unable to create layer folder: ", layer.Path, err)
+		return layer, fmt.Errorf("unable to create layer folder %s: %w", layer.Path, err)
 	}
 	// Write devcontainer.json in all cases since its quick and we can avoid doing a checksum when caching
-	updatedBytes := bytes.ReplaceAll(devcontainerJsonBytes, []byte("{{layerDir}}"), []byte(layer.Path))
+	updatedBytes, err := tools.AddToolSettings(devcontainerJsonBytes, pythonTools)
+	if err != nil {
+		return layer, err
+	}
+	updatedBytes = bytes.ReplaceAll(updatedBytes, []byte("{{layerDir}}"), []byte(layer.Path))
 	if err := utils.WriteFile(path.Join(layer.Path, "devcontainer.json"), updatedBytes); err != nil {
-		log.Fatal("Failed to write devcontainer.json: ", err)
+		return layer, fmt.Errorf("failed to write devcontainer.json: %w", err)
 	}
 
-	// Use pip to install pipx in a temporary spot we'll remove later
-	pyTmp := filepath.Join(layer.Path, "tmp")
-	os.Setenv("PYTHONUSERBASE", pyTmp)
-	os.Setenv("PIP_CACHE_DIR", filepath.Join(pyTmp, "cache"))
-	os.Setenv("PIPX_HOME", filepath.Join(layer.Path, "pipx"))
-	os.Setenv("PIPX_BIN_DIR", filepath.Join(layer.Path, "bin"))
-	pipx := filepath.Join(pyTmp, "bin", "pipx")
-	utils.ExecCmd(layer.Path, false, "pip3", "install", "--disable-pip-version-check", "--no-cache-dir", "--user", "pipx")
-	// Install packages using pipx
-	utils.ExecCmd(layer.Path, false, pipx, "install", "--pip-args=--no-cache-dir", "pipx")
-	for _, pkg := range pkgList {
-		utils.ExecCmd(layer.Path, false, pipx, "install", "--pip-args=--no-cache-dir", pkg)
-	}
-	// Clear out temp folder
-	if err := os.RemoveAll(pyTmp); err != nil {
-		log.Fatal("Unable to remove tmp folder: ", pyTmp, err)
+	// Each package gets its own pipx venv and is only reinstalled if its pinned version changed
+	pinnedTools, err := installPythonTools(layer, pythonTools)
+	if err != nil {
+		return layer, err
 	}
 	// Update devcontainer.json search path for finalize buildpack to pull in properties
 	layer.BuildEnvironment.Append(devcontainer.FINALIZE_JSON_SEARCH_PATH_ENV_VAR_NAME, string(filepath.ListSeparator), layer.Path)
@@ -116,7 +108,8 @@ func (contrib PythonUtilsLayerContributor) Contribute(layer libcnb.Layer) (libcn
 	// Set the layer types based on what was set for the contributor
 	layer.LayerTypes = contrib.LayerTypes
 	layer.Metadata = map[string]interface{}{
-		"sha256": currentHash,
+		"python_version":                pythonVersion,
+		base.PINNED_TOOLS_METADATA_NAME: base.PinnedToolsMetadata(pinnedTools),
 	}
 
 	return layer, nil
@@ -11,6 +11,7 @@ import (
 	"github.com/chuxel/devpacks/internal/buildpacks/base"
 	"github.com/chuxel/devpacks/internal/buildpacks/cpython"
 	"github.com/chuxel/devpacks/internal/common/devcontainer"
+	"github.com/chuxel/devpacks/internal/common/tools"
 )
 
 type PythonUtilsDetector struct {
@@ -51,6 +52,16 @@ func (detector PythonUtilsDetector) DoDetect(context libcnb.DetectContext) (bool
 		return true, reqs, nil, nil
 	}
 
+	// Or a tools section in .devpacks/tools.toml
+	toolsFile, err := tools.LoadToolsFile(context.Application.Path)
+	if err != nil {
+		return false, nil, nil, err
+	}
+	if toolsFile.HasEcosystem(TOOLS_FILE_ECOSYSTEM) {
+		log.Println("Detection passed.")
+		return true, reqs, nil, nil
+	}
+
 	// Look for requirements.txt in the root - TODO: Others?
 	filesToCheck := []string{"requirements.txt"}
 	for _, file := range filesToCheck {
@@ -64,9 +75,9 @@ func (detector PythonUtilsDetector) DoDetect(context libcnb.DetectContext) (bool
 	if _, err := os.Stat(filepath.Join(context.Application.Path, "runtime.txt")); err == nil {
 		contents, err := os.ReadFile(filepath.Join(context.Application.Path, "runtime.txt"))
 		if err != nil {
-			log.Fatal("Failed to read runtime.txt. ", err)
+			return false, nil, nil, fmt.Errorf("failed to read runtime.txt: %w", err)
 		}
-		if strings.Contains(fmt.Sprint(contents), "python-") {
+		if strings.Contains(string(contents), "python-") {
 			log.Println("Detection passed.")
 			return true, reqs, nil, nil
 		}
@@ -0,0 +1,65 @@
+package pythonutils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chuxel/devpacks/internal/common/harness"
+)
+
+func TestPythonUtilsDetector(t *testing.T) {
+	tests := []struct {
+		name           string
+		buildMode      string
+		pythonUtils    string
+		files          map[string]string
+		expectedToPass bool
+	}{
+		{name: "requirements.txt", buildMode: "devcontainer", files: map[string]string{"requirements.txt": "flask\n"}, expectedToPass: true},
+		{name: "production", buildMode: "production", files: map[string]string{"requirements.txt": "flask\n"}, expectedToPass: false},
+		{name: "runtime.txt", buildMode: "devcontainer", files: map[string]string{"runtime.txt": "python-3.11.4\n"}, expectedToPass: true},
+		{name: "runtime.txt for another language", buildMode: "devcontainer", files: map[string]string{"runtime.txt": "java-17\n"}, expectedToPass: false},
+		{name: "BP_PYTHON_UTILS", buildMode: "devcontainer", pythonUtils: "ruff", expectedToPass: true},
+		{name: "tools.toml python section", buildMode: "devcontainer", files: map[string]string{".devpacks/tools.toml": "[python.tools.ruff]\n"}, expectedToPass: true},
+		{name: "tools.toml other section", buildMode: "devcontainer", files: map[string]string{".devpacks/tools.toml": "[node.tools.typescript]\n"}, expectedToPass: false},
+		{name: "no Python", buildMode: "devcontainer", files: map[string]string{"package.json": `{}`}, expectedToPass: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h, err := harness.NewHarness(BUILDPACK_NAME, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer h.Cleanup()
+			h.BuildMode = test.buildMode
+			h.Setenv("BP_PYTHON_UTILS", test.pythonUtils)
+			h.Setenv("BP_CPYTHON_VERSION", "")
+			for filename, content := range test.files {
+				if err := os.MkdirAll(filepath.Dir(filepath.Join(h.ApplicationPath, filename)), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(h.ApplicationPath, filename), []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			result, err := h.Detect(PythonUtilsDetector{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Pass != test.expectedToPass {
+				t.Fatalf("expected detection to pass to be %v", test.expectedToPass)
+			}
+			if !result.Pass {
+				return
+			}
+			if !harness.PlanProvides(result, BUILDPACK_NAME) {
+				t.Errorf("expected plan to provide %s", BUILDPACK_NAME)
+			}
+			if require, hasRequire := harness.PlanRequire(result, "cpython"); !hasRequire || require.Metadata["build"] != true {
+				t.Errorf("expected cpython to be required during the build, got %+v", require)
+			}
+		})
+	}
+}
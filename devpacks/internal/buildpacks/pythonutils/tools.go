@@ -0,0 +1,168 @@
+package pythonutils
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/buildpacks/libcnb"
+	"github.com/chuxel/devpacks/internal/buildpacks/base"
+	"github.com/chuxel/devpacks/internal/common/tools"
+	"github.com/chuxel/devpacks/internal/common/utils"
+)
+
+var packageNameRegexp = regexp.MustCompile(`^[A-Za-z0-9._-]+`)
+var pipxNormalizeRegexp = regexp.MustCompile(`[-_.]+`)
+
+// Installs each package (e.g. pylint==2.13.9 or black) into its own pipx venv in the layer, reusing
+// venvs from a previous build when possible. Packages without an exact version are resolved to one
+// the first time they are installed, which is then pinned in layer metadata.
+func installPythonTools(layer libcnb.Layer, pythonTools []tools.Tool) ([]base.PinnedTool, error) {
+	previousTools := base.PinnedToolsFromLayer(layer)
+	os.Setenv("PIPX_HOME", filepath.Join(layer.Path, "pipx"))
+	os.Setenv("PIPX_BIN_DIR", filepath.Join(layer.Path, "bin"))
+	pipx := ""
+	// Temp folder a bootstrap copy of pipx was installed into, if one was needed
+	pyTmp := ""
+	defer func() {
+		if pyTmp != "" {
+			os.RemoveAll(pyTmp)
+		}
+	}()
+
+	// pipx itself is always installed so it can be used in the dev container
+	pinnedTools := []base.PinnedTool{}
+	listed := map[string]bool{}
+	for _, tool := range append([]tools.Tool{{Name: "pipx"}}, pythonTools...) {
+		name := tool.Name
+		if name == "" || listed[name] {
+			continue
+		}
+		listed[name] = true
+		requested := tool.Version
+		if requested == "" {
+			requested = "latest"
+		} else if unicode.IsDigit(rune(requested[0])) {
+			// Versions from .devpacks/tools.toml can be written without "=="
+			requested = "==" + requested
+		}
+		version := requested
+		previousTool, hasPrevious := previousTools[name]
+		if hasPrevious && previousTool.Requested == requested {
+			version = previousTool.Version
+		}
+		cacheKey, err := pythonToolCacheKey(name, version)
+		if err != nil {
+			return nil, err
+		}
+		if pipxVenvExists(layer.Path, name) && hasPrevious && previousTool.CacheKey == cacheKey {
+			log.Printf("Reusing %s %s.\n", name, version)
+			pinnedTools = append(pinnedTools, previousTool)
+			continue
+		}
+
+		if pipx == "" {
+			if pipx, pyTmp, err = bootstrapPipx(layer.Path); err != nil {
+				return nil, err
+			}
+		}
+		installSpec := name + requested
+		if version != requested {
+			installSpec = name + "==" + version
+		} else if requested == "latest" {
+			installSpec = name
+		}
+		if _, err := utils.ExecCmd(layer.Path, false, pipx, "install", "--force", "--pip-args=--no-cache-dir", installSpec); err != nil {
+			return nil, err
+		}
+		resolvedVersion, err := installedPythonToolVersion(pipx, name)
+		if err != nil {
+			return nil, err
+		}
+		if resolvedVersion != version {
+			log.Printf("Pinned %s %s to %s.\n", name, version, resolvedVersion)
+		}
+		if cacheKey, err = pythonToolCacheKey(name, resolvedVersion); err != nil {
+			return nil, err
+		}
+		pinnedTools = append(pinnedTools, base.PinnedTool{Name: name, Requested: requested, Version: resolvedVersion, CacheKey: cacheKey})
+	}
+
+	// Remove tools that are no longer in the list
+	for name := range previousTools {
+		if listed[name] {
+			continue
+		}
+		if pipx == "" {
+			var err error
+			if pipx, pyTmp, err = bootstrapPipx(layer.Path); err != nil {
+				return nil, err
+			}
+		}
+		if _, err := utils.ExecCmd(layer.Path, false, pipx, "uninstall", name); err != nil {
+			return nil, err
+		}
+	}
+	return pinnedTools, nil
+}
+
+// Splits a package spec like "pylint==2.13.9" into its name and version specifier
+func splitPackageSpec(pkg string) (string, string) {
+	name := packageNameRegexp.FindString(pkg)
+	return name, strings.TrimSpace(pkg[len(name):])
+}
+
+// Checks that the package's pipx venv is still in the layer. Depending on the version, pipx names it
+// after the package as written or its normalized name (e.g. "Flask_Login" is "flask-login").
+func pipxVenvExists(layerPath string, name string) bool {
+	venvsPath := filepath.Join(layerPath, "pipx", "venvs")
+	normalizedName := strings.ToLower(pipxNormalizeRegexp.ReplaceAllString(name, "-"))
+	for _, folderName := range []string{name, normalizedName} {
+		if _, err := os.Stat(filepath.Join(venvsPath, folderName)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func pythonToolCacheKey(name string, version string) (string, error) {
+	cacheKey := base.CacheKey{EnvVarNames: []string{"PYTHON_VERSION"}, Values: []string{name, version}}
+	return cacheKey.Sum()
+}
+
+// Returns the pipx installed in the layer by a previous build, or installs pipx into a temp folder
+// and returns it along with the temp folder to remove later
+func bootstrapPipx(layerPath string) (string, string, error) {
+	layerPipx := filepath.Join(layerPath, "bin", "pipx")
+	if _, err := os.Stat(layerPipx); err == nil {
+		return layerPipx, "", nil
+	}
+	pyTmp, err := os.MkdirTemp("", "pipx-")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create temp folder: %w", err)
+	}
+	os.Setenv("PYTHONUSERBASE", pyTmp)
+	os.Setenv("PIP_CACHE_DIR", filepath.Join(pyTmp, "cache"))
+	if _, err := utils.ExecCmd(layerPath, false, "pip3", "install", "--disable-pip-version-check", "--no-cache-dir", "--user", "pipx"); err != nil {
+		return "", pyTmp, err
+	}
+	return filepath.Join(pyTmp, "bin", "pipx"), pyTmp, nil
+}
+
+// Uses pip in the package's pipx venv to find the installed version
+func installedPythonToolVersion(pipx string, name string) (string, error) {
+	output, err := utils.ExecCmd("", true, pipx, "runpip", name, "show", name)
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(string(output), "\n") {
+		if strings.HasPrefix(line, "Version:") {
+			return strings.TrimSpace(strings.TrimPrefix(line, "Version:")), nil
+		}
+	}
+	return "", fmt.Errorf("unable to determine the version of %s", name)
+}
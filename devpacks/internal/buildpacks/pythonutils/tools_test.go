@@ -0,0 +1,41 @@
+package pythonutils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPipxVenvExists(t *testing.T) {
+	layerPath := t.TempDir()
+	for _, folderName := range []string{"pylint", "flask-login"} {
+		if err := os.MkdirAll(filepath.Join(layerPath, "pipx", "venvs", folderName), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := map[string]bool{
+		"pylint":      true,
+		"flask-login": true,
+		"Flask_Login": true,
+		"black":       false,
+	}
+	for name, expected := range tests {
+		if exists := pipxVenvExists(layerPath, name); exists != expected {
+			t.Errorf("expected venv for %s to exist: %t, got %t", name, expected, exists)
+		}
+	}
+}
+
+func TestSplitPackageSpec(t *testing.T) {
+	tests := map[string][2]string{
+		"pylint==2.13.9":   {"pylint", "==2.13.9"},
+		"black":            {"black", ""},
+		"mypy >=0.950":     {"mypy", ">=0.950"},
+		"flask_login~=0.6": {"flask_login", "~=0.6"},
+	}
+	for spec, expected := range tests {
+		if name, version := splitPackageSpec(spec); name != expected[0] || version != expected[1] {
+			t.Errorf("expected %s to split into %v, got %s and %s", spec, expected, name, version)
+		}
+	}
+}
@@ -2,7 +2,7 @@ package actions
 
 import (
 	"encoding/json"
-	"log"
+	"fmt"
 	"os"
 	"runtime"
 
@@ -29,71 +29,92 @@ type VersionManifest struct {
 	Entries []VersionManifestEntry
 }
 
-func (manifest VersionManifest) Load(manifestPath string) {
+func (manifest *VersionManifest) Load(manifestPath string) error {
 	content, err := os.ReadFile(manifestPath)
 	if err != nil {
-		log.Fatal("Failed to read manifest. ", err)
+		return fmt.Errorf("failed to read manifest: %w", err)
 	}
 	if err := json.Unmarshal(content, &manifest.Entries); err != nil {
-		log.Fatal("Failed to unmarshal manifest contents. ", err)
+		return fmt.Errorf("failed to unmarshal manifest contents: %w", err)
 	}
+	return nil
 }
 
-func (manifest VersionManifest) FindEntry(version string) VersionManifestEntry {
+func (manifest VersionManifest) FindEntry(version string) (VersionManifestEntry, error) {
 	for _, entry := range manifest.Entries {
 		if entry.Version == version {
-			return entry
+			return entry, nil
 		}
 	}
-	log.Fatal("Unable to find entry for version ", version)
-	return VersionManifestEntry{}
+	return VersionManifestEntry{}, fmt.Errorf("unable to find entry for version %s", version)
 }
 
-func (manifest VersionManifest) FindVersion(semverRange string, stableOnly bool) string {
-	versions := make([]semver.Version, len(manifest.Entries))
+func (manifest VersionManifest) FindVersion(semverRange string, stableOnly bool) (string, error) {
+	if semverRange == "latest" {
+		return manifest.FindVersionInRange(func(semver.Version) bool { return true }, stableOnly)
+	}
+	expectedRange, err := utils.NewSemverRange(semverRange)
+	if err != nil {
+		return "", err
+	}
+	version, err := manifest.FindVersionInRange(expectedRange, stableOnly)
+	if err != nil {
+		return "", fmt.Errorf("unable to match version %s: %w", semverRange, err)
+	}
+	return version, nil
+}
+
+// Returns the manifest's version string for the latest entry in the range (e.g. from versions.NewPep440Range)
+func (manifest VersionManifest) FindVersionInRange(expectedRange semver.Range, stableOnly bool) (string, error) {
+	versions := make([]semver.Version, 0, len(manifest.Entries))
+	manifestVersions := make(map[string]string, len(manifest.Entries))
 	for _, entry := range manifest.Entries {
 		if (entry.Stable && stableOnly) || !stableOnly {
 			version, err := semver.ParseTolerant(entry.Version)
 			if err != nil {
-				log.Fatal(err)
+				return "", fmt.Errorf("invalid version %s in manifest: %w", entry.Version, err)
 			}
 			versions = append(versions, version)
+			manifestVersions[version.String()] = entry.Version
 		}
 	}
+	if len(versions) == 0 {
+		return "", fmt.Errorf("no versions found in manifest")
+	}
 	semver.Sort(versions)
 
-	if semverRange != "latest" {
-		expectedRange := utils.NewSemverRange(semverRange)
-		// Sorted in ascending order, so run through in reverse order to get the latest matching
-		for i := len(versions) - 1; i >= 0; i-- {
-			nodeVersion := versions[i]
-			if expectedRange(nodeVersion) {
-				return nodeVersion.FinalizeVersion()
-			}
+	// Sorted in ascending order, so run through in reverse order to get the latest matching
+	for i := len(versions) - 1; i >= 0; i-- {
+		if expectedRange(versions[i]) {
+			return manifestVersions[versions[i].String()], nil
 		}
-		log.Fatal("Unable to match node version", semverRange)
 	}
-
-	return versions[len(versions)-1].FinalizeVersion()
+	return "", fmt.Errorf("no matching version in manifest")
 }
 
-func (manifest VersionManifest) FindDownloadUrl(version string) string {
-	entry := manifest.FindEntry(version)
+func (manifest VersionManifest) FindDownloadUrl(version string) (string, error) {
+	entry, err := manifest.FindEntry(version)
+	if err != nil {
+		return "", err
+	}
 	for _, file := range entry.Files {
 		if file.Arch == manifest.OSArch() && file.Platform == "linux" {
 			// If a PlatformVersion value is set, then the download is specific to a distro version.
 			// Since not all are, verify the distro only if PlatformVersion is actually set.
 			if file.PlatformVersion != "" {
-				osRelease := utils.ReadLinuxDistroInfo()
+				osRelease, err := utils.ReadLinuxDistroInfo()
+				if err != nil {
+					return "", err
+				}
 				if osRelease.Version == file.PlatformVersion || osRelease.VersionId == file.PlatformVersion {
-					return file.DownloadUrl
+					return file.DownloadUrl, nil
 				}
 			} else {
-				return file.DownloadUrl
+				return file.DownloadUrl, nil
 			}
 		}
 	}
-	return ""
+	return "", fmt.Errorf("unable to find a download for version %s on this platform", version)
 }
 
 func (manifest VersionManifest) OSArch() string {
@@ -104,17 +125,24 @@ func (manifest VersionManifest) OSArch() string {
 	return dlArch
 }
 
-func NewVersionManifest(manifestPath string) VersionManifest {
+func NewVersionManifest(manifestPath string) (VersionManifest, error) {
 	manifest := VersionManifest{}
-	manifest.Load(manifestPath)
-	return manifest
+	err := manifest.Load(manifestPath)
+	return manifest, err
+}
+
+func NewVersionManifestFromUrl(url string) (VersionManifest, error) {
+	content, err := utils.DownloadBytesFromUrl(url)
+	if err != nil {
+		return VersionManifest{}, err
+	}
+	return NewVersionManifestFromBytes(content)
 }
 
-func NewVersionManifestFromUrl(url string) VersionManifest {
-	content := utils.DownloadBytesFromUrl(url)
+func NewVersionManifestFromBytes(content []byte) (VersionManifest, error) {
 	manifest := VersionManifest{}
 	if err := json.Unmarshal(content, &manifest.Entries); err != nil {
-		log.Fatal("Failed to unmarshal manifest contents. ", err)
+		return manifest, fmt.Errorf("failed to unmarshal manifest contents: %w", err)
 	}
-	return manifest
+	return manifest, nil
 }
@@ -4,10 +4,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"os"
 	"path/filepath"
-	"reflect"
+	"sort"
+	"strconv"
 
 	"github.com/chuxel/devpacks/internal/common/utils"
 	"github.com/tailscale/hujson"
@@ -26,77 +26,167 @@ func NewEmptyDevContainer() DevContainer {
 	}
 }
 
-func NewDevContainer(applicationFolder string) DevContainer {
+func NewDevContainer(applicationFolder string) (DevContainer, error) {
 	devcontainer := NewEmptyDevContainer()
-	devcontainer.Load(applicationFolder)
+	if _, err := devcontainer.Load(applicationFolder); err != nil {
+		return devcontainer, err
+	}
 	if devcontainer.Path == "" {
-		log.Fatal("Unable to find devcontainer.json file in ", applicationFolder)
+		return devcontainer, fmt.Errorf("unable to find devcontainer.json file in %s", applicationFolder)
 	}
-	return devcontainer
+	return devcontainer, nil
 }
 
-func (devContainer *DevContainer) Load(applicationFolder string) string {
-	content, devContainerJsonPath := loadDevContainerJsonContent(applicationFolder)
+func (devContainer *DevContainer) Load(applicationFolder string) (string, error) {
+	content, devContainerJsonPath, err := loadDevContainerJsonContent(applicationFolder)
+	if err != nil {
+		return "", err
+	}
 	if devContainerJsonPath != "" {
-		err := json.Unmarshal(content, &devContainer.Properties)
-		if err != nil {
-			log.Fatal("Failed to unmarshal devcontainer.json contents. ", err)
+		if err := json.Unmarshal(content, &devContainer.Properties); err != nil {
+			return "", fmt.Errorf("failed to unmarshal %s: %w", devContainerJsonPath, err)
 		}
-
 	}
 	devContainer.Path = devContainerJsonPath
-	return devContainerJsonPath
+	return devContainerJsonPath, nil
+}
+
+// Lifecycle commands can be a string, an array, or an object of named commands that run in parallel
+var LifecycleCommandPropertyNames = []string{"initializeCommand", "onCreateCommand", "updateContentCommand", "postCreateCommand", "postStartCommand", "postAttachCommand"}
+
+// Name used as the key for lifecycle commands from this devcontainer.json when they are combined
+// with others. This is the name of the folder it is in, which for buildpacks is the layer name.
+func (devContainer *DevContainer) SourceName() string {
+	if devContainer.Path == "" {
+		return "devcontainer"
+	}
+	folderName := filepath.Base(filepath.Dir(devContainer.Path))
+	if folderName == ".devcontainer" || folderName == "." || folderName == string(filepath.Separator) {
+		folderName = filepath.Base(filepath.Dir(filepath.Dir(devContainer.Path)))
+	}
+	return folderName
 }
 
-func (devContainer *DevContainer) Merge(inDevContainer DevContainer) {
-	devContainer.MergePropertyMap(inDevContainer.Properties)
+func (devContainer *DevContainer) Merge(inDevContainer DevContainer) error {
+	return devContainer.MergePropertyMap(inDevContainer.Properties, inDevContainer.SourceName())
 }
 
-func (devContainer *DevContainer) MergePropertyMap(inMap map[string]interface{}) {
-	// Special processing for lifecycle commands - append rather than replace
-	// TODO: Support array syntax... only string is supported for now
-	lifecyclePropNames := []string{"initializeCommand", "onCreateCommand", "updateContentCommand", "postCreateCommand", "postStartCommand", "postAttachCommand"}
-	mergedLifecycleProps := make(map[string]string)
-	for _, prop := range lifecyclePropNames {
+// Merges properties into this devcontainer.json. Lifecycle commands are combined rather than replaced
+// using the object form with one key per source (e.g. buildpack) so that they all run.
+func (devContainer *DevContainer) MergePropertyMap(inMap map[string]interface{}, inSourceName string) error {
+	mergedLifecycleProps := make(map[string]interface{})
+	for _, prop := range LifecycleCommandPropertyNames {
 		val, hasKey := devContainer.Properties[prop]
 		inVal, inHasKey := inMap[prop]
-		if hasKey {
-			if inHasKey {
-				mergedLifecycleProps[prop] = fmt.Sprint(val) + "; " + fmt.Sprint(inVal)
-			} else {
-				mergedLifecycleProps[prop] = fmt.Sprint(val)
+		if hasKey && inHasKey {
+			merged, err := MergeLifecycleCommands(val, devContainer.SourceName(), inVal, inSourceName)
+			if err != nil {
+				return fmt.Errorf("failed to merge %s: %w", prop, err)
 			}
+			mergedLifecycleProps[prop] = merged
+		} else if hasKey {
+			mergedLifecycleProps[prop] = val
 		} else if inHasKey {
-			mergedLifecycleProps[prop] = fmt.Sprint(inVal)
+			// Key the command by its source now, since this devcontainer.json's own source name would be
+			// used if it is merged with another command later
+			inObject, err := LifecycleCommandToObject(inVal, inSourceName)
+			if err != nil {
+				return fmt.Errorf("failed to merge %s: %w", prop, err)
+			}
+			mergedLifecycleProps[prop] = inObject
 		}
 	}
 
-	// Handle other properties
-	result := utils.MergeProperties(devContainer.Properties, inMap)
+	// Handle other properties using the merge rule for each
+	result, err := MergeDevContainerProperties(withoutLifecycleCommands(devContainer.Properties), withoutLifecycleCommands(inMap))
+	if err != nil {
+		return err
+	}
 
 	// Update object with result
-	devContainer.Properties = make(map[string]interface{})
-	itr := reflect.ValueOf(result).MapRange()
-	for itr.Next() {
-		key := itr.Key().String()
-		if utils.SliceContainsString(lifecyclePropNames, key) {
-			devContainer.Properties[key] = mergedLifecycleProps[key]
-		} else {
-			devContainer.Properties[key] = itr.Value().Interface()
+	devContainer.Properties = result
+	for prop, val := range mergedLifecycleProps {
+		devContainer.Properties[prop] = val
+	}
+	return nil
+}
+
+// Combines two lifecycle commands into the object form. Commands that are not already in object form
+// are keyed by their source name, with a numeric suffix added if the name is already in use.
+func MergeLifecycleCommands(existingVal interface{}, existingSourceName string, inVal interface{}, inSourceName string) (map[string]interface{}, error) {
+	result, err := LifecycleCommandToObject(existingVal, existingSourceName)
+	if err != nil {
+		return nil, err
+	}
+	inObject, err := LifecycleCommandToObject(inVal, inSourceName)
+	if err != nil {
+		return nil, err
+	}
+	// Sort keys so the result is stable
+	inKeys := make([]string, 0, len(inObject))
+	for key := range inObject {
+		inKeys = append(inKeys, key)
+	}
+	sort.Strings(inKeys)
+	for _, key := range inKeys {
+		uniqueKey := key
+		for i := 2; ; i++ {
+			if _, hasKey := result[uniqueKey]; !hasKey {
+				break
+			}
+			uniqueKey = key + "-" + strconv.Itoa(i)
+		}
+		result[uniqueKey] = inObject[key]
+	}
+	return result, nil
+}
+
+// Converts a lifecycle command in any of its forms into the object form
+func LifecycleCommandToObject(command interface{}, sourceName string) (map[string]interface{}, error) {
+	switch typedCommand := command.(type) {
+	case string:
+		return map[string]interface{}{sourceName: typedCommand}, nil
+	case []interface{}:
+		return map[string]interface{}{sourceName: typedCommand}, nil
+	case []string:
+		return map[string]interface{}{sourceName: utils.StringSliceToInterfaceSlice(typedCommand)}, nil
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(typedCommand))
+		for key, value := range typedCommand {
+			switch value.(type) {
+			case string, []interface{}:
+				result[key] = value
+			default:
+				return nil, fmt.Errorf("unsupported command type %T for %s", value, key)
+			}
+		}
+		return result, nil
+	}
+	return nil, fmt.Errorf("unsupported lifecycle command type %T", command)
+}
+
+func withoutLifecycleCommands(properties map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(properties))
+	for key, value := range properties {
+		if !utils.SliceContainsString(LifecycleCommandPropertyNames, key) {
+			result[key] = value
 		}
 	}
+	return result
 }
 
-func LoadDevContainerJsonAsMap(applicationFolder string) (map[string]json.RawMessage, string) {
+func LoadDevContainerJsonAsMap(applicationFolder string) (map[string]json.RawMessage, string, error) {
 	jsonMap := make(map[string]json.RawMessage)
-	content, devContainerJsonPath := loadDevContainerJsonContent(applicationFolder)
+	content, devContainerJsonPath, err := loadDevContainerJsonContent(applicationFolder)
+	if err != nil {
+		return jsonMap, "", err
+	}
 	if devContainerJsonPath != "" {
-		err := json.Unmarshal(content, &jsonMap)
-		if err != nil {
-			log.Fatal(err)
+		if err := json.Unmarshal(content, &jsonMap); err != nil {
+			return jsonMap, "", fmt.Errorf("failed to unmarshal %s: %w", devContainerJsonPath, err)
 		}
 	}
-	return jsonMap, devContainerJsonPath
+	return jsonMap, devContainerJsonPath, nil
 }
 
 func (devContainer *DevContainer) ConvertUnsupportedPropertiesToRunArgs() {
@@ -136,43 +226,43 @@ func (devContainer *DevContainer) ConvertUnsupportedPropertiesToRunArgs() {
 	devContainer.Properties["runArgs"] = runArgs
 }
 
-func loadDevContainerJsonContent(applicationFolder string) ([]byte, string) {
-	devContainerJsonPath := findDevContainerJson(applicationFolder)
-	if devContainerJsonPath == "" {
-		return []byte{}, devContainerJsonPath
+func loadDevContainerJsonContent(applicationFolder string) ([]byte, string, error) {
+	devContainerJsonPath, err := findDevContainerJson(applicationFolder)
+	if err != nil || devContainerJsonPath == "" {
+		return []byte{}, devContainerJsonPath, err
 	}
 	content, err := ioutil.ReadFile(devContainerJsonPath)
 	if err != nil {
-		log.Fatal(err)
+		return nil, "", fmt.Errorf("failed to read %s: %w", devContainerJsonPath, err)
 	}
 	// Strip out comments to enable parsing
 	ast, err := hujson.Parse(content)
 	if err != nil {
-		log.Fatal(err)
+		return nil, "", fmt.Errorf("failed to parse %s: %w", devContainerJsonPath, err)
 	}
 	ast.Standardize()
 	content = ast.Pack()
 
-	return content, devContainerJsonPath
+	return content, devContainerJsonPath, nil
 }
 
-func findDevContainerJson(applicationFolder string) string {
+func findDevContainerJson(applicationFolder string) (string, error) {
 	// Load devcontainer.json
 	if applicationFolder == "" {
 		var err error
 		if applicationFolder, err = os.Getwd(); err != nil {
-			log.Fatal("Failed to get current working directory. ", err)
+			return "", fmt.Errorf("failed to get current working directory: %w", err)
 		}
 	}
 
 	possiblePaths := []string{filepath.Join(applicationFolder, ".devcontainer", "devcontainer.json"), filepath.Join(applicationFolder, ".devcontainer.json"), filepath.Join(applicationFolder, "devcontainer.json")}
 	for _, expectedPath := range possiblePaths {
 		if _, err := os.Stat(expectedPath); err == nil {
-			return expectedPath
+			return expectedPath, nil
 		} else if !os.IsNotExist(err) {
-			log.Fatal("Stat error for path. ", err)
+			return "", fmt.Errorf("stat error for path %s: %w", expectedPath, err)
 		}
 	}
 
-	return ""
+	return "", nil
 }
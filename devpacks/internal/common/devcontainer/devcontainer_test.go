@@ -0,0 +1,131 @@
+package devcontainer
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestMergeLifecycleCommands(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string
+		in       string
+		expected string
+	}{
+		{
+			name:     "strings are keyed by source",
+			existing: `{"postCreateCommand": "npm install"}`,
+			in:       `{"postCreateCommand": "pip install -r requirements.txt"}`,
+			expected: `{"postCreateCommand": {"existing": "npm install", "incoming": "pip install -r requirements.txt"}}`,
+		},
+		{
+			name:     "arrays are kept as arrays",
+			existing: `{"onCreateCommand": ["echo", "a"]}`,
+			in:       `{"onCreateCommand": "echo b"}`,
+			expected: `{"onCreateCommand": {"existing": ["echo", "a"], "incoming": "echo b"}}`,
+		},
+		{
+			name:     "objects are combined with a suffix for duplicate names",
+			existing: `{"postStartCommand": {"incoming": "echo a", "other": "echo b"}}`,
+			in:       `{"postStartCommand": "echo c"}`,
+			expected: `{"postStartCommand": {"incoming": "echo a", "other": "echo b", "incoming-2": "echo c"}}`,
+		},
+		{
+			name:     "incoming commands are keyed by source even without an existing one",
+			existing: `{"postAttachCommand": "echo a"}`,
+			in:       `{"updateContentCommand": ["echo", "b"]}`,
+			expected: `{"postAttachCommand": "echo a", "updateContentCommand": {"incoming": ["echo", "b"]}}`,
+		},
+		{
+			name:     "initializeCommand is combined too",
+			existing: `{"initializeCommand": "echo a"}`,
+			in:       `{"initializeCommand": {"x": "echo b"}}`,
+			expected: `{"initializeCommand": {"existing": "echo a", "x": "echo b"}}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			devContainer := DevContainer{Properties: parseJsonObject(t, test.existing), Path: "/layers/existing/devcontainer.json"}
+			inDevContainer := DevContainer{Properties: parseJsonObject(t, test.in), Path: "/layers/incoming/devcontainer.json"}
+			if err := devContainer.Merge(inDevContainer); err != nil {
+				t.Fatal(err)
+			}
+			expected := parseJsonObject(t, test.expected)
+			if !reflect.DeepEqual(devContainer.Properties, expected) {
+				resultJson, _ := json.Marshal(devContainer.Properties)
+				t.Errorf("expected %s, got %s", test.expected, resultJson)
+			}
+		})
+	}
+}
+
+// Combining buildpack devcontainer.json files into an empty one keys each command by buildpack
+func TestMergeLifecycleCommandsIntoEmpty(t *testing.T) {
+	devContainer := NewEmptyDevContainer()
+	for _, layerName := range []string{"npminstall", "pipinstall", "goutils"} {
+		inDevContainer := DevContainer{
+			Properties: map[string]interface{}{"postCreateCommand": "echo " + layerName},
+			Path:       "/layers/" + layerName + "/devcontainer.json",
+		}
+		if err := devContainer.Merge(inDevContainer); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected := map[string]interface{}{"postCreateCommand": map[string]interface{}{
+		"npminstall": "echo npminstall",
+		"pipinstall": "echo pipinstall",
+		"goutils":    "echo goutils",
+	}}
+	if !reflect.DeepEqual(devContainer.Properties, expected) {
+		t.Errorf("expected %v, got %v", expected, devContainer.Properties)
+	}
+}
+
+func TestLifecycleCommandToObject(t *testing.T) {
+	tests := []struct {
+		name        string
+		command     interface{}
+		expected    map[string]interface{}
+		expectError bool
+	}{
+		{name: "string", command: "npm install", expected: map[string]interface{}{"npminstall": "npm install"}},
+		{name: "array", command: []interface{}{"npm", "install"}, expected: map[string]interface{}{"npminstall": []interface{}{"npm", "install"}}},
+		{name: "string slice", command: []string{"npm", "install"}, expected: map[string]interface{}{"npminstall": []interface{}{"npm", "install"}}},
+		{name: "object", command: map[string]interface{}{"a": "echo a", "b": []interface{}{"echo", "b"}}, expected: map[string]interface{}{"a": "echo a", "b": []interface{}{"echo", "b"}}},
+		{name: "object with invalid command", command: map[string]interface{}{"a": 1.0}, expectError: true},
+		{name: "number", command: 1.0, expectError: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := LifecycleCommandToObject(test.command, "npminstall")
+			if test.expectError {
+				if err == nil {
+					t.Errorf("expected an error, got %v", result)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(result, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestSourceName(t *testing.T) {
+	tests := map[string]string{
+		"":                                     "devcontainer",
+		"/layers/npminstall/devcontainer.json": "npminstall",
+		"/workspace/app/.devcontainer/devcontainer.json": "app",
+		"/workspace/app/.devcontainer.json":              "app",
+	}
+	for devContainerPath, expected := range tests {
+		devContainer := DevContainer{Path: devContainerPath}
+		if sourceName := devContainer.SourceName(); sourceName != expected {
+			t.Errorf("expected source name %s for %s, got %s", expected, devContainerPath, sourceName)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+package devcontainer
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Merges the value of a property from one devcontainer.json into the value from another
+type propertyMergeFunc func(existingVal interface{}, inVal interface{}) (interface{}, error)
+
+// Merge rules by property based on https://containers.dev/implementors/spec/#merge-logic. Properties
+// not listed here are deep merged where objects are merged by key, arrays are combined without
+// duplicates, and the last value wins for anything else.
+var propertyMergeRules = map[string]propertyMergeFunc{
+	"init":                  mergeBooleanOr,
+	"privileged":            mergeBooleanOr,
+	"capAdd":                mergeUnion,
+	"securityOpt":           mergeUnion,
+	"runArgs":               mergeArgumentGroups,
+	"forwardPorts":          mergeUnion,
+	"entrypoint":            mergeUnion,
+	"mounts":                mergeMounts,
+	"containerEnv":          mergeKeyed,
+	"remoteEnv":             mergeKeyed,
+	"portsAttributes":       mergeKeyed,
+	"features":              mergeKeyed,
+	"hostRequirements":      mergeMaxPerKey,
+	"customizations":        mergeCustomizations,
+	"remoteUser":            mergeLastWins,
+	"containerUser":         mergeLastWins,
+	"userEnvProbe":          mergeLastWins,
+	"overrideCommand":       mergeLastWins,
+	"shutdownAction":        mergeLastWins,
+	"updateRemoteUserUID":   mergeLastWins,
+	"waitFor":               mergeLastWins,
+	"otherPortsAttributes":  mergeLastWins,
+	"workspaceFolder":       mergeLastWins,
+	"workspaceMount":        mergeLastWins,
+	"name":                  mergeLastWins,
+	"image":                 mergeLastWins,
+	"shutdownActionTimeout": mergeLastWins,
+}
+
+// Merge rules for properties under customizations.vscode
+var vscodeCustomizationMergeRules = map[string]propertyMergeFunc{
+	"extensions": mergeUnion,
+	"settings":   mergeKeyed,
+}
+
+// Merges two sets of devcontainer.json properties (excluding lifecycle commands) using the merge rule for each property
+func MergeDevContainerProperties(existingProps map[string]interface{}, inProps map[string]interface{}) (map[string]interface{}, error) {
+	return mergeWithRules(existingProps, inProps, propertyMergeRules)
+}
+
+func mergeWithRules(existingProps map[string]interface{}, inProps map[string]interface{}, rules map[string]propertyMergeFunc) (map[string]interface{}, error) {
+	result := make(map[string]interface{}, len(existingProps)+len(inProps))
+	for key, value := range existingProps {
+		result[key] = value
+	}
+	for key, inVal := range inProps {
+		existingVal, hasKey := result[key]
+		if !hasKey || existingVal == nil {
+			result[key] = inVal
+			continue
+		}
+		mergeFunc, hasRule := rules[key]
+		if !hasRule {
+			mergeFunc = mergeDeep
+		}
+		merged, err := mergeFunc(existingVal, inVal)
+		if err != nil {
+			return nil, fmt.Errorf("failed to merge %s: %w", key, err)
+		}
+		result[key] = merged
+	}
+	return result, nil
+}
+
+func mergeLastWins(existingVal interface{}, inVal interface{}) (interface{}, error) {
+	return inVal, nil
+}
+
+func mergeBooleanOr(existingVal interface{}, inVal interface{}) (interface{}, error) {
+	existingBool, existingIsBool := existingVal.(bool)
+	inBool, inIsBool := inVal.(bool)
+	if !existingIsBool || !inIsBool {
+		return nil, fmt.Errorf("expected boolean values, got %T and %T", existingVal, inVal)
+	}
+	return existingBool || inBool, nil
+}
+
+// Combines arrays, skipping any values that are already present
+func mergeUnion(existingVal interface{}, inVal interface{}) (interface{}, error) {
+	existingSlice, err := toInterfaceSlice(existingVal)
+	if err != nil {
+		return nil, err
+	}
+	inSlice, err := toInterfaceSlice(inVal)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]interface{}, 0, len(existingSlice)+len(inSlice))
+	for _, item := range append(existingSlice, inSlice...) {
+		if !sliceContainsValue(result, item) {
+			result = append(result, item)
+		}
+	}
+	return result, nil
+}
+
+// Concatenates arrays of arguments like runArgs, where values such as ["--cap-add", "SYS_PTRACE"] only
+// make sense together. Individual values are never removed, but an array that is already present in
+// full (e.g. the same snippet merged twice) is not added again.
+func mergeArgumentGroups(existingVal interface{}, inVal interface{}) (interface{}, error) {
+	existingSlice, err := toInterfaceSlice(existingVal)
+	if err != nil {
+		return nil, err
+	}
+	inSlice, err := toInterfaceSlice(inVal)
+	if err != nil {
+		return nil, err
+	}
+	if sliceContainsRun(existingSlice, inSlice) {
+		return existingSlice, nil
+	}
+	return append(append(make([]interface{}, 0, len(existingSlice)+len(inSlice)), existingSlice...), inSlice...), nil
+}
+
+// Merges objects by key with the last value winning for each key
+func mergeKeyed(existingVal interface{}, inVal interface{}) (interface{}, error) {
+	existingMap, existingIsMap := existingVal.(map[string]interface{})
+	inMap, inIsMap := inVal.(map[string]interface{})
+	if !existingIsMap || !inIsMap {
+		return nil, fmt.Errorf("expected objects, got %T and %T", existingVal, inVal)
+	}
+	return mergeWithRules(existingMap, inMap, map[string]propertyMergeFunc{})
+}
+
+// Merges objects by key keeping the larger value when both are numbers (e.g. hostRequirements.cpus)
+func mergeMaxPerKey(existingVal interface{}, inVal interface{}) (interface{}, error) {
+	existingMap, existingIsMap := existingVal.(map[string]interface{})
+	inMap, inIsMap := inVal.(map[string]interface{})
+	if !existingIsMap || !inIsMap {
+		return nil, fmt.Errorf("expected objects, got %T and %T", existingVal, inVal)
+	}
+	result := make(map[string]interface{}, len(existingMap)+len(inMap))
+	for key, value := range existingMap {
+		result[key] = value
+	}
+	for key, value := range inMap {
+		existingNumber, existingIsNumber := result[key].(float64)
+		inNumber, inIsNumber := value.(float64)
+		if existingIsNumber && inIsNumber && existingNumber > inNumber {
+			continue
+		}
+		result[key] = value
+	}
+	return result, nil
+}
+
+// Combines mounts, with the last mount for a given target winning
+func mergeMounts(existingVal interface{}, inVal interface{}) (interface{}, error) {
+	existingSlice, err := toInterfaceSlice(existingVal)
+	if err != nil {
+		return nil, err
+	}
+	inSlice, err := toInterfaceSlice(inVal)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]interface{}, 0, len(existingSlice)+len(inSlice))
+	for _, mount := range append(existingSlice, inSlice...) {
+		target := mountTarget(mount)
+		replaced := false
+		for i, resultMount := range result {
+			if (target != "" && mountTarget(resultMount) == target) || reflect.DeepEqual(resultMount, mount) {
+				result[i] = mount
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			result = append(result, mount)
+		}
+	}
+	return result, nil
+}
+
+// Merges customizations by tool. VS Code extensions are combined and settings are merged by key.
+func mergeCustomizations(existingVal interface{}, inVal interface{}) (interface{}, error) {
+	existingMap, existingIsMap := existingVal.(map[string]interface{})
+	inMap, inIsMap := inVal.(map[string]interface{})
+	if !existingIsMap || !inIsMap {
+		return nil, fmt.Errorf("expected objects, got %T and %T", existingVal, inVal)
+	}
+	return mergeWithRules(existingMap, inMap, map[string]propertyMergeFunc{
+		"vscode": func(existingVal interface{}, inVal interface{}) (interface{}, error) {
+			existingMap, existingIsMap := existingVal.(map[string]interface{})
+			inMap, inIsMap := inVal.(map[string]interface{})
+			if !existingIsMap || !inIsMap {
+				return nil, fmt.Errorf("expected objects, got %T and %T", existingVal, inVal)
+			}
+			return mergeWithRules(existingMap, inMap, vscodeCustomizationMergeRules)
+		},
+	})
+}
+
+// Merges objects recursively, combines arrays without duplicates, and otherwise uses the last value
+func mergeDeep(existingVal interface{}, inVal interface{}) (interface{}, error) {
+	existingMap, existingIsMap := existingVal.(map[string]interface{})
+	inMap, inIsMap := inVal.(map[string]interface{})
+	if existingIsMap && inIsMap {
+		return mergeWithRules(existingMap, inMap, map[string]propertyMergeFunc{})
+	}
+	existingKind := reflect.TypeOf(existingVal).Kind()
+	inKind := reflect.TypeOf(inVal).Kind()
+	if (existingKind == reflect.Slice || existingKind == reflect.Array) && (inKind == reflect.Slice || inKind == reflect.Array) {
+		return mergeUnion(existingVal, inVal)
+	}
+	return inVal, nil
+}
+
+func toInterfaceSlice(value interface{}) ([]interface{}, error) {
+	reflectValue := reflect.ValueOf(value)
+	if reflectValue.Kind() != reflect.Slice && reflectValue.Kind() != reflect.Array {
+		return nil, fmt.Errorf("expected an array, got %T", value)
+	}
+	result := make([]interface{}, reflectValue.Len())
+	for i := 0; i < reflectValue.Len(); i++ {
+		result[i] = reflectValue.Index(i).Interface()
+	}
+	return result, nil
+}
+
+func sliceContainsValue(slice []interface{}, value interface{}) bool {
+	for _, item := range slice {
+		if reflect.DeepEqual(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns true if run appears in slice as a contiguous sequence of values
+func sliceContainsRun(slice []interface{}, run []interface{}) bool {
+	if len(run) == 0 {
+		return true
+	}
+	for start := 0; start+len(run) <= len(slice); start++ {
+		if reflect.DeepEqual(slice[start:start+len(run)], run) {
+			return true
+		}
+	}
+	return false
+}
+
+// Mounts can be in the docker --mount string form or an object
+func mountTarget(mount interface{}) string {
+	switch typedMount := mount.(type) {
+	case string:
+		for _, part := range strings.Split(typedMount, ",") {
+			nameValue := strings.SplitN(strings.TrimSpace(part), "=", 2)
+			if len(nameValue) == 2 && (nameValue[0] == "target" || nameValue[0] == "destination" || nameValue[0] == "dst") {
+				return nameValue[1]
+			}
+		}
+	case map[string]interface{}:
+		if target, ok := typedMount["target"].(string); ok {
+			return target
+		}
+	}
+	return ""
+}
@@ -0,0 +1,148 @@
+package devcontainer
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func parseJsonObject(t *testing.T, content string) map[string]interface{} {
+	t.Helper()
+	result := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(content), &result); err != nil {
+		t.Fatalf("invalid json %s: %v", content, err)
+	}
+	return result
+}
+
+func TestMergeDevContainerProperties(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string
+		in       string
+		expected string
+	}{
+		{
+			name:     "init is true if either is true",
+			existing: `{"init": true}`,
+			in:       `{"init": false}`,
+			expected: `{"init": true}`,
+		},
+		{
+			name:     "privileged is true if either is true",
+			existing: `{"privileged": false}`,
+			in:       `{"privileged": true}`,
+			expected: `{"privileged": true}`,
+		},
+		{
+			name:     "capAdd is combined without duplicates",
+			existing: `{"capAdd": ["SYS_PTRACE", "NET_ADMIN"]}`,
+			in:       `{"capAdd": ["SYS_PTRACE", "SYS_ADMIN"]}`,
+			expected: `{"capAdd": ["SYS_PTRACE", "NET_ADMIN", "SYS_ADMIN"]}`,
+		},
+		{
+			name:     "securityOpt is combined without duplicates",
+			existing: `{"securityOpt": ["seccomp=unconfined"]}`,
+			in:       `{"securityOpt": ["seccomp=unconfined", "apparmor=unconfined"]}`,
+			expected: `{"securityOpt": ["seccomp=unconfined", "apparmor=unconfined"]}`,
+		},
+		{
+			name:     "runArgs are concatenated",
+			existing: `{"runArgs": ["--cap-add", "SYS_ADMIN"]}`,
+			in:       `{"runArgs": ["--cap-add", "SYS_PTRACE"]}`,
+			expected: `{"runArgs": ["--cap-add", "SYS_ADMIN", "--cap-add", "SYS_PTRACE"]}`,
+		},
+		{
+			name:     "runArgs already present as a group are not repeated",
+			existing: `{"runArgs": ["--cap-add", "SYS_ADMIN", "--init"]}`,
+			in:       `{"runArgs": ["--cap-add", "SYS_ADMIN"]}`,
+			expected: `{"runArgs": ["--cap-add", "SYS_ADMIN", "--init"]}`,
+		},
+		{
+			name:     "forwardPorts are combined without duplicates",
+			existing: `{"forwardPorts": [3000, "db:5432"]}`,
+			in:       `{"forwardPorts": [3000, 8080]}`,
+			expected: `{"forwardPorts": [3000, "db:5432", 8080]}`,
+		},
+		{
+			name:     "mounts with the same target are replaced",
+			existing: `{"mounts": ["source=a,target=/data,type=volume", {"source": "b", "target": "/cache", "type": "volume"}]}`,
+			in:       `{"mounts": ["source=c,target=/data,type=volume", {"source": "d", "target": "/other", "type": "volume"}]}`,
+			expected: `{"mounts": ["source=c,target=/data,type=volume", {"source": "b", "target": "/cache", "type": "volume"}, {"source": "d", "target": "/other", "type": "volume"}]}`,
+		},
+		{
+			name:     "containerEnv is merged by key",
+			existing: `{"containerEnv": {"A": "1", "B": "1"}}`,
+			in:       `{"containerEnv": {"B": "2", "C": "2"}}`,
+			expected: `{"containerEnv": {"A": "1", "B": "2", "C": "2"}}`,
+		},
+		{
+			name:     "remoteEnv is merged by key",
+			existing: `{"remoteEnv": {"PATH": "/a:${containerEnv:PATH}"}}`,
+			in:       `{"remoteEnv": {"PATH": "/b:${containerEnv:PATH}", "X": "1"}}`,
+			expected: `{"remoteEnv": {"PATH": "/b:${containerEnv:PATH}", "X": "1"}}`,
+		},
+		{
+			name:     "hostRequirements keep the larger value",
+			existing: `{"hostRequirements": {"cpus": 4, "memory": "8gb"}}`,
+			in:       `{"hostRequirements": {"cpus": 2, "memory": "16gb"}}`,
+			expected: `{"hostRequirements": {"cpus": 4, "memory": "16gb"}}`,
+		},
+		{
+			name:     "vscode extensions are combined and settings merged by key",
+			existing: `{"customizations": {"vscode": {"extensions": ["golang.Go"], "settings": {"a": 1, "b": 1}}, "other": {"x": 1}}}`,
+			in:       `{"customizations": {"vscode": {"extensions": ["golang.Go", "ms-python.python"], "settings": {"b": 2}}}}`,
+			expected: `{"customizations": {"vscode": {"extensions": ["golang.Go", "ms-python.python"], "settings": {"a": 1, "b": 2}}, "other": {"x": 1}}}`,
+		},
+		{
+			name:     "remoteUser uses the last value",
+			existing: `{"remoteUser": "root"}`,
+			in:       `{"remoteUser": "vscode"}`,
+			expected: `{"remoteUser": "vscode"}`,
+		},
+		{
+			name:     "missing properties are added",
+			existing: `{"name": "a"}`,
+			in:       `{"workspaceFolder": "/workspace"}`,
+			expected: `{"name": "a", "workspaceFolder": "/workspace"}`,
+		},
+		{
+			name:     "other properties are deep merged",
+			existing: `{"x-custom": {"list": [1], "nested": {"a": 1}}}`,
+			in:       `{"x-custom": {"list": [1, 2], "nested": {"b": 2}}}`,
+			expected: `{"x-custom": {"list": [1, 2], "nested": {"a": 1, "b": 2}}}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := MergeDevContainerProperties(parseJsonObject(t, test.existing), parseJsonObject(t, test.in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected := parseJsonObject(t, test.expected)
+			if !reflect.DeepEqual(result, expected) {
+				resultJson, _ := json.Marshal(result)
+				t.Errorf("expected %s, got %s", test.expected, resultJson)
+			}
+		})
+	}
+}
+
+func TestMergeDevContainerPropertiesErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string
+		in       string
+	}{
+		{"init must be a boolean", `{"init": true}`, `{"init": "yes"}`},
+		{"capAdd must be an array", `{"capAdd": ["SYS_PTRACE"]}`, `{"capAdd": "SYS_ADMIN"}`},
+		{"containerEnv must be an object", `{"containerEnv": {"A": "1"}}`, `{"containerEnv": ["A=1"]}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := MergeDevContainerProperties(parseJsonObject(t, test.existing), parseJsonObject(t, test.in)); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,14 @@
+package downloads
+
+import "time"
+
+const LAYER_NAME = "devpacks-downloads"
+
+// How long a cached download is used before it is revalidated with the server (Go duration syntax)
+const CACHE_TTL_ENV_VAR_NAME = "BP_DEVPACKS_DOWNLOAD_CACHE_TTL"
+const DEFAULT_CACHE_TTL = 24 * time.Hour
+
+// Cached downloads that have not been used by a build for this long are removed
+const CACHE_MAX_UNUSED = 30 * 24 * time.Hour
+
+const METADATA_FILE_SUFFIX = ".json"
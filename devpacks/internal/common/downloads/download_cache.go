@@ -0,0 +1,179 @@
+package downloads
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/buildpacks/libcnb"
+	"github.com/chuxel/devpacks/internal/common/utils"
+)
+
+// Stores downloaded files in a cache-only layer so they can be reused across builds
+type DownloadCache struct {
+	Path string
+}
+
+type CacheEntry struct {
+	Url          string
+	ETag         string
+	LastModified string
+	Sha256       string
+	Fetched      time.Time
+	LastUsed     time.Time
+}
+
+type CacheLayerContributor struct {
+	// Implements libcnb.LayerContributor
+
+	// Contribute(context libcnb.ContributeContext) (libcnb.Layer, error)
+	// Name() string
+}
+
+func NewDownloadCache(context libcnb.BuildContext) DownloadCache {
+	return DownloadCache{Path: filepath.Join(context.Layers.Path, LAYER_NAME)}
+}
+
+// Implementation of libcnb.LayerContributor.Name
+func (contrib CacheLayerContributor) Name() string {
+	return LAYER_NAME
+}
+
+// Implementation of libcnb.LayerContributor.Contribute
+func (contrib CacheLayerContributor) Contribute(layer libcnb.Layer) (libcnb.Layer, error) {
+	if err := os.MkdirAll(layer.Path, 0755); err != nil {
+		return layer, fmt.Errorf("unable to create layer folder %s: %w", layer.Path, err)
+	}
+	if err := (DownloadCache{Path: layer.Path}).prune(); err != nil {
+		return layer, err
+	}
+	layer.LayerTypes = libcnb.LayerTypes{
+		Build:  false,
+		Cache:  true,
+		Launch: false,
+	}
+	return layer, nil
+}
+
+// Returns the contents of the url, using the cached copy when it is still valid
+func (cache DownloadCache) Bytes(dlUrl string) ([]byte, error) {
+	filePath, _, err := cache.File(dlUrl)
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cached copy of %s: %w", dlUrl, err)
+	}
+	return content, nil
+}
+
+// Returns the path to a cached copy of the url and its sha256. Cached copies are used as-is until
+// the TTL expires, and then revalidated using a conditional GET.
+func (cache DownloadCache) File(dlUrl string) (string, string, error) {
+	filePath := cache.filePath(dlUrl)
+	entry, hasEntry := cache.readEntry(dlUrl)
+	if hasEntry {
+		if _, err := os.Stat(filePath); err != nil {
+			hasEntry = false
+		}
+	}
+
+	if hasEntry && time.Since(entry.Fetched) < cacheTtl() {
+		log.Println("Using cached download of", dlUrl)
+	} else {
+		validators := utils.DownloadValidators{}
+		if hasEntry {
+			validators = utils.DownloadValidators{ETag: entry.ETag, LastModified: entry.LastModified}
+		}
+		validators, modified, digest, err := utils.DownloadToFileIfModified(dlUrl, filePath, validators)
+		if err != nil {
+			if !hasEntry {
+				return "", "", err
+			}
+			// Fall back on the cached copy if the server cannot be reached
+			log.Println("Unable to revalidate", dlUrl, "- using cached download.", err)
+		} else if modified {
+			entry = CacheEntry{Url: dlUrl, ETag: validators.ETag, LastModified: validators.LastModified, Sha256: digest, Fetched: time.Now()}
+		} else {
+			log.Println("Cached download of", dlUrl, "is still valid")
+			entry.Fetched = time.Now()
+		}
+	}
+	entry.LastUsed = time.Now()
+	if err := cache.writeEntry(entry); err != nil {
+		return "", "", err
+	}
+	return filePath, entry.Sha256, nil
+}
+
+// Removes entries that have not been used recently and incomplete downloads
+func (cache DownloadCache) prune() error {
+	files, err := os.ReadDir(cache.Path)
+	if err != nil {
+		return fmt.Errorf("failed to read contents of %s: %w", cache.Path, err)
+	}
+	for _, file := range files {
+		if !strings.HasSuffix(file.Name(), METADATA_FILE_SUFFIX) {
+			continue
+		}
+		metadataPath := filepath.Join(cache.Path, file.Name())
+		var entry CacheEntry
+		content, err := os.ReadFile(metadataPath)
+		if err == nil {
+			err = json.Unmarshal(content, &entry)
+		}
+		if err != nil || time.Since(entry.LastUsed) > CACHE_MAX_UNUSED {
+			log.Println("Removing unused cached download", entry.Url)
+			filePath := strings.TrimSuffix(metadataPath, METADATA_FILE_SUFFIX)
+			for _, toRemove := range []string{filePath, filePath + utils.PARTIAL_DOWNLOAD_SUFFIX, filePath + utils.PARTIAL_DOWNLOAD_SUFFIX + utils.PARTIAL_VALIDATORS_SUFFIX, metadataPath} {
+				if err := os.RemoveAll(toRemove); err != nil {
+					return fmt.Errorf("failed to remove %s: %w", toRemove, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (cache DownloadCache) readEntry(dlUrl string) (CacheEntry, bool) {
+	var entry CacheEntry
+	content, err := os.ReadFile(cache.filePath(dlUrl) + METADATA_FILE_SUFFIX)
+	if err != nil {
+		return entry, false
+	}
+	if err := json.Unmarshal(content, &entry); err != nil || entry.Url != dlUrl {
+		return entry, false
+	}
+	return entry, true
+}
+
+func (cache DownloadCache) writeEntry(entry CacheEntry) error {
+	content, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to convert cache entry for %s to json: %w", entry.Url, err)
+	}
+	if err := utils.WriteFile(cache.filePath(entry.Url)+METADATA_FILE_SUFFIX, content); err != nil {
+		return fmt.Errorf("failed to write cache entry for %s: %w", entry.Url, err)
+	}
+	return nil
+}
+
+// Files are keyed by a hash of the url since urls can contain characters that are not valid in file names
+func (cache DownloadCache) filePath(dlUrl string) string {
+	hash := sha256.Sum256([]byte(dlUrl))
+	return filepath.Join(cache.Path, hex.EncodeToString(hash[:]))
+}
+
+func cacheTtl() time.Duration {
+	if ttl, err := time.ParseDuration(os.Getenv(CACHE_TTL_ENV_VAR_NAME)); err == nil && ttl >= 0 {
+		return ttl
+	}
+	return DEFAULT_CACHE_TTL
+}
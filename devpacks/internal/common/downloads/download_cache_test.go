@@ -0,0 +1,128 @@
+package downloads
+
+import (
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/buildpacks/libcnb"
+	"github.com/chuxel/devpacks/internal/common/harness"
+	"github.com/chuxel/devpacks/internal/common/utils"
+)
+
+const testUrl = "https://example.com/releases/index.json"
+
+// Routes downloads through a stub transport that serves the url with validators
+func useStubTransport(t *testing.T) *harness.StubTransport {
+	t.Helper()
+	transport := harness.NewStubTransport()
+	transport.Responses[testUrl] = harness.StubResponse{
+		StatusCode: http.StatusOK,
+		Body:       []byte("v1"),
+		Header:     http.Header{"Etag": {`"v1"`}, "Last-Modified": {"Wed, 01 Mar 2023 00:00:00 GMT"}},
+	}
+	utils.SetHttpClient(&http.Client{Transport: transport})
+	t.Cleanup(func() { utils.SetHttpClient(nil) })
+	return transport
+}
+
+func TestDownloadCacheRevalidation(t *testing.T) {
+	t.Setenv(CACHE_TTL_ENV_VAR_NAME, "0s")
+	transport := useStubTransport(t)
+	cache := DownloadCache{Path: t.TempDir()}
+	if content, err := cache.Bytes(testUrl); err != nil || string(content) != "v1" {
+		t.Fatalf("expected v1, got %q (%v)", content, err)
+	}
+
+	// An expired entry is revalidated with its validators, and a 304 keeps the cached file
+	transport.Responses[testUrl] = harness.StubResponse{StatusCode: http.StatusNotModified}
+	content, err := cache.Bytes(testUrl)
+	if err != nil || string(content) != "v1" {
+		t.Fatalf("expected the cached v1 after a 304, got %q (%v)", content, err)
+	}
+	if len(transport.Requests) != 2 {
+		t.Fatalf("expected a revalidation request, got %v", transport.RequestedUrls())
+	}
+	revalidation := transport.Requests[1]
+	if ifNoneMatch := revalidation.Header.Get("If-None-Match"); ifNoneMatch != `"v1"` {
+		t.Errorf("expected If-None-Match \"v1\", got %q", ifNoneMatch)
+	}
+	if ifModifiedSince := revalidation.Header.Get("If-Modified-Since"); ifModifiedSince != "Wed, 01 Mar 2023 00:00:00 GMT" {
+		t.Errorf("expected If-Modified-Since from Last-Modified, got %q", ifModifiedSince)
+	}
+
+	// A changed file replaces the cached copy
+	transport.AddBytes(testUrl, []byte("v2"))
+	if content, err := cache.Bytes(testUrl); err != nil || string(content) != "v2" {
+		t.Errorf("expected v2 after the file changed, got %q (%v)", content, err)
+	}
+}
+
+func TestDownloadCacheTtl(t *testing.T) {
+	transport := useStubTransport(t)
+	cache := DownloadCache{Path: t.TempDir()}
+	t.Setenv(CACHE_TTL_ENV_VAR_NAME, "1h")
+	for i := 0; i < 2; i++ {
+		if _, err := cache.Bytes(testUrl); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(transport.Requests) != 1 {
+		t.Errorf("expected the cached copy to be used within the TTL, got %v", transport.RequestedUrls())
+	}
+
+	// Once the TTL expires the entry is checked with the server again
+	entry, _ := cache.readEntry(testUrl)
+	entry.Fetched = time.Now().Add(-2 * time.Hour)
+	if err := cache.writeEntry(entry); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.Bytes(testUrl); err != nil {
+		t.Fatal(err)
+	}
+	if len(transport.Requests) != 2 {
+		t.Errorf("expected a request after the TTL expired, got %v", transport.RequestedUrls())
+	}
+}
+
+func TestDownloadCachePrune(t *testing.T) {
+	useStubTransport(t)
+	cache := DownloadCache{Path: t.TempDir()}
+	const recentUrl = "https://example.com/recent.json"
+	for _, dlUrl := range []string{testUrl, recentUrl} {
+		if err := utils.WriteFile(cache.filePath(dlUrl), []byte("content")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	unused := CacheEntry{Url: testUrl, Fetched: time.Now().Add(-40 * 24 * time.Hour), LastUsed: time.Now().Add(-31 * 24 * time.Hour)}
+	recent := CacheEntry{Url: recentUrl, Fetched: time.Now().Add(-40 * 24 * time.Hour), LastUsed: time.Now().Add(-29 * 24 * time.Hour)}
+	for _, entry := range []CacheEntry{unused, recent} {
+		if err := cache.writeEntry(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Leftovers from an interrupted download of the unused entry go too
+	if err := utils.WriteFile(cache.filePath(testUrl)+utils.PARTIAL_DOWNLOAD_SUFFIX, []byte("cont")); err != nil {
+		t.Fatal(err)
+	}
+
+	layer, err := CacheLayerContributor{}.Contribute(libcnb.Layer{Name: LAYER_NAME, Path: cache.Path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !layer.LayerTypes.Cache || layer.LayerTypes.Build || layer.LayerTypes.Launch {
+		t.Errorf("expected a cache-only layer, got %+v", layer.LayerTypes)
+	}
+	for _, removed := range []string{cache.filePath(testUrl), cache.filePath(testUrl) + METADATA_FILE_SUFFIX, cache.filePath(testUrl) + utils.PARTIAL_DOWNLOAD_SUFFIX} {
+		if _, err := os.Stat(removed); !os.IsNotExist(err) {
+			t.Errorf("expected %s unused for 30 days to be removed", removed)
+		}
+	}
+	if _, hasEntry := cache.readEntry(recentUrl); !hasEntry {
+		t.Error("expected the recently used entry to be kept")
+	}
+	if _, err := os.Stat(cache.filePath(recentUrl)); err != nil {
+		t.Errorf("expected the recently used file to be kept: %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package harness
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Creates a .tar.gz with the files (keyed by path) and entries for their parent folders, which is
+// enough to stand in for a runtime download. Files in a bin folder are executable.
+func TarGz(files map[string]string) ([]byte, error) {
+	names := []string{}
+	folders := map[string]bool{}
+	for name := range files {
+		names = append(names, name)
+		for folder := path.Dir(name); folder != "." && folder != "/"; folder = path.Dir(folder) {
+			folders[folder] = true
+		}
+	}
+	for folder := range folders {
+		names = append(names, folder+"/")
+	}
+	// Sorting puts each folder before its contents
+	sort.Strings(names)
+
+	var buffer bytes.Buffer
+	gzWriter := gzip.NewWriter(&buffer)
+	tarWriter := tar.NewWriter(gzWriter)
+	for _, name := range names {
+		header := &tar.Header{Name: name, Mode: 0755, Typeflag: tar.TypeDir}
+		content, isFile := files[name]
+		if isFile {
+			header = &tar.Header{Name: name, Mode: 0644, Typeflag: tar.TypeReg, Size: int64(len(content))}
+			if path.Base(path.Dir(name)) == "bin" {
+				header.Mode = 0755
+			}
+		}
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return nil, fmt.Errorf("failed to write tar header for %s: %w", name, err)
+		}
+		if isFile {
+			if _, err := tarWriter.Write([]byte(content)); err != nil {
+				return nil, fmt.Errorf("failed to write %s to tar: %w", name, err)
+			}
+		}
+	}
+	if err := tarWriter.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close tar: %w", err)
+	}
+	if err := gzWriter.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close gzip: %w", err)
+	}
+	return buffer.Bytes(), nil
+}
+
+// Adds a shell script to the front of the PATH until Cleanup is called, so commands like npm or pip
+// can be replaced with ones that record their arguments instead of going to the network
+func (harness *Harness) AddFakeCommand(name string, script string) error {
+	binPath := filepath.Join(harness.rootPath, "fake-bin")
+	if err := os.MkdirAll(binPath, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", binPath, err)
+	}
+	if err := os.WriteFile(filepath.Join(binPath, name), []byte("#!/bin/sh\n"+script+"\n"), 0755); err != nil {
+		return fmt.Errorf("failed to write fake %s command: %w", name, err)
+	}
+	if !strings.HasPrefix(os.Getenv("PATH"), binPath+string(filepath.ListSeparator)) {
+		harness.Setenv("PATH", binPath+string(filepath.ListSeparator)+os.Getenv("PATH"))
+	}
+	return nil
+}
@@ -0,0 +1,325 @@
+// Utilities for exercising buildpack detectors and builders in plain "go test" without pack or Docker.
+// A Harness copies a fixture application folder to a temp location, creates temp layers and platform
+// folders, and then invokes a libcnb.Detector or libcnb.Builder much like libcnb.Main would.
+package harness
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/buildpacks/libcnb"
+	"github.com/chuxel/devpacks/internal/common/devcontainer"
+	"github.com/chuxel/devpacks/internal/common/utils"
+)
+
+const DEFAULT_STACK_ID = "com.chuxel.stacks.test.bionic"
+
+// Relative to the module root
+const TEST_ASSETS_RELATIVE_PATH = "test/assets"
+const TEST_PROJECT_RELATIVE_PATH = "test/test-project"
+
+type Harness struct {
+	Buildpack       libcnb.Buildpack
+	ApplicationPath string
+	LayersPath      string
+	PlatformPath    string
+	BuildMode       string
+	StackID         string
+	rootPath        string
+	envToRestore    map[string]*string
+	onCleanup       []func()
+}
+
+// Output from running a builder along with the layers contributed by it
+type BuildOutput struct {
+	Result libcnb.BuildResult
+	Layers []libcnb.Layer
+}
+
+// Creates a harness for the buildpack using the buildpack.toml from test/assets/<buildpackName> and a copy
+// of the fixture application folder. A relative fixture path is resolved against the module root.
+func NewHarness(buildpackName string, fixturePath string) (*Harness, error) {
+	moduleRoot, err := ModuleRoot()
+	if err != nil {
+		return nil, err
+	}
+	rootPath, err := os.MkdirTemp("", "devpacks-harness-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp folder: %w", err)
+	}
+	harness := &Harness{
+		ApplicationPath: filepath.Join(rootPath, "workspace"),
+		LayersPath:      filepath.Join(rootPath, "layers"),
+		PlatformPath:    filepath.Join(rootPath, "platform"),
+		BuildMode:       devcontainer.DEFAULT_CONTAINER_IMAGE_BUILD_MODE,
+		StackID:         DEFAULT_STACK_ID,
+		rootPath:        rootPath,
+		envToRestore:    make(map[string]*string),
+	}
+	for _, folder := range []string{harness.ApplicationPath, harness.LayersPath, filepath.Join(harness.PlatformPath, "env")} {
+		if err := os.MkdirAll(folder, 0755); err != nil {
+			harness.Cleanup()
+			return nil, fmt.Errorf("failed to create %s: %w", folder, err)
+		}
+	}
+
+	// Load buildpack.toml
+	buildpackPath := filepath.Join(moduleRoot, TEST_ASSETS_RELATIVE_PATH, buildpackName)
+	if _, err := toml.DecodeFile(filepath.Join(buildpackPath, "buildpack.toml"), &harness.Buildpack); err != nil {
+		harness.Cleanup()
+		return nil, fmt.Errorf("failed to read buildpack.toml in %s: %w", buildpackPath, err)
+	}
+	harness.Buildpack.Path = buildpackPath
+
+	// Copy fixture contents so builders that modify the application folder (e.g. finalize) do not alter it
+	if fixturePath != "" {
+		if !filepath.IsAbs(fixturePath) {
+			fixturePath = filepath.Join(moduleRoot, fixturePath)
+		}
+		entries, err := os.ReadDir(fixturePath)
+		if err != nil {
+			harness.Cleanup()
+			return nil, fmt.Errorf("failed to read fixture folder %s: %w", fixturePath, err)
+		}
+		for _, entry := range entries {
+			if err := utils.CpR(filepath.Join(fixturePath, entry.Name()), harness.ApplicationPath); err != nil {
+				harness.Cleanup()
+				return nil, fmt.Errorf("failed to copy fixture %s: %w", fixturePath, err)
+			}
+		}
+	}
+	return harness, nil
+}
+
+// Removes temp folders and restores any env vars changed by the harness
+func (harness *Harness) Cleanup() {
+	for _, cleanup := range harness.onCleanup {
+		cleanup()
+	}
+	harness.onCleanup = nil
+	for name, value := range harness.envToRestore {
+		if value == nil {
+			os.Unsetenv(name)
+		} else {
+			os.Setenv(name, *value)
+		}
+	}
+	harness.envToRestore = make(map[string]*string)
+	utils.ResetMirrors()
+	os.RemoveAll(harness.rootPath)
+}
+
+// Sets an env var for the process until Cleanup is called
+func (harness *Harness) Setenv(name string, value string) {
+	if _, saved := harness.envToRestore[name]; !saved {
+		if existing, hasValue := os.LookupEnv(name); hasValue {
+			harness.envToRestore[name] = &existing
+		} else {
+			harness.envToRestore[name] = nil
+		}
+	}
+	os.Setenv(name, value)
+	// Mirrors are read from env vars and bindings once per process
+	utils.ResetMirrors()
+}
+
+// Adds a file to the platform env folder for code that reads libcnb.Platform.Environment. The lifecycle
+// also passes these to buildpacks as env vars, which is how the buildpacks here read BP_* settings, so
+// use Setenv for those instead.
+func (harness *Harness) SetPlatformEnv(name string, value string) error {
+	return utils.WriteFile(filepath.Join(harness.PlatformPath, "env", name), []byte(value))
+}
+
+// Adds a binding of the given type with the specified files to the platform bindings folder
+func (harness *Harness) AddBinding(name string, bindingType string, files map[string]string) error {
+	bindingPath := filepath.Join(harness.PlatformPath, "bindings", name)
+	if err := os.MkdirAll(bindingPath, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", bindingPath, err)
+	}
+	files["type"] = bindingType
+	for filename, content := range files {
+		if err := utils.WriteFile(filepath.Join(bindingPath, filename), []byte(content)); err != nil {
+			return err
+		}
+	}
+	harness.Setenv("SERVICE_BINDING_ROOT", filepath.Join(harness.PlatformPath, "bindings"))
+	return nil
+}
+
+func (harness *Harness) DetectContext() libcnb.DetectContext {
+	return libcnb.DetectContext{
+		Application: libcnb.Application{Path: harness.ApplicationPath},
+		Buildpack:   harness.Buildpack,
+		Platform:    harness.platform(),
+		StackID:     harness.StackID,
+	}
+}
+
+func (harness *Harness) BuildContext(plan libcnb.BuildpackPlan) libcnb.BuildContext {
+	return libcnb.BuildContext{
+		Application:        libcnb.Application{Path: harness.ApplicationPath},
+		Buildpack:          harness.Buildpack,
+		Layers:             libcnb.Layers{Path: harness.LayersPath},
+		PersistentMetadata: map[string]interface{}{},
+		Plan:               plan,
+		Platform:           harness.platform(),
+		StackID:            harness.StackID,
+	}
+}
+
+// Reads buildpack-plan.toml from the buildpack's test assets folder
+func (harness *Harness) DefaultPlan() (libcnb.BuildpackPlan, error) {
+	var plan libcnb.BuildpackPlan
+	planPath := filepath.Join(harness.Buildpack.Path, "buildpack-plan.toml")
+	if _, err := toml.DecodeFile(planPath, &plan); err != nil {
+		return plan, fmt.Errorf("failed to read %s: %w", planPath, err)
+	}
+	return plan, nil
+}
+
+func (harness *Harness) Detect(detector libcnb.Detector) (libcnb.DetectResult, error) {
+	harness.Setenv(devcontainer.CONTAINER_IMAGE_BUILD_MODE_ENV_VAR_NAME, harness.BuildMode)
+	return detector.Detect(harness.DetectContext())
+}
+
+// Runs the builder and then contributes each layer in the result, writing layer metadata and env
+// files the same way libcnb does so later builds (or other buildpacks) can use them.
+func (harness *Harness) Build(builder libcnb.Builder, plan libcnb.BuildpackPlan) (BuildOutput, error) {
+	harness.Setenv(devcontainer.CONTAINER_IMAGE_BUILD_MODE_ENV_VAR_NAME, harness.BuildMode)
+	context := harness.BuildContext(plan)
+	output := BuildOutput{}
+	result, err := builder.Build(context)
+	if err != nil {
+		return output, err
+	}
+	output.Result = result
+	for _, contributor := range result.Layers {
+		layer, err := context.Layers.Layer(contributor.Name())
+		if err != nil {
+			return output, fmt.Errorf("failed to load layer %s: %w", contributor.Name(), err)
+		}
+		layer, err = contributor.Contribute(layer)
+		if err != nil {
+			return output, fmt.Errorf("failed to contribute layer %s: %w", contributor.Name(), err)
+		}
+		if err := writeLayer(harness.LayersPath, layer); err != nil {
+			return output, err
+		}
+		output.Layers = append(output.Layers, layer)
+	}
+	return output, nil
+}
+
+// Applies the shared and build env of the layers to the process like the lifecycle does for
+// subsequent buildpacks (e.g. so finalize sees FINALIZE_JSON_SEARCH_PATH)
+func (harness *Harness) ApplyBuildEnvironment(layers []libcnb.Layer) {
+	for _, layer := range layers {
+		harness.applyEnvironment(layer.SharedEnvironment)
+		harness.applyEnvironment(layer.BuildEnvironment)
+	}
+}
+
+func (harness *Harness) applyEnvironment(environment libcnb.Environment) {
+	for key, value := range environment {
+		nameAction := strings.SplitN(key, ".", 2)
+		if len(nameAction) != 2 || nameAction[1] == "delim" {
+			continue
+		}
+		name := nameAction[0]
+		existing, hasExisting := os.LookupEnv(name)
+		delim := environment[name+".delim"]
+		switch nameAction[1] {
+		case "override":
+			harness.Setenv(name, value)
+		case "default":
+			if !hasExisting {
+				harness.Setenv(name, value)
+			}
+		case "append":
+			if hasExisting && existing != "" {
+				value = existing + delim + value
+			}
+			harness.Setenv(name, value)
+		case "prepend":
+			if hasExisting && existing != "" {
+				value = value + delim + existing
+			}
+			harness.Setenv(name, value)
+		}
+	}
+}
+
+// Loads a layer from the layers folder including any metadata written by a previous build
+func (harness *Harness) Layer(name string) (libcnb.Layer, error) {
+	layers := libcnb.Layers{Path: harness.LayersPath}
+	return layers.Layer(name)
+}
+
+func (harness *Harness) platform() libcnb.Platform {
+	platform := libcnb.Platform{
+		Path:        harness.PlatformPath,
+		Bindings:    libcnb.Bindings{},
+		Environment: map[string]string{},
+	}
+	envPath := filepath.Join(harness.PlatformPath, "env")
+	if entries, err := os.ReadDir(envPath); err == nil {
+		for _, entry := range entries {
+			if content, err := os.ReadFile(filepath.Join(envPath, entry.Name())); err == nil {
+				platform.Environment[entry.Name()] = string(content)
+			}
+		}
+	}
+	return platform
+}
+
+func writeLayer(layersPath string, layer libcnb.Layer) error {
+	envFolders := map[string]libcnb.Environment{
+		"env":        layer.SharedEnvironment,
+		"env.build":  layer.BuildEnvironment,
+		"env.launch": layer.LaunchEnvironment,
+	}
+	for folder, environment := range envFolders {
+		if len(environment) == 0 {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Join(layer.Path, folder), 0755); err != nil {
+			return fmt.Errorf("failed to create %s folder for layer %s: %w", folder, layer.Name, err)
+		}
+		for key, value := range environment {
+			if err := utils.WriteFile(filepath.Join(layer.Path, folder, key), []byte(value)); err != nil {
+				return err
+			}
+		}
+	}
+	layerTomlPath := filepath.Join(layersPath, layer.Name+".toml")
+	layerToml, err := os.Create(layerTomlPath)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", layerTomlPath, err)
+	}
+	defer layerToml.Close()
+	if err := toml.NewEncoder(layerToml).Encode(layer); err != nil {
+		return fmt.Errorf("failed to write %s: %w", layerTomlPath, err)
+	}
+	return nil
+}
+
+// Finds the root of the module by looking for go.mod in the current folder or its parents
+func ModuleRoot() (string, error) {
+	folder, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get working directory: %w", err)
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(folder, "go.mod")); err == nil {
+			return folder, nil
+		}
+		parent := filepath.Dir(folder)
+		if parent == folder {
+			return "", fmt.Errorf("unable to find go.mod in working directory or its parents")
+		}
+		folder = parent
+	}
+}
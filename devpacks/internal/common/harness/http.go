@@ -0,0 +1,105 @@
+package harness
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/chuxel/devpacks/internal/common/utils"
+)
+
+// Canned response for a url
+type StubResponse struct {
+	StatusCode int
+	Body       []byte
+	Header     http.Header
+}
+
+// http.RoundTripper that serves canned responses instead of going to the network. Requests for
+// urls without a response get a 404 so unexpected downloads are easy to spot.
+type StubTransport struct {
+	Responses map[string]StubResponse
+	Requests  []*http.Request
+	mutex     sync.Mutex
+}
+
+func NewStubTransport() *StubTransport {
+	return &StubTransport{Responses: make(map[string]StubResponse)}
+}
+
+// Serves the content with a 200 for the url
+func (transport *StubTransport) AddBytes(url string, content []byte) {
+	transport.Responses[url] = StubResponse{StatusCode: http.StatusOK, Body: content}
+}
+
+// Serves the contents of a file with a 200 for the url
+func (transport *StubTransport) AddFile(url string, filePath string) error {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", filePath, err)
+	}
+	transport.AddBytes(url, content)
+	return nil
+}
+
+// Serves each file in the folder under the base url (e.g. a fixture copy of a release folder)
+func (transport *StubTransport) AddFolder(baseUrl string, folderPath string) error {
+	return filepath.WalkDir(folderPath, func(filePath string, entry os.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		relativePath, err := filepath.Rel(folderPath, filePath)
+		if err != nil {
+			return err
+		}
+		return transport.AddFile(baseUrl+"/"+filepath.ToSlash(relativePath), filePath)
+	})
+}
+
+// Urls requested so far, in order
+func (transport *StubTransport) RequestedUrls() []string {
+	transport.mutex.Lock()
+	defer transport.mutex.Unlock()
+	urls := make([]string, len(transport.Requests))
+	for i, request := range transport.Requests {
+		urls[i] = request.URL.String()
+	}
+	return urls
+}
+
+// Implementation of http.RoundTripper.RoundTrip
+func (transport *StubTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	transport.mutex.Lock()
+	transport.Requests = append(transport.Requests, request)
+	transport.mutex.Unlock()
+
+	stubResponse, hasResponse := transport.Responses[request.URL.String()]
+	if !hasResponse {
+		stubResponse = StubResponse{StatusCode: http.StatusNotFound}
+	}
+	header := http.Header{}
+	for key, values := range stubResponse.Header {
+		header[key] = values
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", stubResponse.StatusCode, http.StatusText(stubResponse.StatusCode)),
+		StatusCode:    stubResponse.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(stubResponse.Body)),
+		ContentLength: int64(len(stubResponse.Body)),
+		Request:       request,
+	}, nil
+}
+
+// Routes all downloads through the transport until Cleanup is called
+func (harness *Harness) UseStubTransport(transport *StubTransport) {
+	utils.SetHttpClient(&http.Client{Transport: transport})
+	harness.onCleanup = append(harness.onCleanup, func() { utils.SetHttpClient(nil) })
+}
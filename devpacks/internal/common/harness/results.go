@@ -0,0 +1,85 @@
+package harness
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/buildpacks/libcnb"
+	"github.com/chuxel/devpacks/internal/common/devcontainer"
+)
+
+// Returns true if any plan in the result provides the name
+func PlanProvides(result libcnb.DetectResult, name string) bool {
+	for _, plan := range result.Plans {
+		for _, provide := range plan.Provides {
+			if provide.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Returns the first requirement with the name from any plan in the result
+func PlanRequire(result libcnb.DetectResult, name string) (libcnb.BuildPlanRequire, bool) {
+	for _, plan := range result.Plans {
+		for _, require := range plan.Requires {
+			if require.Name == name {
+				return require, true
+			}
+		}
+	}
+	return libcnb.BuildPlanRequire{}, false
+}
+
+// Returns the contributed layer with the name
+func (output BuildOutput) Layer(name string) (libcnb.Layer, bool) {
+	for _, layer := range output.Layers {
+		if layer.Name == name {
+			return layer, true
+		}
+	}
+	return libcnb.Layer{}, false
+}
+
+// Returns the process with the type from the build result
+func (output BuildOutput) Process(processType string) (libcnb.Process, bool) {
+	for _, process := range output.Result.Processes {
+		if process.Type == processType {
+			return process, true
+		}
+	}
+	return libcnb.Process{}, false
+}
+
+// Reads an env file written for a layer. Scope is "env", "env.build" or "env.launch" and key is in
+// the form <NAME>.<action> (e.g. PATH.prepend).
+func ReadLayerEnvFile(layer libcnb.Layer, scope string, key string) (string, error) {
+	envFilePath := filepath.Join(layer.Path, scope, key)
+	content, err := os.ReadFile(envFilePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", envFilePath, err)
+	}
+	return string(content), nil
+}
+
+// Parses the devcontainer.metadata label from a build result (e.g. from finalize)
+func DevContainerMetadataLabel(result libcnb.BuildResult) ([]map[string]interface{}, error) {
+	for _, label := range result.Labels {
+		if label.Key == devcontainer.DEVCONTAINER_JSON_LABEL_NAME {
+			var metadata []map[string]interface{}
+			if err := json.Unmarshal([]byte(label.Value), &metadata); err != nil {
+				return nil, fmt.Errorf("failed to parse %s label: %w", devcontainer.DEVCONTAINER_JSON_LABEL_NAME, err)
+			}
+			return metadata, nil
+		}
+	}
+	return nil, fmt.Errorf("no %s label in build result", devcontainer.DEVCONTAINER_JSON_LABEL_NAME)
+}
+
+// Loads the devcontainer.json a buildpack wrote into one of its layers
+func LayerDevContainerJson(layer libcnb.Layer) (devcontainer.DevContainer, error) {
+	return devcontainer.NewDevContainer(layer.Path)
+}
@@ -0,0 +1,155 @@
+// Reads the list of dev container tools to install for each ecosystem (e.g. "go" or "python") from
+// .devpacks/tools.toml in the application folder. For example:
+//
+//	[go]
+//	remove = ["golang.org/x/lint/golint"]
+//
+//	[go.tools."golang.org/x/tools/gopls"]
+//	version = "v0.11.0"
+//
+//	[python.tools.ruff]
+//	version = "0.0.254"
+//	settings = { "python.linting.ruffPath" = "{{layerDir}}/bin/ruff" }
+//
+// Tools are added to (or override the version of) the defaults for the ecosystem, and tools in
+// "remove" are dropped from them (but are still added if they are also under "tools"). Set
+// include_defaults to false to only use the tools in the file.
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Location of the tools file relative to the application folder
+const TOOLS_FILE_RELATIVE_PATH = ".devpacks/tools.toml"
+
+type Tool struct {
+	Name string
+	// Version as written in the tools file or default spec. Empty means the latest version.
+	Version string
+	// VS Code settings to add to devcontainer.json when this tool is installed
+	Settings map[string]interface{}
+}
+
+type ToolConfig struct {
+	Version  string
+	Settings map[string]interface{}
+}
+
+type EcosystemConfig struct {
+	IncludeDefaults *bool `toml:"include_defaults"`
+	Remove          []string
+	Tools           map[string]ToolConfig
+}
+
+// Contents of .devpacks/tools.toml keyed by ecosystem
+type ToolsFile map[string]EcosystemConfig
+
+// Loads .devpacks/tools.toml from the application folder. Returns an empty ToolsFile if there isn't one.
+func LoadToolsFile(appPath string) (ToolsFile, error) {
+	toolsFile := ToolsFile{}
+	toolsFilePath := filepath.Join(appPath, TOOLS_FILE_RELATIVE_PATH)
+	if _, err := os.Stat(toolsFilePath); err != nil {
+		return toolsFile, nil
+	}
+	if _, err := toml.DecodeFile(toolsFilePath, &toolsFile); err != nil {
+		return toolsFile, fmt.Errorf("failed to parse %s: %w", TOOLS_FILE_RELATIVE_PATH, err)
+	}
+	return toolsFile, nil
+}
+
+// Returns true if the tools file has a section for the ecosystem
+func (toolsFile ToolsFile) HasEcosystem(ecosystem string) bool {
+	_, hasEcosystem := toolsFile[ecosystem]
+	return hasEcosystem
+}
+
+// Applies the ecosystem's section of the tools file to the default tool specs (e.g. from DEFAULT_GO_UTILS
+// or BP_GO_UTILS). parseSpec splits a spec like "pylint==2.13.9" into its name and version.
+func (toolsFile ToolsFile) ResolveTools(ecosystem string, defaultSpecs []string, parseSpec func(spec string) (string, string)) []Tool {
+	config := toolsFile[ecosystem]
+	removed := make(map[string]bool, len(config.Remove))
+	for _, name := range config.Remove {
+		removed[name] = true
+	}
+
+	resolved := []Tool{}
+	indexes := map[string]int{}
+	if config.IncludeDefaults == nil || *config.IncludeDefaults {
+		for _, spec := range defaultSpecs {
+			if spec == "" {
+				continue
+			}
+			name, version := parseSpec(spec)
+			if removed[name] {
+				continue
+			}
+			indexes[name] = len(resolved)
+			resolved = append(resolved, Tool{Name: name, Version: version})
+		}
+	}
+	// Sort so tools are always installed in the same order
+	names := make([]string, 0, len(config.Tools))
+	for name := range config.Tools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		toolConfig := config.Tools[name]
+		tool := Tool{Name: name, Version: toolConfig.Version, Settings: toolConfig.Settings}
+		if index, exists := indexes[name]; exists {
+			if tool.Version == "" {
+				tool.Version = resolved[index].Version
+			}
+			resolved[index] = tool
+			continue
+		}
+		indexes[name] = len(resolved)
+		resolved = append(resolved, tool)
+	}
+	return resolved
+}
+
+// Adds the settings from each tool to customizations.vscode.settings in the devcontainer.json content
+func AddToolSettings(devcontainerJsonBytes []byte, tools []Tool) ([]byte, error) {
+	hasSettings := false
+	for _, tool := range tools {
+		if len(tool.Settings) > 0 {
+			hasSettings = true
+		}
+	}
+	if !hasSettings {
+		return devcontainerJsonBytes, nil
+	}
+	var properties map[string]interface{}
+	if err := json.Unmarshal(devcontainerJsonBytes, &properties); err != nil {
+		return nil, fmt.Errorf("failed to parse devcontainer.json: %w", err)
+	}
+	settings := childMap(childMap(childMap(properties, "customizations"), "vscode"), "settings")
+	for _, tool := range tools {
+		for key, value := range tool.Settings {
+			settings[key] = value
+		}
+	}
+	updatedBytes, err := json.MarshalIndent(properties, "", "    ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal devcontainer.json: %w", err)
+	}
+	return updatedBytes, nil
+}
+
+// Returns the object at the key, adding an empty one if it does not exist
+func childMap(parent map[string]interface{}, key string) map[string]interface{} {
+	if child, isMap := parent[key].(map[string]interface{}); isMap {
+		return child
+	}
+	child := map[string]interface{}{}
+	parent[key] = child
+	return child
+}
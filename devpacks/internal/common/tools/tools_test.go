@@ -0,0 +1,170 @@
+package tools
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Splits name@version like goutils and nodeutils specs
+func parseTestSpec(spec string) (string, string) {
+	if at := strings.LastIndex(spec, "@"); at > 0 {
+		return spec[:at], spec[at+1:]
+	}
+	return spec, ""
+}
+
+func TestResolveTools(t *testing.T) {
+	defaultSpecs := []string{"gopls@latest", "dlv", "golint@v0.1.0", ""}
+	tests := []struct {
+		name      string
+		toolsToml string
+		expected  []Tool
+	}{
+		{
+			name:     "no tools file",
+			expected: []Tool{{Name: "gopls", Version: "latest"}, {Name: "dlv"}, {Name: "golint", Version: "v0.1.0"}},
+		},
+		{
+			name:      "other ecosystem only",
+			toolsToml: "[python.tools.ruff]\nversion = \"0.0.254\"\n",
+			expected:  []Tool{{Name: "gopls", Version: "latest"}, {Name: "dlv"}, {Name: "golint", Version: "v0.1.0"}},
+		},
+		{
+			name:      "without defaults",
+			toolsToml: "[go]\ninclude_defaults = false\n\n[go.tools.staticcheck]\nversion = \"2023.1.6\"\n",
+			expected:  []Tool{{Name: "staticcheck", Version: "2023.1.6"}},
+		},
+		{
+			name:      "remove",
+			toolsToml: "[go]\nremove = [\"golint\", \"not-a-default\"]\n",
+			expected:  []Tool{{Name: "gopls", Version: "latest"}, {Name: "dlv"}},
+		},
+		{
+			name:      "override version",
+			toolsToml: "[go.tools.gopls]\nversion = \"v0.11.0\"\n",
+			expected:  []Tool{{Name: "gopls", Version: "v0.11.0"}, {Name: "dlv"}, {Name: "golint", Version: "v0.1.0"}},
+		},
+		{
+			name:      "override settings keeps the default version",
+			toolsToml: "[go.tools.golint]\nsettings = { \"go.lintTool\" = \"golint\" }\n",
+			expected: []Tool{{Name: "gopls", Version: "latest"}, {Name: "dlv"},
+				{Name: "golint", Version: "v0.1.0", Settings: map[string]interface{}{"go.lintTool": "golint"}}},
+		},
+		{
+			name:      "added tools are sorted",
+			toolsToml: "[go]\ninclude_defaults = false\n\n[go.tools.zz]\n[go.tools.aa]\nversion = \"v1.0.0\"\n[go.tools.mm]\n",
+			expected:  []Tool{{Name: "aa", Version: "v1.0.0"}, {Name: "mm"}, {Name: "zz"}},
+		},
+		{
+			name:      "removed and listed",
+			toolsToml: "[go]\nremove = [\"golint\"]\n\n[go.tools.golint]\nversion = \"v0.2.0\"\n",
+			expected:  []Tool{{Name: "gopls", Version: "latest"}, {Name: "dlv"}, {Name: "golint", Version: "v0.2.0"}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			appPath := t.TempDir()
+			if test.toolsToml != "" {
+				if err := os.MkdirAll(filepath.Join(appPath, ".devpacks"), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(appPath, TOOLS_FILE_RELATIVE_PATH), []byte(test.toolsToml), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			toolsFile, err := LoadToolsFile(appPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resolved := toolsFile.ResolveTools("go", defaultSpecs, parseTestSpec); !reflect.DeepEqual(resolved, test.expected) {
+				t.Errorf("expected %+v, got %+v", test.expected, resolved)
+			}
+		})
+	}
+}
+
+func TestLoadToolsFileInvalid(t *testing.T) {
+	appPath := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(appPath, ".devpacks"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(appPath, TOOLS_FILE_RELATIVE_PATH), []byte("[go\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadToolsFile(appPath); err == nil {
+		t.Error("expected an error for an invalid tools file")
+	}
+}
+
+func TestAddToolSettings(t *testing.T) {
+	tools := []Tool{
+		{Name: "gopls", Settings: map[string]interface{}{"go.useLanguageServer": true}},
+		{Name: "golint", Settings: map[string]interface{}{"go.lintTool": "golint"}},
+		{Name: "dlv"},
+	}
+	tests := []struct {
+		name             string
+		devcontainerJson string
+		expectedSettings map[string]interface{}
+	}{
+		{
+			name:             "no existing settings",
+			devcontainerJson: `{"customizations": {"vscode": {"extensions": ["golang.go"]}}}`,
+			expectedSettings: map[string]interface{}{"go.useLanguageServer": true, "go.lintTool": "golint"},
+		},
+		{
+			name:             "existing settings",
+			devcontainerJson: `{"customizations": {"vscode": {"extensions": ["golang.go"], "settings": {"go.gopath": "/go", "go.lintTool": "staticcheck"}}}}`,
+			expectedSettings: map[string]interface{}{"go.gopath": "/go", "go.useLanguageServer": true, "go.lintTool": "golint"},
+		},
+		{
+			name:             "no customizations",
+			devcontainerJson: `{}`,
+			expectedSettings: map[string]interface{}{"go.useLanguageServer": true, "go.lintTool": "golint"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			updatedBytes, err := AddToolSettings([]byte(test.devcontainerJson), tools)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var properties struct {
+				Customizations struct {
+					Vscode struct {
+						Extensions []string
+						Settings   map[string]interface{}
+					}
+				}
+			}
+			if err := json.Unmarshal(updatedBytes, &properties); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(properties.Customizations.Vscode.Settings, test.expectedSettings) {
+				t.Errorf("expected settings %v, got %v", test.expectedSettings, properties.Customizations.Vscode.Settings)
+			}
+			if strings.Contains(test.devcontainerJson, "extensions") && len(properties.Customizations.Vscode.Extensions) != 1 {
+				t.Errorf("expected extensions to be kept, got %s", updatedBytes)
+			}
+		})
+	}
+}
+
+func TestAddToolSettingsWithoutSettings(t *testing.T) {
+	// Returned as-is so the formatting of the embedded devcontainer.json is kept
+	devcontainerJson := []byte("{\n  \"customizations\": {}\n}")
+	updatedBytes, err := AddToolSettings(devcontainerJson, []Tool{{Name: "dlv"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(updatedBytes) != string(devcontainerJson) {
+		t.Errorf("expected devcontainer.json to be unchanged, got %s", updatedBytes)
+	}
+	if _, err := AddToolSettings([]byte("{"), []Tool{{Name: "gopls", Settings: map[string]interface{}{"a": "b"}}}); err == nil {
+		t.Error("expected an error for invalid devcontainer.json")
+	}
+}
@@ -0,0 +1,469 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Download behaviors can be tuned using these env vars. Durations use Go syntax (e.g. 30s, 2m).
+const DOWNLOAD_RETRIES_ENV_VAR_NAME = "BP_DEVPACKS_DOWNLOAD_RETRIES"
+const DOWNLOAD_RETRY_DELAY_ENV_VAR_NAME = "BP_DEVPACKS_DOWNLOAD_RETRY_DELAY"
+const DOWNLOAD_TIMEOUT_ENV_VAR_NAME = "BP_DEVPACKS_DOWNLOAD_TIMEOUT"
+const DOWNLOAD_PROGRESS_INTERVAL_ENV_VAR_NAME = "BP_DEVPACKS_DOWNLOAD_PROGRESS_INTERVAL"
+
+const DEFAULT_DOWNLOAD_RETRIES = 3
+const DEFAULT_DOWNLOAD_RETRY_DELAY = 2 * time.Second
+const DEFAULT_DOWNLOAD_TIMEOUT = 30 * time.Second
+const DEFAULT_DOWNLOAD_PROGRESS_INTERVAL = 10 * time.Second
+
+const PARTIAL_DOWNLOAD_SUFFIX = ".partial"
+
+// Added to the partial download's path to store the validators of the response it came from, so it is
+// only resumed if the file on the server is unchanged
+const PARTIAL_VALIDATORS_SUFFIX = ".validators"
+
+// Response headers used to revalidate a previous download with a conditional GET
+type DownloadValidators struct {
+	ETag         string
+	LastModified string
+}
+
+// Value for an If-Range header, which requires a strong ETag or a date. Empty if there is neither.
+func (validators DownloadValidators) ifRange() string {
+	if validators.ETag != "" && !strings.HasPrefix(validators.ETag, "W/") {
+		return validators.ETag
+	}
+	return validators.LastModified
+}
+
+type DownloadStatusError struct {
+	Url        string
+	StatusCode int
+}
+
+func (err DownloadStatusError) Error() string {
+	return "Got status code " + strconv.Itoa(err.StatusCode) + " for " + err.Url
+}
+
+// Server errors and throttling are worth retrying, but other status codes are not
+func (err DownloadStatusError) Transient() bool {
+	return err.StatusCode >= 500 || err.StatusCode == http.StatusTooManyRequests
+}
+
+var cachedHttpClient *http.Client = nil
+
+func httpClient() *http.Client {
+	if cachedHttpClient == nil {
+		// Time out connecting and waiting for a response here, while openUrl times out reading a body
+		// that stalls. There is no overall timeout since large downloads can take a while.
+		timeout := durationFromEnv(DOWNLOAD_TIMEOUT_ENV_VAR_NAME, DEFAULT_DOWNLOAD_TIMEOUT)
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.DialContext = (&net.Dialer{Timeout: timeout}).DialContext
+		transport.TLSHandshakeTimeout = timeout
+		transport.ResponseHeaderTimeout = timeout
+		cachedHttpClient = &http.Client{Transport: transport}
+	}
+	return cachedHttpClient
+}
+
+// Replaces the client used for all downloads (e.g. with one using a stub transport in tests). Passing
+// nil restores the default client.
+func SetHttpClient(client *http.Client) {
+	cachedHttpClient = client
+}
+
+func DownloadBytesFromUrl(dlUrl string) ([]byte, error) {
+	var outBytes []byte
+	_, err := StreamFromUrl(dlUrl, func(reader io.Reader) error {
+		var err error
+		outBytes, err = io.ReadAll(reader)
+		return err
+	})
+	return outBytes, err
+}
+
+// Opens the url (with retries) and passes the response body to the handler without buffering
+// it in memory. Returns the sha256 of everything the handler read.
+func StreamFromUrl(dlUrl string, handler func(reader io.Reader) error) (string, error) {
+	var digest string
+	err := withRetries(dlUrl, func() error {
+		body, size, err := openUrl(dlUrl, 0, DownloadValidators{}, "")
+		if err != nil {
+			return err
+		}
+		defer body.Close()
+		hashGen := sha256.New()
+		bodyReader := &errorRecordingReader{reader: body}
+		reader := io.TeeReader(newProgressReader(bodyReader, dlUrl, 0, size), hashGen)
+		if err := handler(reader); err != nil {
+			if bodyReader.err != nil {
+				// The connection failed, so try again
+				return fmt.Errorf("failed to download %s: %w", dlUrl, bodyReader.err)
+			}
+			// Errors from the handler (e.g. a corrupt archive) should not be retried
+			return permanentError{err}
+		}
+		digest = hex.EncodeToString(hashGen.Sum(nil))
+		return nil
+	})
+	return digest, err
+}
+
+// Downloads the url to the target file, resuming from a previous partial download if one exists. The
+// download is skipped if the validators from a previous download show the file has not changed.
+// Returns the validators for the file, whether it was downloaded, and the sha256 of the downloaded file.
+func DownloadToFileIfModified(dlUrl string, targetFilePath string, validators DownloadValidators) (DownloadValidators, bool, string, error) {
+	if err := os.MkdirAll(filepath.Dir(targetFilePath), 0755); err != nil {
+		return validators, false, "", fmt.Errorf("unable to create folder for %s: %w", targetFilePath, err)
+	}
+	partialFilePath := targetFilePath + PARTIAL_DOWNLOAD_SUFFIX
+	partialValidatorsPath := partialFilePath + PARTIAL_VALIDATORS_SUFFIX
+	var digest string
+	notModified := false
+	err := withRetries(dlUrl, func() error {
+		file, err := os.OpenFile(partialFilePath, os.O_CREATE|os.O_RDWR, 0644)
+		if err != nil {
+			return permanentError{fmt.Errorf("failed to open %s: %w", partialFilePath, err)}
+		}
+		defer file.Close()
+
+		// Hash what is already there so the result covers the whole file
+		hashGen := sha256.New()
+		offset, err := io.Copy(hashGen, file)
+		if err != nil {
+			return permanentError{fmt.Errorf("failed to read %s: %w", partialFilePath, err)}
+		}
+		conditionalValidators := DownloadValidators{}
+		rangeValidator := ""
+		if offset == 0 {
+			conditionalValidators = validators
+		} else {
+			rangeValidator = readPartialValidators(partialValidatorsPath).ifRange()
+			if rangeValidator == "" {
+				// Without a validator there is no way to know the partial download has the same contents
+				log.Println("Unable to resume download of", dlUrl, "- starting over")
+				if err := resetFile(file); err != nil {
+					return permanentError{err}
+				}
+				hashGen = sha256.New()
+				offset = 0
+			}
+		}
+		body, size, err := openUrl(dlUrl, offset, conditionalValidators, rangeValidator)
+		if err != nil {
+			return err
+		}
+		defer body.Close()
+		if body.notModified {
+			notModified = true
+			return nil
+		}
+		validators = body.validators
+		if offset > 0 && body.resumed {
+			log.Println("Resuming download of", dlUrl, "at byte", offset)
+		} else if offset > 0 {
+			// Server did not honor the range request or the file changed, so start over
+			if err := resetFile(file); err != nil {
+				return permanentError{err}
+			}
+			hashGen = sha256.New()
+			offset = 0
+		}
+		if offset == 0 {
+			if err := writePartialValidators(partialValidatorsPath, body.validators); err != nil {
+				return permanentError{err}
+			}
+		}
+		reader := newProgressReader(body, dlUrl, offset, size)
+		if _, err := io.Copy(io.MultiWriter(file, hashGen), reader); err != nil {
+			return fmt.Errorf("failed to download %s: %w", dlUrl, err)
+		}
+		digest = hex.EncodeToString(hashGen.Sum(nil))
+		return nil
+	})
+	if err != nil {
+		return validators, false, "", err
+	}
+	os.Remove(partialValidatorsPath)
+	if notModified {
+		os.Remove(partialFilePath)
+		return validators, false, "", nil
+	}
+	if err := os.Rename(partialFilePath, targetFilePath); err != nil {
+		return validators, false, "", fmt.Errorf("failed to move %s to %s: %w", partialFilePath, targetFilePath, err)
+	}
+	return validators, true, digest, nil
+}
+
+type urlBody struct {
+	io.ReadCloser
+	// Whether the body starts at the requested offset rather than the start of the file
+	resumed bool
+	// Whether the validators passed in show the contents are unchanged. There is no body if so.
+	notModified bool
+	validators  DownloadValidators
+}
+
+// Opens the (mirrored) url starting at the specified offset and returns the body and total size (-1 if
+// unknown). The rangeValidator (see DownloadValidators.ifRange) is used to only get the rest of the file
+// if it has not changed, and otherwise the whole file is returned with resumed set to false.
+func openUrl(dlUrl string, offset int64, validators DownloadValidators, rangeValidator string) (urlBody, int64, error) {
+	dlUrl, err := MirrorUrl(dlUrl)
+	if err != nil {
+		return urlBody{}, -1, permanentError{err}
+	}
+
+	// Allow a local folder to stand in for a remote server
+	if parsedUrl, err := url.Parse(dlUrl); err == nil && parsedUrl.Scheme == "file" {
+		file, err := os.Open(filepath.FromSlash(parsedUrl.Path))
+		if err != nil {
+			return urlBody{}, -1, permanentError{fmt.Errorf("failed to read %s: %w", dlUrl, err)}
+		}
+		size := int64(-1)
+		fileValidators := DownloadValidators{}
+		if fileInfo, err := file.Stat(); err == nil {
+			size = fileInfo.Size()
+			fileValidators.LastModified = fileInfo.ModTime().UTC().Format(http.TimeFormat)
+		}
+		if validators.LastModified != "" && validators.LastModified == fileValidators.LastModified {
+			file.Close()
+			return urlBody{ReadCloser: io.NopCloser(bytes.NewReader(nil)), notModified: true, validators: validators}, 0, nil
+		}
+		resumed := offset > 0 && rangeValidator == fileValidators.LastModified
+		if !resumed {
+			offset = 0
+		}
+		if _, err := file.Seek(offset, io.SeekStart); err != nil {
+			file.Close()
+			return urlBody{}, -1, permanentError{fmt.Errorf("failed to seek in %s: %w", dlUrl, err)}
+		}
+		return urlBody{ReadCloser: file, resumed: resumed, validators: fileValidators}, size, nil
+	}
+
+	request, err := http.NewRequest(http.MethodGet, dlUrl, nil)
+	if err != nil {
+		return urlBody{}, -1, permanentError{fmt.Errorf("invalid url %s: %w", dlUrl, err)}
+	}
+	if offset > 0 {
+		request.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+		request.Header.Set("If-Range", rangeValidator)
+	}
+	if validators.ETag != "" {
+		request.Header.Set("If-None-Match", validators.ETag)
+	}
+	if validators.LastModified != "" {
+		request.Header.Set("If-Modified-Since", validators.LastModified)
+	}
+	response, err := httpClient().Do(request)
+	if err != nil {
+		return urlBody{}, -1, fmt.Errorf("failed to download %s: %w", dlUrl, err)
+	}
+	responseValidators := DownloadValidators{
+		ETag:         response.Header.Get("ETag"),
+		LastModified: response.Header.Get("Last-Modified"),
+	}
+	response.Body = newIdleTimeoutBody(response.Body, durationFromEnv(DOWNLOAD_TIMEOUT_ENV_VAR_NAME, DEFAULT_DOWNLOAD_TIMEOUT))
+	switch response.StatusCode {
+	case http.StatusOK:
+		return urlBody{ReadCloser: response.Body, validators: responseValidators}, response.ContentLength, nil
+	case http.StatusPartialContent:
+		size := int64(-1)
+		if response.ContentLength >= 0 {
+			size = offset + response.ContentLength
+		}
+		return urlBody{ReadCloser: response.Body, resumed: true, validators: responseValidators}, size, nil
+	case http.StatusNotModified:
+		response.Body.Close()
+		return urlBody{ReadCloser: io.NopCloser(bytes.NewReader(nil)), notModified: true, validators: validators}, 0, nil
+	case http.StatusRequestedRangeNotSatisfiable:
+		// Happens when a previous attempt already got the whole file
+		if offset > 0 {
+			response.Body.Close()
+			return urlBody{ReadCloser: io.NopCloser(bytes.NewReader(nil)), resumed: true, validators: responseValidators}, offset, nil
+		}
+	}
+	response.Body.Close()
+	statusErr := DownloadStatusError{Url: dlUrl, StatusCode: response.StatusCode}
+	if statusErr.Transient() {
+		return urlBody{}, -1, statusErr
+	}
+	return urlBody{}, -1, permanentError{statusErr}
+}
+
+// Wraps errors that should not be retried
+type permanentError struct {
+	err error
+}
+
+func (err permanentError) Error() string {
+	return err.err.Error()
+}
+
+func (err permanentError) Unwrap() error {
+	return err.err
+}
+
+func withRetries(dlUrl string, action func() error) error {
+	retries := intFromEnv(DOWNLOAD_RETRIES_ENV_VAR_NAME, DEFAULT_DOWNLOAD_RETRIES)
+	delay := durationFromEnv(DOWNLOAD_RETRY_DELAY_ENV_VAR_NAME, DEFAULT_DOWNLOAD_RETRY_DELAY)
+	for attempt := 0; ; attempt++ {
+		err := action()
+		if err == nil {
+			return nil
+		}
+		var permanentErr permanentError
+		if errors.As(err, &permanentErr) {
+			return permanentErr.err
+		}
+		if attempt >= retries {
+			return err
+		}
+		log.Println("Download of", dlUrl, "failed, retrying in", delay, "-", err)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// Reads the validators stored for a partial download, which are empty if there are none
+func readPartialValidators(partialValidatorsPath string) DownloadValidators {
+	var validators DownloadValidators
+	if content, err := os.ReadFile(partialValidatorsPath); err == nil {
+		json.Unmarshal(content, &validators)
+	}
+	return validators
+}
+
+func writePartialValidators(partialValidatorsPath string, validators DownloadValidators) error {
+	content, err := json.Marshal(validators)
+	if err != nil {
+		return fmt.Errorf("failed to convert validators to json: %w", err)
+	}
+	return WriteFile(partialValidatorsPath, content)
+}
+
+func resetFile(file *os.File) error {
+	if err := file.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate %s: %w", file.Name(), err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek in %s: %w", file.Name(), err)
+	}
+	return nil
+}
+
+// Remembers the last error reading from the reader so connection failures can be told apart from
+// errors returned by a handler
+type errorRecordingReader struct {
+	reader io.Reader
+	err    error
+}
+
+func (recorder *errorRecordingReader) Read(buffer []byte) (int, error) {
+	count, err := recorder.reader.Read(buffer)
+	if err != nil && err != io.EOF {
+		recorder.err = err
+	}
+	return count, err
+}
+
+// Closes the body if no data arrives within the timeout so a stalled connection fails (and is
+// retried) instead of hanging the build
+type idleTimeoutBody struct {
+	body     io.ReadCloser
+	timeout  time.Duration
+	timer    *time.Timer
+	timedOut int32
+}
+
+func newIdleTimeoutBody(body io.ReadCloser, timeout time.Duration) *idleTimeoutBody {
+	idleBody := &idleTimeoutBody{body: body, timeout: timeout}
+	idleBody.timer = time.AfterFunc(timeout, func() {
+		atomic.StoreInt32(&idleBody.timedOut, 1)
+		body.Close()
+	})
+	return idleBody
+}
+
+func (idleBody *idleTimeoutBody) Read(buffer []byte) (int, error) {
+	// Only time spent waiting for data counts, not time spent by the caller between reads
+	idleBody.timer.Reset(idleBody.timeout)
+	count, err := idleBody.body.Read(buffer)
+	idleBody.timer.Stop()
+	if err != nil && atomic.LoadInt32(&idleBody.timedOut) == 1 {
+		err = fmt.Errorf("no data received for %s: %w", idleBody.timeout, err)
+	}
+	return count, err
+}
+
+func (idleBody *idleTimeoutBody) Close() error {
+	idleBody.timer.Stop()
+	return idleBody.body.Close()
+}
+
+// Logs download progress at an interval
+type progressReader struct {
+	reader   io.Reader
+	dlUrl    string
+	read     int64
+	size     int64
+	interval time.Duration
+	lastLog  time.Time
+}
+
+func newProgressReader(reader io.Reader, dlUrl string, offset int64, size int64) *progressReader {
+	return &progressReader{
+		reader:   reader,
+		dlUrl:    dlUrl,
+		read:     offset,
+		size:     size,
+		interval: durationFromEnv(DOWNLOAD_PROGRESS_INTERVAL_ENV_VAR_NAME, DEFAULT_DOWNLOAD_PROGRESS_INTERVAL),
+		lastLog:  time.Now(),
+	}
+}
+
+func (progress *progressReader) Read(buffer []byte) (int, error) {
+	count, err := progress.reader.Read(buffer)
+	progress.read += int64(count)
+	if time.Since(progress.lastLog) >= progress.interval {
+		progress.lastLog = time.Now()
+		if progress.size > 0 {
+			log.Printf("Downloaded %.1f of %.1f MB (%d%%) from %s", megabytes(progress.read), megabytes(progress.size), progress.read*100/progress.size, progress.dlUrl)
+		} else {
+			log.Printf("Downloaded %.1f MB from %s", megabytes(progress.read), progress.dlUrl)
+		}
+	}
+	return count, err
+}
+
+func megabytes(byteCount int64) float64 {
+	return float64(byteCount) / (1024 * 1024)
+}
+
+func intFromEnv(envVarName string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(envVarName)); err == nil && value >= 0 {
+		return value
+	}
+	return defaultValue
+}
+
+func durationFromEnv(envVarName string, defaultValue time.Duration) time.Duration {
+	if value, err := time.ParseDuration(os.Getenv(envVarName)); err == nil && value > 0 {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,160 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDownloadToFileResume(t *testing.T) {
+	content := []byte(strings.Repeat("0123456789", 100))
+	tests := []struct {
+		name string
+		// Validators stored with the partial download, if any
+		partialValidators *DownloadValidators
+		partialContent    []byte
+		expectedIfRange   string
+		expectedRange     string
+	}{
+		{
+			name:              "resumes when the file is unchanged",
+			partialValidators: &DownloadValidators{ETag: `"v1"`},
+			partialContent:    content[:400],
+			expectedIfRange:   `"v1"`,
+			expectedRange:     "bytes=400-",
+		},
+		{
+			name:              "starts over when the file changed",
+			partialValidators: &DownloadValidators{ETag: `"old"`},
+			partialContent:    []byte(strings.Repeat("x", 400)),
+			expectedIfRange:   `"old"`,
+			expectedRange:     "bytes=400-",
+		},
+		{
+			name:           "starts over without validators",
+			partialContent: []byte(strings.Repeat("x", 400)),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var ifRange, rangeHeader string
+			server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				ifRange, rangeHeader = request.Header.Get("If-Range"), request.Header.Get("Range")
+				writer.Header().Set("ETag", `"v1"`)
+				// ServeContent honors Range and If-Range
+				http.ServeContent(writer, request, "file", time.Time{}, bytes.NewReader(content))
+			}))
+			defer server.Close()
+
+			targetFilePath := filepath.Join(t.TempDir(), "file")
+			partialFilePath := targetFilePath + PARTIAL_DOWNLOAD_SUFFIX
+			if err := WriteFile(partialFilePath, test.partialContent); err != nil {
+				t.Fatal(err)
+			}
+			if test.partialValidators != nil {
+				if err := writePartialValidators(partialFilePath+PARTIAL_VALIDATORS_SUFFIX, *test.partialValidators); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			_, modified, _, err := DownloadToFileIfModified(server.URL+"/file", targetFilePath, DownloadValidators{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !modified {
+				t.Fatal("expected file to be downloaded")
+			}
+			if ifRange != test.expectedIfRange || rangeHeader != test.expectedRange {
+				t.Errorf("expected If-Range %q and Range %q, got %q and %q", test.expectedIfRange, test.expectedRange, ifRange, rangeHeader)
+			}
+			downloaded, err := os.ReadFile(targetFilePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(downloaded, content) {
+				t.Errorf("downloaded file does not match, got %q", downloaded)
+			}
+			if _, err := os.Stat(partialFilePath + PARTIAL_VALIDATORS_SUFFIX); !os.IsNotExist(err) {
+				t.Error("expected partial validators to be removed")
+			}
+		})
+	}
+}
+
+func TestStreamFromUrlRetries(t *testing.T) {
+	t.Setenv(DOWNLOAD_RETRY_DELAY_ENV_VAR_NAME, "1ms")
+	t.Setenv(DOWNLOAD_TIMEOUT_ENV_VAR_NAME, "100ms")
+	handlerErr := errors.New("corrupt archive")
+	tests := []struct {
+		name             string
+		firstResponse    func(writer http.ResponseWriter)
+		handlerErr       error
+		expectedAttempts int
+		expectErr        bool
+	}{
+		{
+			name: "retries when the connection drops mid-body",
+			firstResponse: func(writer http.ResponseWriter) {
+				writer.Header().Set("Content-Length", "1000")
+				writer.Write([]byte("partial"))
+				panic(http.ErrAbortHandler)
+			},
+			expectedAttempts: 2,
+		},
+		{
+			name: "retries when the body stalls",
+			firstResponse: func(writer http.ResponseWriter) {
+				writer.Header().Set("Content-Length", "1000")
+				writer.Write([]byte("partial"))
+				writer.(http.Flusher).Flush()
+				time.Sleep(500 * time.Millisecond)
+			},
+			expectedAttempts: 2,
+		},
+		{
+			name:             "does not retry handler errors",
+			handlerErr:       handlerErr,
+			expectedAttempts: 1,
+			expectErr:        true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			attempts := 0
+			server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				attempts++
+				if attempts == 1 && test.firstResponse != nil {
+					test.firstResponse(writer)
+					return
+				}
+				writer.Write([]byte("complete"))
+			}))
+			defer server.Close()
+
+			var received []byte
+			_, err := StreamFromUrl(server.URL, func(reader io.Reader) error {
+				var err error
+				if received, err = io.ReadAll(reader); err != nil {
+					return err
+				}
+				return test.handlerErr
+			})
+			if test.expectErr != (err != nil) {
+				t.Fatalf("unexpected error result: %v", err)
+			}
+			if attempts != test.expectedAttempts {
+				t.Errorf("expected %d attempts, got %d", test.expectedAttempts, attempts)
+			}
+			if !test.expectErr && string(received) != "complete" {
+				t.Errorf("expected complete body, got %q", received)
+			}
+		})
+	}
+}
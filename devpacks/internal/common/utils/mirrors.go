@@ -0,0 +1,114 @@
+package utils
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Mirrors can be set using env vars in the form BP_DEVPACKS_MIRROR_<HOST>=<base url> where <HOST> is
+// the upper case host name with anything other than letters and numbers replaced by "_". For example:
+// BP_DEVPACKS_MIRROR_NODEJS_ORG=file:///mirrors/nodejs
+const MIRROR_ENV_VAR_PREFIX = "BP_DEVPACKS_MIRROR_"
+
+// Alternatively, a binding of this type with a mirrors.toml file in it can be used. For example:
+// [mirrors]
+// "nodejs.org" = "http://localhost:8080/nodejs"
+const MIRRORS_BINDING_TYPE = "devpacks-mirrors"
+const MIRRORS_TOML_FILENAME = "mirrors.toml"
+
+type MirrorsToml struct {
+	Mirrors map[string]string
+}
+
+var cachedMirrors map[string]string = nil
+
+var mirrorHostKeyRegexp = regexp.MustCompile(`[^A-Z0-9]`)
+
+// Rewrites the scheme and host of the url to the mirror configured for the host if one exists
+func MirrorUrl(dlUrl string) (string, error) {
+	parsedUrl, err := url.Parse(dlUrl)
+	if err != nil || parsedUrl.Host == "" {
+		return dlUrl, nil
+	}
+	mirrors, err := Mirrors()
+	if err != nil {
+		return "", err
+	}
+	mirror, hasMirror := mirrors[mirrorHostKey(parsedUrl.Hostname())]
+	if !hasMirror {
+		return dlUrl, nil
+	}
+	mirroredUrl := strings.TrimSuffix(mirror, "/") + parsedUrl.EscapedPath()
+	if parsedUrl.RawQuery != "" {
+		mirroredUrl += "?" + parsedUrl.RawQuery
+	}
+	log.Println("Using mirror", mirroredUrl, "for", dlUrl)
+	return mirroredUrl, nil
+}
+
+// Map of normalized host name (see MIRROR_ENV_VAR_PREFIX) to mirror base url. Env vars take
+// precedence over a mirrors.toml binding.
+func Mirrors() (map[string]string, error) {
+	if cachedMirrors != nil {
+		return cachedMirrors, nil
+	}
+	bindingMirrors, err := readMirrorsBinding()
+	if err != nil {
+		return nil, err
+	}
+	cachedMirrors = make(map[string]string)
+	for host, mirror := range bindingMirrors {
+		cachedMirrors[mirrorHostKey(host)] = mirror
+	}
+	for _, envVar := range os.Environ() {
+		nameValue := strings.SplitN(envVar, "=", 2)
+		if !strings.HasPrefix(nameValue[0], MIRROR_ENV_VAR_PREFIX) || len(nameValue) != 2 || nameValue[1] == "" {
+			continue
+		}
+		cachedMirrors[strings.TrimPrefix(nameValue[0], MIRROR_ENV_VAR_PREFIX)] = nameValue[1]
+	}
+	return cachedMirrors, nil
+}
+
+// Clears the mirrors read by Mirrors so env var or binding changes are picked up (e.g. in tests)
+func ResetMirrors() {
+	cachedMirrors = nil
+}
+
+func mirrorHostKey(host string) string {
+	return mirrorHostKeyRegexp.ReplaceAllString(strings.ToUpper(host), "_")
+}
+
+func readMirrorsBinding() (map[string]string, error) {
+	bindingsRoot := os.Getenv("SERVICE_BINDING_ROOT")
+	if bindingsRoot == "" && os.Getenv("CNB_PLATFORM_DIR") != "" {
+		bindingsRoot = filepath.Join(os.Getenv("CNB_PLATFORM_DIR"), "bindings")
+	}
+	if bindingsRoot == "" {
+		return nil, nil
+	}
+	bindings, err := os.ReadDir(bindingsRoot)
+	if err != nil {
+		return nil, nil
+	}
+	for _, binding := range bindings {
+		bindingPath := filepath.Join(bindingsRoot, binding.Name())
+		typeBytes, err := os.ReadFile(filepath.Join(bindingPath, "type"))
+		if err != nil || strings.TrimSpace(string(typeBytes)) != MIRRORS_BINDING_TYPE {
+			continue
+		}
+		var mirrorsToml MirrorsToml
+		if _, err := toml.DecodeFile(filepath.Join(bindingPath, MIRRORS_TOML_FILENAME), &mirrorsToml); err != nil {
+			return nil, fmt.Errorf("failed to read %s in binding %s: %w", MIRRORS_TOML_FILENAME, bindingPath, err)
+		}
+		return mirrorsToml.Mirrors, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,125 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMirrors(t *testing.T) {
+	// Stand-in for a mirror that serves anything under /mirror
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Write([]byte("mirrored " + request.URL.Path))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name           string
+		envMirror      string
+		bindingMirrors string
+		dlUrl          string
+		expected       string
+	}{
+		{
+			name:      "env var mirror",
+			envMirror: server.URL + "/mirror/env",
+			dlUrl:     "https://nodejs.org/download/release/index.json",
+			expected:  "mirrored /mirror/env/download/release/index.json",
+		},
+		{
+			name:           "binding mirror",
+			bindingMirrors: `"nodejs.org" = "` + server.URL + `/mirror/binding"`,
+			dlUrl:          "https://nodejs.org/download/release/index.json",
+			expected:       "mirrored /mirror/binding/download/release/index.json",
+		},
+		{
+			name:           "env var takes precedence over binding",
+			envMirror:      server.URL + "/mirror/env",
+			bindingMirrors: `"nodejs.org" = "` + server.URL + `/mirror/binding"`,
+			dlUrl:          "https://nodejs.org/dist/index.json",
+			expected:       "mirrored /mirror/env/dist/index.json",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bindingsRoot := t.TempDir()
+			t.Setenv("SERVICE_BINDING_ROOT", bindingsRoot)
+			t.Setenv(MIRROR_ENV_VAR_PREFIX+"NODEJS_ORG", test.envMirror)
+			if test.bindingMirrors != "" {
+				bindingPath := filepath.Join(bindingsRoot, "mirrors")
+				if err := os.MkdirAll(bindingPath, 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := WriteFile(filepath.Join(bindingPath, "type"), []byte(MIRRORS_BINDING_TYPE)); err != nil {
+					t.Fatal(err)
+				}
+				if err := WriteFile(filepath.Join(bindingPath, MIRRORS_TOML_FILENAME), []byte("[mirrors]\n"+test.bindingMirrors+"\n")); err != nil {
+					t.Fatal(err)
+				}
+			}
+			ResetMirrors()
+			defer ResetMirrors()
+
+			content, err := DownloadBytesFromUrl(test.dlUrl)
+			if err != nil {
+				t.Fatalf("download failed: %v", err)
+			}
+			if string(content) != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, string(content))
+			}
+		})
+	}
+}
+
+func TestMirrorUrlWithoutMirror(t *testing.T) {
+	t.Setenv("SERVICE_BINDING_ROOT", t.TempDir())
+	ResetMirrors()
+	defer ResetMirrors()
+
+	mirroredUrl, err := MirrorUrl("https://example.com/file.tgz?x=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mirroredUrl != "https://example.com/file.tgz?x=1" {
+		t.Errorf("expected url to be unchanged, got %s", mirroredUrl)
+	}
+}
+
+func TestFileMirror(t *testing.T) {
+	// A local folder laid out like the server stands in for it
+	mirrorPath := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(mirrorPath, "dist"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFile(filepath.Join(mirrorPath, "dist", "index.json"), []byte(`[{"version": "v18.18.2"}]`)); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SERVICE_BINDING_ROOT", t.TempDir())
+	t.Setenv(MIRROR_ENV_VAR_PREFIX+"NODEJS_ORG", "file://"+filepath.ToSlash(mirrorPath))
+	ResetMirrors()
+	defer ResetMirrors()
+
+	content, err := DownloadBytesFromUrl("https://nodejs.org/dist/index.json")
+	if err != nil {
+		t.Fatalf("download failed: %v", err)
+	}
+	if string(content) != `[{"version": "v18.18.2"}]` {
+		t.Errorf("expected the mirrored file, got %q", content)
+	}
+
+	// The file's modification time stands in for Last-Modified when revalidating
+	targetFilePath := filepath.Join(t.TempDir(), "index.json")
+	validators, modified, _, err := DownloadToFileIfModified("https://nodejs.org/dist/index.json", targetFilePath, DownloadValidators{})
+	if err != nil || !modified {
+		t.Fatalf("expected the file to be downloaded, got %v (%v)", modified, err)
+	}
+	if _, modified, _, err := DownloadToFileIfModified("https://nodejs.org/dist/index.json", targetFilePath, validators); err != nil || modified {
+		t.Errorf("expected the unchanged file not to be downloaded again, got %v (%v)", modified, err)
+	}
+
+	if _, err := DownloadBytesFromUrl("https://nodejs.org/dist/missing.json"); err == nil {
+		t.Error("expected an error for a file missing from the mirror")
+	}
+}
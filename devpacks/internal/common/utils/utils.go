@@ -10,16 +10,15 @@ import (
 	"io/fs"
 	"io/ioutil"
 	"log"
-	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"reflect"
-	"regexp"
 	"strconv"
 	"strings"
 
 	"github.com/blang/semver/v4"
+	"github.com/chuxel/devpacks/internal/common/versions"
 	"github.com/joho/godotenv"
 	"gonum.org/v1/gonum/stat/combin"
 )
@@ -42,14 +41,14 @@ type LinuxDistroInfo struct {
 
 var cachedLinuxDistroInfo LinuxDistroInfo = LinuxDistroInfo{}
 
-func ReadLinuxDistroInfo() LinuxDistroInfo {
+func ReadLinuxDistroInfo() (LinuxDistroInfo, error) {
 	if cachedLinuxDistroInfo.Name == "" {
 		if _, err := os.Stat("/etc/os-release"); err != nil {
-			log.Fatal("/etc/os-release not found.")
+			return LinuxDistroInfo{}, fmt.Errorf("/etc/os-release not found: %w", err)
 		}
 		osRelease, err := godotenv.Read("/etc/os-release")
 		if err != nil {
-			log.Fatal("Unable to read /etc/os-release.")
+			return LinuxDistroInfo{}, fmt.Errorf("unable to read /etc/os-release: %w", err)
 		}
 		cachedLinuxDistroInfo = LinuxDistroInfo{
 			Name:         osRelease["NAME"],
@@ -63,7 +62,7 @@ func ReadLinuxDistroInfo() LinuxDistroInfo {
 			BugReportUrl: osRelease["BUG_REPORT_URL"],
 		}
 	}
-	return cachedLinuxDistroInfo
+	return cachedLinuxDistroInfo, nil
 }
 
 func (err NonZeroExitError) Error() string {
@@ -101,19 +100,19 @@ func SetFieldValue(field reflect.Value, value interface{}) {
 	}
 }
 
-func MergeProperties(existingVal interface{}, inVal interface{}) interface{} {
+func MergeProperties(existingVal interface{}, inVal interface{}) (interface{}, error) {
 	if existingVal == nil {
-		return inVal
+		return inVal, nil
 	}
 
 	if inVal == nil {
-		return existingVal
+		return existingVal, nil
 	}
 
 	typ := reflect.TypeOf(inVal).Kind()
 	existingTyp := reflect.TypeOf(existingVal).Kind()
 	if typ != existingTyp {
-		log.Fatal("Failed to merge properties due to type mismatch. Existing: ", existingTyp, ", input:", typ)
+		return nil, fmt.Errorf("failed to merge properties due to type mismatch. Existing: %s, input: %s", existingTyp, typ)
 	}
 	if typ == reflect.Slice || typ == reflect.Array {
 		outVal := make([]interface{}, 0)
@@ -125,7 +124,7 @@ func MergeProperties(existingVal interface{}, inVal interface{}) interface{} {
 		for i := 0; i < rInVal.Len(); i++ {
 			outVal = append(outVal, rInVal.Index(i).Interface())
 		}
-		return outVal
+		return outVal, nil
 
 	} else if typ == reflect.Map {
 		outVal := make(map[string]interface{})
@@ -137,59 +136,68 @@ func MergeProperties(existingVal interface{}, inVal interface{}) interface{} {
 		}
 		inItr := rInVal.MapRange()
 		for inItr.Next() {
-			rExistingMapVal := rExVal.MapIndex(inItr.Key())
-			if rExistingMapVal.Kind() == reflect.Invalid {
-				outVal[inItr.Key().String()] = MergeProperties(nil, inItr.Value().Interface())
-			} else {
-				outVal[inItr.Key().String()] = MergeProperties(rExistingMapVal.Interface(), inItr.Value().Interface())
+			var existingMapVal interface{}
+			if rExistingMapVal := rExVal.MapIndex(inItr.Key()); rExistingMapVal.Kind() != reflect.Invalid {
+				existingMapVal = rExistingMapVal.Interface()
 			}
+			mergedVal, err := MergeProperties(existingMapVal, inItr.Value().Interface())
+			if err != nil {
+				return nil, err
+			}
+			outVal[inItr.Key().String()] = mergedVal
 		}
-		return outVal
+		return outVal, nil
 	}
-	return inVal
+	return inVal, nil
 }
 
-func CpR(sourcePath string, targetFolderPath string) {
+func CpR(sourcePath string, targetFolderPath string) error {
 	sourceFileInfo, err := os.Stat(sourcePath)
 	if err != nil {
 		// Return if source path doesn't exist so we can use this with optional files
-		return
+		return nil
 	}
 	// Handle if source is file
 	if !sourceFileInfo.IsDir() {
-		Cp(sourcePath, targetFolderPath)
-		return
+		return Cp(sourcePath, targetFolderPath)
 	}
 
 	// Otherwise create the directory and scan contents
 	toFolderPath := filepath.Join(targetFolderPath, sourceFileInfo.Name())
-	os.MkdirAll(toFolderPath, sourceFileInfo.Mode())
+	if err := os.MkdirAll(toFolderPath, sourceFileInfo.Mode()); err != nil {
+		return fmt.Errorf("failed to create %s: %w", toFolderPath, err)
+	}
 	fileInfos, err := ioutil.ReadDir(sourcePath)
 	if err != nil {
-		log.Fatal(err)
+		return fmt.Errorf("failed to read contents of %s: %w", sourcePath, err)
 	}
 	for _, fileInfo := range fileInfos {
 		fromPath := filepath.Join(sourcePath, fileInfo.Name())
 		if fileInfo.IsDir() {
-			CpR(fromPath, toFolderPath)
+			err = CpR(fromPath, toFolderPath)
 		} else {
-			Cp(fromPath, toFolderPath)
+			err = Cp(fromPath, toFolderPath)
+		}
+		if err != nil {
+			return err
 		}
 	}
+	return nil
 }
 
-func Cp(sourceFilePath string, targetFolderPath string) {
+func Cp(sourceFilePath string, targetFolderPath string) error {
 	sourceFileInfo, err := os.Stat(sourceFilePath)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	// Make target file
 	targetFilePath := filepath.Join(targetFolderPath, sourceFileInfo.Name())
 	targetFile, err := os.Create(targetFilePath)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
+	defer targetFile.Close()
 	// Sync source and target file mode and ownership
 	targetFile.Chmod(sourceFileInfo.Mode())
 	SyncUIDGID(targetFile, sourceFileInfo)
@@ -197,14 +205,76 @@ func Cp(sourceFilePath string, targetFolderPath string) {
 	// Execute copy
 	sourceFile, err := os.Open(sourceFilePath)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-	_, err = io.Copy(targetFile, sourceFile)
-	if err != nil {
-		log.Fatal(err)
+	defer sourceFile.Close()
+	if _, err = io.Copy(targetFile, sourceFile); err != nil {
+		return fmt.Errorf("failed to copy %s to %s: %w", sourceFilePath, targetFilePath, err)
 	}
-	targetFile.Close()
-	sourceFile.Close()
+	return nil
+}
+
+// Moves a folder, falling back to a copy when the source and target are on different devices. Symlinks
+// are kept as symlinks, and relative ones that point outside the folder are made absolute first so they
+// still resolve from the new location (e.g. workspace packages linked into node_modules).
+func MoveFolder(sourcePath string, targetPath string) error {
+	if err := filepath.Walk(sourcePath, func(path string, fileInfo fs.FileInfo, err error) error {
+		if err != nil || fileInfo.Mode()&os.ModeSymlink == 0 {
+			return err
+		}
+		link, err := os.Readlink(path)
+		if err != nil || filepath.IsAbs(link) {
+			return err
+		}
+		linkTarget := filepath.Join(filepath.Dir(path), link)
+		if relPath, err := filepath.Rel(sourcePath, linkTarget); err == nil && relPath != ".." && !strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+			return nil
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		return os.Symlink(linkTarget, path)
+	}); err != nil {
+		return fmt.Errorf("failed to update symlinks in %s: %w", sourcePath, err)
+	}
+	if err := os.Rename(sourcePath, targetPath); err == nil {
+		return nil
+	}
+	if err := copyFolder(sourcePath, targetPath); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(sourcePath); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", sourcePath, err)
+	}
+	return nil
+}
+
+// Copies a folder without following symlinks, unlike CpR
+func copyFolder(sourcePath string, targetPath string) error {
+	if err := filepath.Walk(sourcePath, func(path string, fileInfo fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(sourcePath, path)
+		if err != nil {
+			return err
+		}
+		toPath := filepath.Join(targetPath, relPath)
+		switch {
+		case fileInfo.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, toPath)
+		case fileInfo.IsDir():
+			return os.MkdirAll(toPath, fileInfo.Mode().Perm())
+		}
+		return Cp(path, filepath.Dir(toPath))
+	}); err != nil {
+		return fmt.Errorf("failed to copy %s to %s: %w", sourcePath, targetPath, err)
+	}
+	return nil
 }
 
 func WriteFile(filename string, fileBytes []byte) error {
@@ -262,16 +332,24 @@ func InterfaceToStringSlice(inter interface{}) []string {
 	return newSlice
 }
 
-func ToJsonRawMessage(value interface{}) json.RawMessage {
+func StringSliceToInterfaceSlice(slice []string) []interface{} {
+	newSlice := make([]interface{}, len(slice))
+	for i, item := range slice {
+		newSlice[i] = item
+	}
+	return newSlice
+}
+
+func ToJsonRawMessage(value interface{}) (json.RawMessage, error) {
 	var err error
 	var bytes json.RawMessage
 	if bytes, err = json.Marshal(value); err != nil {
-		log.Fatal("Failed to convert to json.RawMessage:", err)
+		return nil, fmt.Errorf("failed to convert to json.RawMessage: %w", err)
 	}
-	return bytes
+	return bytes, nil
 }
 
-func ExecCmd(workingDir string, captureOutput bool, command string, args ...string) []byte {
+func ExecCmd(workingDir string, captureOutput bool, command string, args ...string) ([]byte, error) {
 	var outputBytes bytes.Buffer
 	var errorOutput bytes.Buffer
 
@@ -289,29 +367,30 @@ func ExecCmd(workingDir string, captureOutput bool, command string, args ...stri
 		cmd.Dir = workingDir
 	}
 	if err := cmd.Run(); err != nil {
-		log.Fatal("Command ", command, fmt.Sprint(args), " failed. ", err)
-	} else if cmd.ProcessState.ExitCode() != 0 {
-		log.Fatal("Command ", command, fmt.Sprint(args), " failed with exit code ", cmd.ProcessState.ExitCode())
+		if exitErr, isExitErr := err.(*exec.ExitError); isExitErr {
+			err = NonZeroExitError{ExitCode: exitErr.ExitCode()}
+		}
 		if captureOutput {
-			log.Fatal("Command output:", outputBytes.String())
+			return outputBytes.Bytes(), fmt.Errorf("command %s %v failed: %w\n%s", command, args, err, errorOutput.String())
 		}
+		return outputBytes.Bytes(), fmt.Errorf("command %s %v failed: %w", command, args, err)
 	}
-	return outputBytes.Bytes()
+	return outputBytes.Bytes(), nil
 }
 
-func UntarBytes(tarBytes []byte, destination string, strip int) {
-	Untar(bytes.NewReader(tarBytes), destination, strip)
+func UntarBytes(tarBytes []byte, destination string, strip int) error {
+	return Untar(bytes.NewReader(tarBytes), destination, strip)
 }
 
-func Untar(reader io.Reader, destination string, strip int) {
+func Untar(reader io.Reader, destination string, strip int) error {
 	var err error
 	if destination, err = filepath.Abs(destination); err != nil {
-		log.Fatal("Failed to convert path to absolute path. ", err)
+		return fmt.Errorf("failed to convert path to absolute path: %w", err)
 	}
 
 	gzReader, err := gzip.NewReader(reader)
 	if err != nil {
-		log.Fatal("Unable to create gzip reader. ", err)
+		return fmt.Errorf("unable to create gzip reader: %w", err)
 	}
 	defer gzReader.Close()
 
@@ -322,7 +401,7 @@ func Untar(reader io.Reader, destination string, strip int) {
 		if err == io.EOF {
 			break
 		} else if err != nil {
-			log.Fatal("Error reading tar file. ", err)
+			return fmt.Errorf("error reading tar file: %w", err)
 		}
 
 		// Strip out specified number of folders from target path
@@ -344,14 +423,14 @@ func Untar(reader io.Reader, destination string, strip int) {
 		// Convert to absolute paths
 		var targetPath, linkPath string
 		if targetPath, err = filepath.Abs(filepath.Join(destination, targetRelPath)); err != nil {
-			log.Fatal("Failed to convert path to absolute path. ", err)
+			return fmt.Errorf("failed to convert path to absolute path: %w", err)
 		}
 		if !strings.HasPrefix(targetPath, destination) {
 			continue
 		}
 		if typeflag == tar.TypeLink || typeflag == tar.TypeSymlink {
 			if linkPath, err = filepath.Abs(filepath.Join(destination, linkRelPath)); err != nil {
-				log.Fatal("Failed to convert path to absolute path. ", err)
+				return fmt.Errorf("failed to convert path to absolute path: %w", err)
 			}
 			if !strings.HasPrefix(linkPath, destination) {
 				continue
@@ -363,107 +442,33 @@ func Untar(reader io.Reader, destination string, strip int) {
 		case tar.TypeDir:
 			if _, err := os.Stat(targetPath); err != nil {
 				if err := os.MkdirAll(targetPath, fs.FileMode(header.Mode)); err != nil {
-					log.Fatal("Failed to create directory. ", err)
+					return fmt.Errorf("failed to create directory: %w", err)
 				}
 			}
 		case tar.TypeSymlink:
 			if err := os.Symlink(linkPath, targetPath); err != nil {
-				log.Fatal("Failed to create symlink. ", err)
+				return fmt.Errorf("failed to create symlink: %w", err)
 			}
 		case tar.TypeLink:
 			if err := os.Link(linkPath, targetPath); err != nil {
-				log.Fatal("Failed to create link. ", err)
+				return fmt.Errorf("failed to create link: %w", err)
 			}
 		case tar.TypeReg:
 			file, err := os.OpenFile(targetPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, fs.FileMode(header.Mode))
 			if err != nil {
-				log.Fatal("Failed to open file. ", err)
+				return fmt.Errorf("failed to open file: %w", err)
 			}
-			defer file.Close()
-			if _, err = io.Copy(file, tarReader); err != nil {
-				log.Fatal("Failed to copy file. ", err)
-			}
-		}
-	}
-}
-
-func NewSemverRange(version string) semver.Range {
-	// Convert node shorthands to semver.Range string
-	requestedVersion := strings.ReplaceAll(version, "*", "x")
-	// 18.1.2 - 18.3.2 is >=18.1.2 <=18.3.2
-	exp := regexp.MustCompile(`[0-9x]+(\.[^ ]+)? - [0-9x]+`)
-	rangeLocs := exp.FindAllStringIndex(requestedVersion, -1)
-	if rangeLocs != nil {
-		for _, loc := range rangeLocs {
-			requestedVersion = requestedVersion[:loc[0]] + ">=" + requestedVersion[loc[0]:]
-		}
-		requestedVersion = strings.ReplaceAll(requestedVersion, " - ", " <=")
-	}
-	// Handle ^ and ~
-	hasCarrotOrTilde, _ := regexp.MatchString("(^|~)", requestedVersion)
-	if hasCarrotOrTilde {
-		semverRange := ""
-		rangeParts := strings.Split(requestedVersion, " ")
-		for _, part := range rangeParts {
-			if part[0] == '~' {
-				// ~18.1.2 is >=18.1.2 <18.2.0
-				semverRange += ">=" + part[1:]
-				tempVersion, err := semver.ParseTolerant(part[1:])
-				if err != nil {
-					log.Fatal(err)
-				}
-				tempVersion.IncrementMinor()
-				tempVersion.Patch = 0
-				semverRange += " <" + tempVersion.FinalizeVersion() + " "
-			} else if part[0] == '^' {
-				// ^18.1.2 is >=18.1.2 <19.0.0
-				semverRange += ">=" + part[1:] + " "
-				tempVersion, err := semver.ParseTolerant(part[1:])
-				if err != nil {
-					log.Fatal(err)
-				}
-				tempVersion.IncrementMajor()
-				tempVersion.Minor = 0
-				tempVersion.Patch = 0
-				semverRange += " <" + tempVersion.FinalizeVersion() + " "
-			} else {
-				semverRange += part + " "
+			_, err = io.Copy(file, tarReader)
+			file.Close()
+			if err != nil {
+				return fmt.Errorf("failed to copy file: %w", err)
 			}
 		}
-		requestedVersion = semverRange
-	}
-	// 18 is 18.x.x, 18.1 is 18.1.x
-	expX := regexp.MustCompile(`[!=>< ][0-9x]+(\.[0-9x]+)? `)
-	requestedVersion = " " + requestedVersion + " "
-	for {
-		loc := expX.FindStringIndex(requestedVersion)
-		if loc == nil {
-			break
-		}
-		version := requestedVersion[loc[0]+1 : loc[1]-1]
-		if strings.Contains(version, ".") {
-			version = version + ".x"
-		} else {
-			version = version + ".x.x"
-		}
-		requestedVersion = requestedVersion[:loc[0]+1] + version + requestedVersion[loc[1]-1:]
 	}
-
-	return semver.MustParseRange(requestedVersion)
+	return nil
 }
 
-func DownloadBytesFromUrl(dlUrl string) []byte {
-	response, err := http.Get(dlUrl)
-	if err != nil {
-		log.Fatal(err)
-	}
-	if response.StatusCode != 200 {
-		log.Fatal("Got status code ", response.StatusCode, " for ", dlUrl)
-	}
-	defer response.Body.Close()
-	outBytes, err := io.ReadAll(response.Body)
-	if err != nil {
-		log.Fatal("Failed to read response body. ", err)
-	}
-	return outBytes
+// Parses an npm style version range (e.g. "18", "^18.1.0", "16 - 18" or "16.x || 18.x"). See versions.NewNpmRange.
+func NewSemverRange(version string) (semver.Range, error) {
+	return versions.NewNpmRange(version)
 }
@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMoveFolder(t *testing.T) {
+	appPath := t.TempDir()
+	sourcePath := filepath.Join(appPath, "node_modules")
+	for _, folder := range []string{filepath.Join(sourcePath, "left-pad"), filepath.Join(appPath, "packages", "a")} {
+		if err := os.MkdirAll(folder, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(sourcePath, "left-pad", "index.js"), []byte("module.exports = {}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// One link inside the folder and one to a workspace package outside of it
+	if err := os.Symlink("left-pad", filepath.Join(sourcePath, "pad")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join("..", "packages", "a"), filepath.Join(sourcePath, "a")); err != nil {
+		t.Fatal(err)
+	}
+
+	targetPath := filepath.Join(t.TempDir(), "node_modules")
+	if err := MoveFolder(sourcePath, targetPath); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Lstat(sourcePath); !os.IsNotExist(err) {
+		t.Errorf("expected %s to be removed, got %v", sourcePath, err)
+	}
+	if _, err := os.Stat(filepath.Join(targetPath, "left-pad", "index.js")); err != nil {
+		t.Errorf("expected moved file: %v", err)
+	}
+	if link, err := os.Readlink(filepath.Join(targetPath, "pad")); err != nil || link != "left-pad" {
+		t.Errorf("expected link inside the folder to stay relative, got %s (%v)", link, err)
+	}
+	if link, err := os.Readlink(filepath.Join(targetPath, "a")); err != nil || link != filepath.Join(appPath, "packages", "a") {
+		t.Errorf("expected link outside the folder to be absolute, got %s (%v)", link, err)
+	}
+}
+
+// Used by MoveFolder when the source and target are on different devices
+func TestCopyFolder(t *testing.T) {
+	sourcePath := filepath.Join(t.TempDir(), "node_modules")
+	if err := os.MkdirAll(filepath.Join(sourcePath, "left-pad", "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(sourcePath, "left-pad", "bin", "pad"), []byte("#!/bin/sh"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(sourcePath, ".bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join("..", "left-pad", "bin", "pad"), filepath.Join(sourcePath, ".bin", "pad")); err != nil {
+		t.Fatal(err)
+	}
+
+	targetPath := filepath.Join(t.TempDir(), "node_modules")
+	if err := copyFolder(sourcePath, targetPath); err != nil {
+		t.Fatal(err)
+	}
+	if fileInfo, err := os.Stat(filepath.Join(targetPath, "left-pad", "bin", "pad")); err != nil || fileInfo.Mode().Perm() != 0755 {
+		t.Errorf("expected copied executable, got %v (%v)", fileInfo, err)
+	}
+	if link, err := os.Readlink(filepath.Join(targetPath, ".bin", "pad")); err != nil || link != filepath.Join("..", "left-pad", "bin", "pad") {
+		t.Errorf("expected symlink to be copied as a symlink, got %s (%v)", link, err)
+	}
+}
@@ -0,0 +1,46 @@
+package versions
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/blang/semver/v4"
+)
+
+var goVersionRegexp = regexp.MustCompile(`^(?:go)?([0-9]+)(?:\.([0-9]+))?(?:\.([0-9]+))?(?:(beta|rc)([0-9]+))?$`)
+
+// Converts a Go release version (e.g. "go1.21.3", "go1.20" or "go1.21rc2", as used on go.dev/dl and in
+// go.mod) into a semver version. Missing parts are zero, so go1.20 is 1.20.0, and betas and release
+// candidates become "beta.N" and "rc.N" prereleases.
+func ParseGoVersion(versionString string) (semver.Version, error) {
+	match := goVersionRegexp.FindStringSubmatch(strings.TrimSpace(versionString))
+	if match == nil {
+		return semver.Version{}, fmt.Errorf("invalid Go version %s", versionString)
+	}
+	numbers := [3]uint64{}
+	for i, part := range match[1:4] {
+		if part == "" {
+			continue
+		}
+		number, err := strconv.ParseUint(part, 10, 64)
+		if err != nil {
+			return semver.Version{}, fmt.Errorf("invalid Go version %s", versionString)
+		}
+		numbers[i] = number
+	}
+	version := semver.Version{Major: numbers[0], Minor: numbers[1], Patch: numbers[2]}
+	if match[4] != "" {
+		prerelease, err := semver.NewPRVersion(match[4])
+		if err != nil {
+			return semver.Version{}, fmt.Errorf("invalid Go version %s: %w", versionString, err)
+		}
+		number, err := semver.NewPRVersion(match[5])
+		if err != nil {
+			return semver.Version{}, fmt.Errorf("invalid Go version %s: %w", versionString, err)
+		}
+		version.Pre = []semver.PRVersion{prerelease, number}
+	}
+	return version, nil
+}
@@ -0,0 +1,44 @@
+package versions
+
+import (
+	"testing"
+)
+
+func TestParseGoVersion(t *testing.T) {
+	tests := []struct {
+		versionString string
+		expected      string
+		expectError   bool
+	}{
+		{versionString: "go1.21.3", expected: "1.21.3"},
+		{versionString: "1.21.3", expected: "1.21.3"},
+		{versionString: "go1.20", expected: "1.20.0"},
+		{versionString: "1.20", expected: "1.20.0"},
+		{versionString: "go1", expected: "1.0.0"},
+		{versionString: " go1.21.0 ", expected: "1.21.0"},
+		{versionString: "go1.21rc2", expected: "1.21.0-rc.2"},
+		{versionString: "go1.21.0rc1", expected: "1.21.0-rc.1"},
+		{versionString: "go1.22beta1", expected: "1.22.0-beta.1"},
+		{versionString: "go1.21.3.4", expectError: true},
+		{versionString: "go1.21alpha1", expectError: true},
+		{versionString: "gotip", expectError: true},
+		{versionString: "", expectError: true},
+	}
+	for _, test := range tests {
+		t.Run(test.versionString, func(t *testing.T) {
+			version, err := ParseGoVersion(test.versionString)
+			if test.expectError {
+				if err == nil {
+					t.Errorf("expected an error, got %s", version)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if version.String() != test.expected {
+				t.Errorf("expected %s, got %s", test.expected, version)
+			}
+		})
+	}
+}
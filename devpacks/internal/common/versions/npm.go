@@ -0,0 +1,228 @@
+// Parsers for version range grammars that produce a semver.Range that can be used to find a matching
+// version. NewNpmRange handles npm / node-semver style ranges (used for Node.js and Poetry), while
+// NewPep440Range handles Python's PEP 440 version specifiers. ParseGoVersion converts Go release
+// versions like go1.21rc2 so they can be matched against either.
+package versions
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/blang/semver/v4"
+)
+
+type comparator struct {
+	operator string
+	version  semver.Version
+}
+
+func (c comparator) matches(version semver.Version) bool {
+	switch c.operator {
+	case "<":
+		return version.LT(c.version)
+	case "<=":
+		return version.LTE(c.version)
+	case ">":
+		return version.GT(c.version)
+	case ">=":
+		return version.GTE(c.version)
+	}
+	return version.EQ(c.version)
+}
+
+// A partial version like "1", "1.2", "1.x" or "1.2.3-beta.1". Parts is the number of numeric
+// parts before any wildcard.
+type partialVersion struct {
+	version semver.Version
+	parts   int
+}
+
+var npmComparatorRegexp = regexp.MustCompile(`^(<=|>=|<|>|=|~>|~|\^)?v?(.*)$`)
+var npmOperatorSpaceRegexp = regexp.MustCompile(`(<=|>=|<|>|=|~>|~|\^)\s+`)
+var npmHyphenRegexp = regexp.MustCompile(`^(\S+)\s+-\s+(\S+)$`)
+
+// Parses an npm style range (see https://github.com/npm/node-semver#ranges) including x-ranges
+// ("18", "18.x", "*"), tilde and caret ranges, hyphen ranges and "||". As with npm, prerelease
+// versions only match if a comparator in the range has a prerelease for the same major.minor.patch.
+func NewNpmRange(rangeString string) (semver.Range, error) {
+	comparatorSets := [][]comparator{}
+	for _, rangePart := range strings.Split(rangeString, "||") {
+		comparatorSet, err := parseNpmComparatorSet(strings.TrimSpace(rangePart))
+		if err != nil {
+			return nil, fmt.Errorf("invalid version range %s: %w", rangeString, err)
+		}
+		comparatorSets = append(comparatorSets, comparatorSet)
+	}
+	return func(version semver.Version) bool {
+		for _, comparatorSet := range comparatorSets {
+			if npmComparatorSetMatches(comparatorSet, version) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+func npmComparatorSetMatches(comparatorSet []comparator, version semver.Version) bool {
+	for _, c := range comparatorSet {
+		if !c.matches(version) {
+			return false
+		}
+	}
+	if len(version.Pre) == 0 {
+		return true
+	}
+	for _, c := range comparatorSet {
+		if len(c.version.Pre) > 0 && c.version.Major == version.Major && c.version.Minor == version.Minor && c.version.Patch == version.Patch {
+			return true
+		}
+	}
+	return false
+}
+
+func parseNpmComparatorSet(rangePart string) ([]comparator, error) {
+	// Hyphen range: 1.2.3 - 2.3.4 is >=1.2.3 <=2.3.4
+	if match := npmHyphenRegexp.FindStringSubmatch(rangePart); match != nil {
+		from, err := parsePartialVersion(strings.TrimPrefix(match[1], "v"))
+		if err != nil {
+			return nil, err
+		}
+		to, err := parsePartialVersion(strings.TrimPrefix(match[2], "v"))
+		if err != nil {
+			return nil, err
+		}
+		comparators := []comparator{}
+		if from.parts > 0 {
+			comparators = append(comparators, comparator{">=", from.version})
+		}
+		if to.parts == 3 {
+			comparators = append(comparators, comparator{"<=", to.version})
+		} else if to.parts > 0 {
+			comparators = append(comparators, comparator{"<", incrementPart(to.version, to.parts)})
+		}
+		return comparators, nil
+	}
+
+	comparators := []comparator{}
+	for _, simple := range strings.Fields(npmOperatorSpaceRegexp.ReplaceAllString(rangePart, "$1")) {
+		simpleComparators, err := parseNpmSimple(simple)
+		if err != nil {
+			return nil, err
+		}
+		comparators = append(comparators, simpleComparators...)
+	}
+	return comparators, nil
+}
+
+func parseNpmSimple(simple string) ([]comparator, error) {
+	match := npmComparatorRegexp.FindStringSubmatch(simple)
+	operator := match[1]
+	partial, err := parsePartialVersion(match[2])
+	if err != nil {
+		return nil, err
+	}
+	version, parts := partial.version, partial.parts
+	// A "*" matches everything, except when it can't (e.g. "<*")
+	if parts == 0 {
+		if operator == "<" || operator == ">" {
+			return []comparator{{"<", semver.Version{}}}, nil
+		}
+		return []comparator{}, nil
+	}
+
+	switch operator {
+	case "", "=":
+		if parts == 3 {
+			return []comparator{{"=", version}}, nil
+		}
+		return []comparator{{">=", version}, {"<", incrementPart(version, parts)}}, nil
+	case ">":
+		if parts == 3 {
+			return []comparator{{">", version}}, nil
+		}
+		return []comparator{{">=", incrementPart(version, parts)}}, nil
+	case ">=":
+		return []comparator{{">=", version}}, nil
+	case "<":
+		return []comparator{{"<", version}}, nil
+	case "<=":
+		if parts == 3 {
+			return []comparator{{"<=", version}}, nil
+		}
+		return []comparator{{"<", incrementPart(version, parts)}}, nil
+	case "~", "~>":
+		// ~1.2.3 is >=1.2.3 <1.3.0, ~1.2 is >=1.2.0 <1.3.0, ~1 is >=1.0.0 <2.0.0
+		if parts == 1 {
+			return []comparator{{">=", version}, {"<", incrementPart(version, 1)}}, nil
+		}
+		return []comparator{{">=", version}, {"<", incrementPart(version, 2)}}, nil
+	case "^":
+		// Allows changes that do not modify the left-most non-zero part
+		// ^1.2.3 is >=1.2.3 <2.0.0, ^0.2.3 is >=0.2.3 <0.3.0, ^0.0.3 is >=0.0.3 <0.0.4
+		upperPart := 1
+		if version.Major == 0 && parts >= 2 {
+			upperPart = 2
+			if version.Minor == 0 && parts == 3 {
+				upperPart = 3
+			}
+		}
+		return []comparator{{">=", version}, {"<", incrementPart(version, upperPart)}}, nil
+	}
+	return nil, fmt.Errorf("unsupported operator %s", operator)
+}
+
+// Parses "1", "1.2", "1.x", "1.2.*", "1.2.3" or "1.2.3-rc.1" with missing parts set to 0
+func parsePartialVersion(partial string) (partialVersion, error) {
+	partial = strings.TrimSpace(partial)
+	if partial == "" {
+		return partialVersion{}, nil
+	}
+	// Split off prerelease and build metadata
+	core := partial
+	suffix := ""
+	if index := strings.IndexAny(partial, "-+"); index >= 0 {
+		core = partial[:index]
+		suffix = partial[index:]
+	}
+	numbers := [3]uint64{}
+	parts := 0
+	for i, part := range strings.Split(core, ".") {
+		if i > 2 {
+			return partialVersion{}, fmt.Errorf("too many parts in version %s", partial)
+		}
+		if part == "x" || part == "X" || part == "*" {
+			break
+		}
+		number, err := strconv.ParseUint(part, 10, 64)
+		if err != nil {
+			return partialVersion{}, fmt.Errorf("invalid version %s", partial)
+		}
+		numbers[i] = number
+		parts++
+	}
+	version := semver.Version{Major: numbers[0], Minor: numbers[1], Patch: numbers[2]}
+	if suffix != "" {
+		if parts != 3 {
+			return partialVersion{}, fmt.Errorf("prerelease requires a full version in %s", partial)
+		}
+		parsed, err := semver.Parse(core + suffix)
+		if err != nil {
+			return partialVersion{}, fmt.Errorf("invalid version %s: %w", partial, err)
+		}
+		version = parsed
+	}
+	return partialVersion{version: version, parts: parts}, nil
+}
+
+// Increments the part at the index (1 = major, 2 = minor, 3 = patch) and zeros everything after it
+func incrementPart(version semver.Version, part int) semver.Version {
+	switch part {
+	case 1:
+		return semver.Version{Major: version.Major + 1}
+	case 2:
+		return semver.Version{Major: version.Major, Minor: version.Minor + 1}
+	}
+	return semver.Version{Major: version.Major, Minor: version.Minor, Patch: version.Patch + 1}
+}
@@ -0,0 +1,96 @@
+package versions
+
+import (
+	"testing"
+
+	"github.com/blang/semver/v4"
+)
+
+func TestNewNpmRange(t *testing.T) {
+	tests := []struct {
+		rangeString string
+		matches     []string
+		notMatches  []string
+	}{
+		// Exact and x-ranges
+		{"1.2.3", []string{"1.2.3"}, []string{"1.2.4", "1.2.2"}},
+		{"=1.2.3", []string{"1.2.3"}, []string{"1.2.4"}},
+		{"18", []string{"18.0.0", "18.19.1"}, []string{"17.9.9", "19.0.0"}},
+		{"18.x", []string{"18.0.0", "18.19.1"}, []string{"19.0.0"}},
+		{"1.2.x", []string{"1.2.0", "1.2.9"}, []string{"1.3.0", "1.1.9"}},
+		{"1.2.*", []string{"1.2.0", "1.2.9"}, []string{"1.3.0"}},
+		{"1.X", []string{"1.0.0", "1.9.9"}, []string{"2.0.0"}},
+		{"*", []string{"0.0.0", "21.1.0"}, []string{"21.0.0-rc.1"}},
+		{"", []string{"1.0.0"}, []string{}},
+		{"x", []string{"1.0.0"}, []string{}},
+		// Comparators
+		{">1.2.3", []string{"1.2.4", "2.0.0"}, []string{"1.2.3"}},
+		{">1.2", []string{"1.3.0"}, []string{"1.2.9"}},
+		{">=1.2.3", []string{"1.2.3", "2.0.0"}, []string{"1.2.2"}},
+		{"<1.2.3", []string{"1.2.2"}, []string{"1.2.3"}},
+		{"<=1.2.3", []string{"1.2.3"}, []string{"1.2.4"}},
+		{"<=1.2", []string{"1.2.9"}, []string{"1.3.0"}},
+		{">= 1.2.3 < 2", []string{"1.2.3", "1.9.9"}, []string{"2.0.0", "1.2.2"}},
+		{"<*", []string{}, []string{"0.0.0", "1.0.0"}},
+		// Tilde ranges
+		{"~1.2.3", []string{"1.2.3", "1.2.9"}, []string{"1.3.0", "1.2.2"}},
+		{"~1.2", []string{"1.2.0", "1.2.9"}, []string{"1.3.0"}},
+		{"~1", []string{"1.0.0", "1.9.9"}, []string{"2.0.0"}},
+		{"~>1.2.3", []string{"1.2.9"}, []string{"1.3.0"}},
+		{"~0.2.3", []string{"0.2.3", "0.2.9"}, []string{"0.3.0"}},
+		// Caret ranges
+		{"^1.2.3", []string{"1.2.3", "1.9.9"}, []string{"2.0.0", "1.2.2"}},
+		{"^0.2.3", []string{"0.2.3", "0.2.9"}, []string{"0.3.0"}},
+		{"^0.0.3", []string{"0.0.3"}, []string{"0.0.4"}},
+		{"^1.2", []string{"1.2.0", "1.9.9"}, []string{"2.0.0"}},
+		{"^0.0", []string{"0.0.0", "0.0.9"}, []string{"0.1.0"}},
+		{"^1.x", []string{"1.0.0", "1.9.9"}, []string{"2.0.0"}},
+		{"^ 18.2.0", []string{"18.20.0"}, []string{"19.0.0"}},
+		// Hyphen ranges
+		{"1.2.3 - 2.3.4", []string{"1.2.3", "2.3.4"}, []string{"1.2.2", "2.3.5"}},
+		{"1.2 - 2.3.4", []string{"1.2.0"}, []string{"1.1.9"}},
+		{"1.2.3 - 2.3", []string{"2.3.9"}, []string{"2.4.0"}},
+		{"1.2.3 - 2", []string{"2.9.9"}, []string{"3.0.0"}},
+		// Unions
+		{"^16 || ^18", []string{"16.0.0", "18.19.1"}, []string{"17.0.0", "20.0.0"}},
+		{"1.2.3 || >=2.0.0 <2.1.0", []string{"1.2.3", "2.0.5"}, []string{"1.2.4", "2.1.0"}},
+		// "v" prefixes
+		{"v1.2.3", []string{"1.2.3"}, []string{"1.2.4"}},
+		{"^v18.2.0", []string{"18.20.0"}, []string{"19.0.0"}},
+		{">=v16", []string{"16.0.0"}, []string{"15.9.9"}},
+		{"v1.2.3 - v2.3.4", []string{"2.0.0"}, []string{"2.3.5"}},
+		// Prereleases only match if a comparator has a prerelease on the same major.minor.patch
+		{">=1.2.3-beta.1", []string{"1.2.3-beta.1", "1.2.3-rc.1", "1.2.3", "1.3.0"}, []string{"1.2.3-alpha.1", "1.3.0-rc.1"}},
+		{"^1.2.3-rc.1", []string{"1.2.3-rc.2", "1.2.4"}, []string{"1.2.4-rc.1", "2.0.0"}},
+		{"^20", []string{"20.1.0"}, []string{"20.2.0-rc.1", "21.0.0-nightly"}},
+		{"1.2.3-rc.1", []string{"1.2.3-rc.1"}, []string{"1.2.3"}},
+	}
+	for _, test := range tests {
+		t.Run(test.rangeString, func(t *testing.T) {
+			expectedRange, err := NewNpmRange(test.rangeString)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, version := range test.matches {
+				if !expectedRange(semver.MustParse(version)) {
+					t.Errorf("expected %s to match %s", version, test.rangeString)
+				}
+			}
+			for _, version := range test.notMatches {
+				if expectedRange(semver.MustParse(version)) {
+					t.Errorf("expected %s not to match %s", version, test.rangeString)
+				}
+			}
+		})
+	}
+}
+
+func TestNewNpmRangeErrors(t *testing.T) {
+	for _, rangeString := range []string{"1.2.3.4", "abc", "^1.2-rc.1", ">=1.2.3 || foo", "1.a"} {
+		t.Run(rangeString, func(t *testing.T) {
+			if _, err := NewNpmRange(rangeString); err == nil {
+				t.Errorf("expected an error for %s", rangeString)
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package versions
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/blang/semver/v4"
+)
+
+// See https://peps.python.org/pep-0440/#appendix-b-parsing-version-strings-with-regular-expressions
+var pep440VersionRegexp = regexp.MustCompile(`(?i)^v?(?:([0-9]+)!)?([0-9]+(?:\.[0-9]+)*)` +
+	`(?:[-_.]?(a|alpha|b|beta|c|rc|pre|preview)[-_.]?([0-9]+)?)?` +
+	`(?:-([0-9]+)|[-_.]?(?:post|rev|r)[-_.]?([0-9]+)?)?` +
+	`(?:[-_.]?(dev)[-_.]?([0-9]+)?)?` +
+	`(?:\+[a-z0-9]+(?:[-_.][a-z0-9]+)*)?$`)
+var pep440ClauseRegexp = regexp.MustCompile(`^(~=|===|==|!=|<=|>=|<|>)\s*(.+?)(\.\*)?$`)
+var pep440BareVersionRegexp = regexp.MustCompile(`^v?[0-9]+(\.[0-9]+)*$`)
+var pep440BareWildcardRegexp = regexp.MustCompile(`^v?[0-9]+(\.[0-9]+)*\.[*xX]$`)
+var pep440OperatorRegexp = regexp.MustCompile(`^(~=|===|==|!=|<=|>=|<|>)$`)
+
+// Prerelease names used by Python's own version strings (and the actions/python-versions manifest)
+var pep440PrereleaseNames = map[string]string{
+	"a":       "alpha",
+	"alpha":   "alpha",
+	"b":       "beta",
+	"beta":    "beta",
+	"c":       "rc",
+	"rc":      "rc",
+	"pre":     "rc",
+	"preview": "rc",
+}
+
+type pep440Clause struct {
+	operator string
+	raw      string
+	version  semver.Version
+	// Number of release segments, used for prefix matching with ".*" and "~="
+	segments int
+	wildcard bool
+}
+
+// Parses a PEP 440 version specifier like "~=3.10", ">=3.9,<3.12", "!=3.11.0" or "==3.10.*" (see
+// https://peps.python.org/pep-0440/#version-specifiers). As PEP 440 requires, prereleases are only
+// matched if one of the clauses explicitly references a prerelease.
+func NewPep440Range(specifier string) (semver.Range, error) {
+	clauses := []pep440Clause{}
+	allowPrerelease := false
+	for _, clauseString := range strings.Split(specifier, ",") {
+		clause, err := parsePep440Clause(strings.TrimSpace(clauseString))
+		if err != nil {
+			return nil, fmt.Errorf("invalid version specifier %s: %w", specifier, err)
+		}
+		if len(clause.version.Pre) > 0 {
+			allowPrerelease = true
+		}
+		clauses = append(clauses, clause)
+	}
+	return func(version semver.Version) bool {
+		if len(version.Pre) > 0 && !allowPrerelease {
+			return false
+		}
+		for _, clause := range clauses {
+			if !clause.matches(version) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+// Returns a range for a Python version from any of the places it can be specified: a PEP 440
+// specifier, a bare version like "3.10" (as in .python-version) which matches any 3.10.x, a Poetry
+// constraint (e.g. "^3.10", "3.10.*" or ">=3.8 <4.0"), or "latest" / "*" for any stable version.
+func NewPythonRange(specifier string) (semver.Range, error) {
+	specifier = strings.TrimSpace(specifier)
+	switch {
+	case specifier == "" || specifier == "*" || specifier == "latest":
+		return NewPep440Range(">=0")
+	case strings.HasPrefix(specifier, "^") || (strings.HasPrefix(specifier, "~") && !strings.HasPrefix(specifier, "~=")) || strings.Contains(specifier, "||"):
+		return NewNpmRange(specifier)
+	case pep440BareVersionRegexp.MatchString(specifier):
+		return NewPep440Range("==" + specifier + ".*")
+	case pep440BareWildcardRegexp.MatchString(specifier):
+		return NewPep440Range("==" + specifier[:len(specifier)-1] + "*")
+	case pep440VersionRegexp.MatchString(specifier):
+		return NewPep440Range("==" + specifier)
+	}
+	return NewPep440Range(joinWhitespaceClauses(specifier))
+}
+
+// Poetry allows clauses to be separated by spaces instead of commas (e.g. ">=3.8 <4.0"), so this
+// converts them to a comma separated PEP 440 specifier. Operators followed by a space are kept
+// with the version after them.
+func joinWhitespaceClauses(specifier string) string {
+	if strings.Contains(specifier, ",") {
+		return specifier
+	}
+	clauses := []string{}
+	pendingOperator := ""
+	for _, field := range strings.Fields(specifier) {
+		if pep440OperatorRegexp.MatchString(field) {
+			pendingOperator += field
+			continue
+		}
+		clauses = append(clauses, pendingOperator+field)
+		pendingOperator = ""
+	}
+	if pendingOperator != "" {
+		clauses = append(clauses, pendingOperator)
+	}
+	return strings.Join(clauses, ",")
+}
+
+func parsePep440Clause(clauseString string) (pep440Clause, error) {
+	match := pep440ClauseRegexp.FindStringSubmatch(clauseString)
+	if match == nil {
+		return pep440Clause{}, fmt.Errorf("unsupported clause %s", clauseString)
+	}
+	clause := pep440Clause{operator: match[1], raw: match[2], wildcard: match[3] != ""}
+	if clause.operator == "===" {
+		// Arbitrary equality is a string comparison, so the version does not need to be valid
+		clause.raw += match[3]
+		clause.wildcard = false
+		if version, err := semver.ParseTolerant(clause.raw); err == nil {
+			clause.version = version
+		}
+		return clause, nil
+	}
+	if clause.wildcard && clause.operator != "==" && clause.operator != "!=" {
+		return clause, fmt.Errorf("wildcards are not allowed with %s", clause.operator)
+	}
+	version, segments, err := parsePep440Version(clause.raw)
+	if err != nil {
+		return clause, err
+	}
+	clause.version = version
+	clause.segments = segments
+	if clause.wildcard && len(version.Pre) > 0 {
+		return clause, fmt.Errorf("wildcards are not allowed with prereleases in %s", clauseString)
+	}
+	if clause.operator == "~=" && segments < 2 {
+		return clause, fmt.Errorf("%s requires at least two release segments", clauseString)
+	}
+	if (clause.wildcard || clause.operator == "~=") && segments > 3 {
+		return clause, fmt.Errorf("prefix matching on more than three release segments is not supported in %s", clauseString)
+	}
+	return clause, nil
+}
+
+func (clause pep440Clause) matches(version semver.Version) bool {
+	switch clause.operator {
+	case "===":
+		return version.String() == clause.raw || version.FinalizeVersion() == clause.raw
+	case "==":
+		if clause.wildcard {
+			return hasReleasePrefix(version, clause.version, clause.segments)
+		}
+		return version.EQ(clause.version)
+	case "!=":
+		if clause.wildcard {
+			return !hasReleasePrefix(version, clause.version, clause.segments)
+		}
+		return !version.EQ(clause.version)
+	case "~=":
+		// ~=3.10 is >=3.10 ==3.*, and ~=3.10.2 is >=3.10.2 ==3.10.*
+		return version.GTE(clause.version) && hasReleasePrefix(version, clause.version, clause.segments-1)
+	case "<=":
+		return version.LTE(clause.version)
+	case ">=":
+		return version.GTE(clause.version)
+	case "<":
+		// <3.12 must not match 3.12.0rc1 unless the clause itself is a prerelease
+		if len(clause.version.Pre) == 0 && len(version.Pre) > 0 && hasReleasePrefix(version, clause.version, 3) {
+			return false
+		}
+		return version.LT(clause.version)
+	case ">":
+		return version.GT(clause.version)
+	}
+	return false
+}
+
+// Compares the first "segments" parts of major.minor.patch
+func hasReleasePrefix(version semver.Version, prefix semver.Version, segments int) bool {
+	release := []uint64{version.Major, version.Minor, version.Patch}
+	prefixRelease := []uint64{prefix.Major, prefix.Minor, prefix.Patch}
+	for i := 0; i < segments && i < 3; i++ {
+		if release[i] != prefixRelease[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Converts a PEP 440 version into a semver version, returning the number of release segments that
+// were specified. Alpha, beta and rc releases become the "alpha.N", "beta.N" and "rc.N" prereleases
+// Python uses, post releases and local versions are ignored, and dev releases of a final release
+// sort before any alpha.
+func parsePep440Version(versionString string) (semver.Version, int, error) {
+	match := pep440VersionRegexp.FindStringSubmatch(versionString)
+	if match == nil {
+		return semver.Version{}, 0, fmt.Errorf("invalid version %s", versionString)
+	}
+	if match[1] != "" && match[1] != "0" {
+		return semver.Version{}, 0, fmt.Errorf("version epochs are not supported in %s", versionString)
+	}
+	release := strings.Split(match[2], ".")
+	numbers := [3]uint64{}
+	for i, segment := range release {
+		number, err := strconv.ParseUint(segment, 10, 64)
+		if err != nil {
+			return semver.Version{}, 0, fmt.Errorf("invalid version %s", versionString)
+		}
+		if i < 3 {
+			numbers[i] = number
+		} else if number != 0 {
+			return semver.Version{}, 0, fmt.Errorf("more than three non-zero release segments are not supported in %s", versionString)
+		}
+	}
+	version := semver.Version{Major: numbers[0], Minor: numbers[1], Patch: numbers[2]}
+
+	preName, preNumber, isDev, devNumber := strings.ToLower(match[3]), match[4], match[7] != "", match[8]
+	if preNumber == "" {
+		preNumber = "0"
+	}
+	if devNumber == "" {
+		devNumber = "0"
+	}
+	var prerelease string
+	switch {
+	case preName != "" && isDev:
+		return semver.Version{}, 0, fmt.Errorf("dev releases of prereleases are not supported in %s", versionString)
+	case preName != "":
+		prerelease = pep440PrereleaseNames[preName] + "." + preNumber
+	case isDev:
+		// Numeric identifiers sort before alphanumeric ones, so 0.dev.N comes before alpha.N
+		prerelease = "0.dev." + devNumber
+	}
+	if prerelease != "" {
+		parsed, err := semver.Parse(version.String() + "-" + prerelease)
+		if err != nil {
+			return semver.Version{}, 0, fmt.Errorf("invalid version %s: %w", versionString, err)
+		}
+		version = parsed
+	}
+	return version, len(release), nil
+}
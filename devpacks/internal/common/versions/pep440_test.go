@@ -0,0 +1,132 @@
+package versions
+
+import (
+	"testing"
+
+	"github.com/blang/semver/v4"
+)
+
+func TestNewPep440Range(t *testing.T) {
+	tests := []struct {
+		specifier  string
+		matches    []string
+		notMatches []string
+	}{
+		// Compatible release
+		{"~=3.10", []string{"3.10.0", "3.12.1"}, []string{"3.9.9", "4.0.0"}},
+		{"~=3.10.2", []string{"3.10.2", "3.10.9"}, []string{"3.10.1", "3.11.0"}},
+		{"~= 3.10", []string{"3.11.0"}, []string{"3.9.0"}},
+		// Comparisons
+		{">=3.9,<3.12", []string{"3.9.0", "3.11.9"}, []string{"3.8.9", "3.12.0"}},
+		{">=3.9, <3.12", []string{"3.10.0"}, []string{"3.12.0"}},
+		{">3.9", []string{"3.9.1", "3.10.0"}, []string{"3.9.0"}},
+		{"<=3.9", []string{"3.9.0", "3.8.5"}, []string{"3.9.1"}},
+		{"<3.12", []string{"3.11.9"}, []string{"3.12.0", "3.12.0-rc.1"}},
+		// Equality and exclusion, with and without wildcards
+		{"==3.10.4", []string{"3.10.4"}, []string{"3.10.5"}},
+		{"==3.10", []string{"3.10.0"}, []string{"3.10.1"}},
+		{"==3.10.*", []string{"3.10.0", "3.10.13"}, []string{"3.11.0", "3.1.0"}},
+		{"==3.*", []string{"3.0.0", "3.12.1"}, []string{"2.7.18", "4.0.0"}},
+		{"!=3.11.0", []string{"3.11.1", "3.10.0"}, []string{"3.11.0"}},
+		{"!=3.11.*", []string{"3.10.9", "3.12.0"}, []string{"3.11.0", "3.11.8"}},
+		{">=3.8,!=3.9.*,<4", []string{"3.8.10", "3.10.0"}, []string{"3.9.5", "4.0.0", "3.7.9"}},
+		{"===3.10.4", []string{"3.10.4"}, []string{"3.10.5"}},
+		// "v" prefixes and long release segments
+		{">=v3.9", []string{"3.9.0"}, []string{"3.8.0"}},
+		{"==3.10.0.0", []string{"3.10.0"}, []string{"3.10.1"}},
+		// Prereleases only match when a clause references one
+		{">=3.11", []string{"3.11.0", "3.12.0"}, []string{"3.12.0-rc.1", "3.13.0-alpha.1"}},
+		{">=3.12.0rc1", []string{"3.12.0-rc.1", "3.12.0-rc.2", "3.12.0", "3.13.0-alpha.1"}, []string{"3.12.0-beta.4"}},
+		{"==3.13.0a1", []string{"3.13.0-alpha.1"}, []string{"3.13.0-alpha.2", "3.13.0"}},
+		{"==3.13.0b2", []string{"3.13.0-beta.2"}, []string{"3.13.0-alpha.2"}},
+		{"==3.13.0c1", []string{"3.13.0-rc.1"}, []string{"3.13.0"}},
+		{"<3.12.0rc2", []string{"3.12.0-rc.1", "3.11.0"}, []string{"3.12.0-rc.2", "3.12.0"}},
+		{">=3.12.0.dev1", []string{"3.12.0-0.dev.1", "3.12.0-alpha.1", "3.12.0"}, []string{"3.11.9"}},
+	}
+	for _, test := range tests {
+		t.Run(test.specifier, func(t *testing.T) {
+			expectedRange, err := NewPep440Range(test.specifier)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, version := range test.matches {
+				if !expectedRange(semver.MustParse(version)) {
+					t.Errorf("expected %s to match %s", version, test.specifier)
+				}
+			}
+			for _, version := range test.notMatches {
+				if expectedRange(semver.MustParse(version)) {
+					t.Errorf("expected %s not to match %s", version, test.specifier)
+				}
+			}
+		})
+	}
+}
+
+func TestNewPep440RangeErrors(t *testing.T) {
+	tests := []string{
+		"3.10",
+		"~=3",
+		">=3.10.*",
+		"==3.13.0a1.*",
+		"==1!3.10",
+		"==3.10.0.0.1",
+		"==3.13.0a1.dev1",
+		">=3.9,",
+		"=>3.9",
+		"==abc",
+	}
+	for _, specifier := range tests {
+		t.Run(specifier, func(t *testing.T) {
+			if _, err := NewPep440Range(specifier); err == nil {
+				t.Errorf("expected an error for %s", specifier)
+			}
+		})
+	}
+}
+
+func TestNewPythonRange(t *testing.T) {
+	tests := []struct {
+		specifier  string
+		matches    []string
+		notMatches []string
+	}{
+		{"", []string{"3.12.1"}, []string{"3.13.0-rc.1"}},
+		{"*", []string{"2.7.18"}, []string{"3.13.0-rc.1"}},
+		{"latest", []string{"3.12.1"}, []string{"3.13.0-alpha.1"}},
+		{"3.10", []string{"3.10.0", "3.10.13"}, []string{"3.11.0"}},
+		{"3", []string{"3.12.1"}, []string{"2.7.18"}},
+		{"3.10.4", []string{"3.10.4"}, []string{"3.10.5"}},
+		{"3.13.0rc1", []string{"3.13.0-rc.1"}, []string{"3.13.0"}},
+		{"^3.10", []string{"3.12.0"}, []string{"4.0.0", "3.9.0"}},
+		{"~3.10", []string{"3.10.5"}, []string{"3.11.0"}},
+		{"^3.8 || ^4.0", []string{"3.8.0", "4.1.0"}, []string{"3.7.0"}},
+		{"~=3.10", []string{"3.11.0"}, []string{"3.9.0"}},
+		{">=3.9,<3.12", []string{"3.11.0"}, []string{"3.12.0"}},
+		// Poetry constraints
+		{"3.10.*", []string{"3.10.0", "3.10.13"}, []string{"3.11.0", "3.9.9"}},
+		{"3.x", []string{"3.12.1"}, []string{"4.0.0"}},
+		{">=3.8 <4.0", []string{"3.8.0", "3.12.1"}, []string{"3.7.9", "4.0.0"}},
+		{">= 3.8  < 3.11", []string{"3.10.9"}, []string{"3.11.0"}},
+		{">=3.8 !=3.9.1", []string{"3.9.0", "3.9.2"}, []string{"3.9.1"}},
+		{">=3.8 <3.11 || ^3.12", []string{"3.10.0", "3.12.1"}, []string{"3.11.0"}},
+	}
+	for _, test := range tests {
+		t.Run(test.specifier, func(t *testing.T) {
+			expectedRange, err := NewPythonRange(test.specifier)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, version := range test.matches {
+				if !expectedRange(semver.MustParse(version)) {
+					t.Errorf("expected %s to match %s", version, test.specifier)
+				}
+			}
+			for _, version := range test.notMatches {
+				if expectedRange(semver.MustParse(version)) {
+					t.Errorf("expected %s not to match %s", version, test.specifier)
+				}
+			}
+		})
+	}
+}